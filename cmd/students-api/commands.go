@@ -0,0 +1,709 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/backup"
+	"github.com/cmanish049/students-api/internal/blob"
+	"github.com/cmanish049/students-api/internal/bulk"
+	"github.com/cmanish049/students-api/internal/config"
+	"github.com/cmanish049/students-api/internal/legacyimport"
+	"github.com/cmanish049/students-api/internal/replica"
+	"github.com/cmanish049/students-api/internal/seed"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/storage/sqlite"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+	"github.com/cmanish049/students-api/internal/validation"
+)
+
+// The maintenance commands work on the configured storage directly, so they
+// don't need the server to be running. Changes made through them are not
+// published as events.
+
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	// assigned here because usage refers back to commands
+	commands = map[string]command{
+		"serve":   {"run the API server (default)", serve},
+		"migrate": {"create or upgrade the database schema", migrate},
+		"create":  {"add a student", create},
+		"list":    {"list students", list},
+		"delete":  {"delete students by id", remove},
+		"export":  {"write all students as JSON or CSV", export},
+		"seed":    {"fill the database with fake students", seedStudents},
+		"backup":  {"write a portable JSON or SQL dump", backupDb},
+		"restore": {"load a dump written by backup", restoreDb},
+		"recover": {"restore the database from its replicated snapshots", recoverDb},
+		"doctor":  {"check the database for integrity problems", doctor},
+		"import":  {"import students from a legacy fixed-width or XML export", importLegacy},
+		"help":    {"show this help", help},
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: students-api [command] [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "run \"students-api <command> -h\" for the flags of a command")
+}
+
+func help([]string) error {
+	usage()
+	return nil
+}
+
+// newFlagSet returns the flag set of a subcommand; the configuration flags
+// are added by config.MustLoadArgs.
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: students-api %s [flags] %s\n\nflags:\n", name, args)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// openStorage opens the configured database, or that of --tenant if the
+// command has the flag and the tenant has a database of its own.
+func openStorage(fs *flag.FlagSet, args []string) (*sqlite.Sqlite, error) {
+	cfg := config.MustLoadArgs(fs, args)
+	useTenantDatabase(fs, cfg)
+
+	return sqlite.New(cfg)
+}
+
+// openWithRules is openStorage that also returns the validator with the
+// configured rules for student records.
+func openWithRules(fs *flag.FlagSet, args []string) (*sqlite.Sqlite, *validation.Validator, error) {
+	cfg := config.MustLoadArgs(fs, args)
+
+	validate, err := validation.New(cfg.Validation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	useTenantDatabase(fs, cfg)
+	db, err := sqlite.New(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return db, validate, nil
+}
+
+// tenantFlag adds --tenant, the tenant (school) a command acts for.
+func tenantFlag(fs *flag.FlagSet) *string {
+	return fs.String("tenant", tenant.Default, "tenant to act for")
+}
+
+// useTenantDatabase points cfg at the database of the --tenant of fs, if
+// it has one.
+func useTenantDatabase(fs *flag.FlagSet, cfg *config.Config) {
+	f := fs.Lookup("tenant")
+	if f == nil {
+		return
+	}
+
+	if path, ok := cfg.Tenancy.Databases[f.Value.String()]; ok {
+		cfg.StoragePath = path
+	}
+}
+
+// tenantContext returns a context acting for tenant id.
+func tenantContext(id string) (context.Context, error) {
+	if !tenant.Valid(id) {
+		return nil, fmt.Errorf("invalid tenant %q", id)
+	}
+
+	return tenant.With(context.Background(), id), nil
+}
+
+func migrate(args []string) error {
+	fs := newFlagSet("migrate", "")
+
+	// opening the storage creates whatever is missing
+	db, err := openStorage(fs, args)
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+
+	fmt.Printf("schema is up to date (version %d)\n", sqlite.SchemaVersion)
+
+	return nil
+}
+
+func create(args []string) error {
+	fs := newFlagSet("create", "")
+	name := fs.String("name", "", "student name")
+	email := fs.String("email", "", "student email")
+	age := fs.Int("age", 0, "student age")
+	tenantId := tenantFlag(fs)
+
+	db, validate, err := openWithRules(fs, args)
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+
+	ctx, err := tenantContext(*tenantId)
+	if err != nil {
+		return err
+	}
+
+	students := studentsvc.New(db, nil, validate)
+
+	id, err := students.Create(ctx, types.Student{Name: *name, Email: *email, Age: *age})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(id)
+
+	return nil
+}
+
+func list(args []string) error {
+	fs := newFlagSet("list", "")
+	format := fs.String("format", "table", "output format: table or json")
+	tenantId := tenantFlag(fs)
+
+	db, err := openStorage(fs, args)
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+
+	ctx, err := tenantContext(*tenantId)
+	if err != nil {
+		return err
+	}
+
+	students, err := db.GetStudentList(ctx, storage.StudentFilter{})
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "table":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tEMAIL\tAGE")
+		for _, s := range students {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d\n", s.Id, s.Name, s.Email, s.Age)
+		}
+		return tw.Flush()
+	case "json":
+		return bulk.WriteJSON(os.Stdout, students)
+	}
+
+	return fmt.Errorf("unknown format %q, use table or json", *format)
+}
+
+func remove(args []string) error {
+	fs := newFlagSet("delete", "<id>...")
+	tenantId := tenantFlag(fs)
+
+	db, validate, err := openWithRules(fs, args)
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+
+	ctx, err := tenantContext(*tenantId)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no student id given")
+	}
+
+	var ids []int64
+	for _, arg := range fs.Args() {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid id %q", arg)
+		}
+		ids = append(ids, id)
+	}
+
+	students := studentsvc.New(db, nil, validate)
+
+	for _, id := range ids {
+		if err := students.Delete(ctx, id); err != nil {
+			return fmt.Errorf("delete %d: %w", id, err)
+		}
+		fmt.Println("deleted", id)
+	}
+
+	return nil
+}
+
+func export(args []string) error {
+	fs := newFlagSet("export", "")
+	format := fs.String("format", "json", "output format: json or csv")
+	out := fs.String("out", "", "file to write to (default stdout)")
+	tenantId := tenantFlag(fs)
+
+	db, err := openStorage(fs, args)
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown format %q, use json or csv", *format)
+	}
+
+	ctx, err := tenantContext(*tenantId)
+	if err != nil {
+		return err
+	}
+
+	students, err := db.GetStudentList(ctx, storage.StudentFilter{})
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "json" {
+		err = bulk.WriteJSON(w, students)
+	} else {
+		err = bulk.WriteCSV(w, students)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "exported %d students to %s\n", len(students), *out)
+	}
+
+	return nil
+}
+
+func seedStudents(args []string) error {
+	fs := newFlagSet("seed", "")
+	count := fs.Int("count", 100, "number of students to generate")
+	seedFlag := fs.Uint64("seed", 0, "random seed for reproducible data (default random)")
+	tenantId := tenantFlag(fs)
+
+	db, err := openStorage(fs, args)
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+
+	if *count < 1 {
+		return errors.New("count must be at least 1")
+	}
+
+	ctx, err := tenantContext(*tenantId)
+	if err != nil {
+		return err
+	}
+
+	r := rand.New(rand.NewPCG(*seedFlag, *seedFlag))
+	if *seedFlag == 0 {
+		r = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+
+	start := time.Now()
+	if err := db.CreateStudents(ctx, seed.Students(r, *count)); err != nil {
+		return err
+	}
+
+	fmt.Printf("created %d students in %s\n", *count, time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
+
+func backupDb(args []string) error {
+	fs := newFlagSet("backup", "")
+	out := fs.String("out", "", "file to write to (default stdout)")
+	format := fs.String("format", "", "json or sql (default from the file extension, else json)")
+
+	db, err := openStorage(fs, args)
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+
+	f := dumpFormat(*format, *out)
+	if f != "json" && f != "sql" {
+		return fmt.Errorf("unknown format %q, use json or sql", f)
+	}
+
+	// a backup has every tenant's data, archived students included
+	ctx := tenant.All(context.Background())
+
+	students, err := db.GetStudentList(ctx, storage.StudentFilter{IncludeArchived: true})
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := db.GetWebhookList(ctx)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	dump := backup.New(students, webhooks)
+	if f == "sql" {
+		err = backup.WriteSQL(w, dump)
+	} else {
+		err = backup.WriteJSON(w, dump)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "backed up %d students and %d webhooks\n", len(students), len(webhooks))
+
+	return nil
+}
+
+func restoreDb(args []string) error {
+	fs := newFlagSet("restore", "")
+	in := fs.String("in", "", "dump to read (default stdin)")
+	format := fs.String("format", "", "json or sql (default from the file extension, else json)")
+	replace := fs.Bool("replace", false, "delete all existing data first instead of requiring an empty database")
+
+	db, err := openStorage(fs, args)
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	ctx := context.Background()
+
+	switch f := dumpFormat(*format, *in); f {
+	case "json":
+		dump, err := backup.ReadJSON(r)
+		if err != nil {
+			return err
+		}
+
+		err = db.Restore(ctx, dump.Students, dump.Webhooks, *replace)
+		if err != nil {
+			return restoreError(err)
+		}
+
+		fmt.Fprintf(os.Stderr, "restored %d students and %d webhooks from a backup made %s\n", len(dump.Students), len(dump.Webhooks), dump.CreatedAt.Format(time.RFC3339))
+	case "sql":
+		script, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+
+		if err := db.RestoreSQL(ctx, string(script), *replace); err != nil {
+			return restoreError(err)
+		}
+
+		fmt.Fprintln(os.Stderr, "restored SQL dump")
+	default:
+		return fmt.Errorf("unknown format %q, use json or sql", f)
+	}
+
+	return nil
+}
+
+func restoreError(err error) error {
+	if errors.Is(err, sqlite.ErrNotEmpty) {
+		return fmt.Errorf("%w, restore into a fresh database or pass --replace", err)
+	}
+
+	return err
+}
+
+// dumpFormat is the explicit format, or the one implied by the file name.
+func dumpFormat(format, file string) string {
+	if format != "" {
+		return format
+	}
+
+	if filepath.Ext(file) == ".sql" {
+		return "sql"
+	}
+
+	return "json"
+}
+
+func recoverDb(args []string) error {
+	fs := newFlagSet("recover", "")
+	tenantId := tenantFlag(fs)
+	at := fs.String("at", "", "restore the database as it was at this RFC 3339 time (default the newest snapshot)")
+	listOnly := fs.Bool("list", false, "list the snapshots instead of restoring one")
+	out := fs.String("out", "", "file to restore to (default the configured database)")
+	replace := fs.Bool("replace", false, "replace an existing database; stop the server first")
+
+	cfg := config.MustLoadArgs(fs, args)
+	if cfg.Replication.Bucket == "" {
+		return errors.New("replication is not configured, set replication.bucket")
+	}
+	if !tenant.Valid(*tenantId) {
+		return fmt.Errorf("invalid tenant %q", *tenantId)
+	}
+
+	// tenants without a database of their own are in the main one
+	id, path := "", cfg.StoragePath
+	if p, ok := cfg.Tenancy.Databases[*tenantId]; ok {
+		id, path = *tenantId, p
+	}
+	if *out != "" {
+		path = *out
+	}
+
+	bucket, err := replicaBucket(cfg.Replication)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	snapshots, err := replica.Snapshots(ctx, bucket, cfg.Replication.Prefix, id)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		return fmt.Errorf("no snapshots in %s", replica.Dir(cfg.Replication.Prefix, id))
+	}
+
+	if *listOnly {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "TAKEN\tSIZE\tKEY")
+		for _, s := range snapshots {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", s.Time.Format(time.RFC3339), s.Size, s.Key)
+		}
+		return tw.Flush()
+	}
+
+	t := time.Now()
+	if *at != "" {
+		if t, err = time.Parse(time.RFC3339, *at); err != nil {
+			return fmt.Errorf("invalid --at %q, use a time such as 2026-10-14T09:30:00Z", *at)
+		}
+	}
+
+	s, ok := replica.At(snapshots, t)
+	if !ok {
+		return fmt.Errorf("the oldest snapshot was taken %s, after %s", snapshots[0].Time.Format(time.RFC3339), t.Format(time.RFC3339))
+	}
+
+	if _, err := os.Stat(path); err == nil && !*replace {
+		return fmt.Errorf("%s already exists, stop the server and pass --replace", path)
+	}
+
+	// after a disk loss the directory may be gone too
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	if err := replica.Restore(ctx, bucket, s, path); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "restored %s from the snapshot taken %s\n", path, s.Time.Format(time.RFC3339))
+
+	return nil
+}
+
+// replicaBucket returns the bucket the databases are replicated to.
+func replicaBucket(cfg config.Replication) (*blob.S3, error) {
+	return blob.NewS3(blob.S3Config{
+		Endpoint:     cfg.Endpoint,
+		Region:       cfg.Region,
+		Bucket:       cfg.Bucket,
+		AccessKey:    cfg.AccessKey,
+		SecretKey:    cfg.SecretKey,
+		SessionToken: cfg.SessionToken,
+		PathStyle:    cfg.PathStyle,
+		Timeout:      cfg.Timeout,
+	})
+}
+
+func doctor(args []string) error {
+	fs := newFlagSet("doctor", "")
+	repair := fs.Bool("repair", false, "fix the problems that can be fixed without losing student data")
+
+	cfg := config.MustLoadArgs(fs, args)
+
+	// without migrating, so missing tables and indexes are reported
+	db, err := sqlite.Open(cfg.StoragePath)
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+
+	findings, err := db.Doctor(context.Background(), *repair)
+	if err != nil {
+		return err
+	}
+
+	open := 0
+	for _, f := range findings {
+		switch {
+		case f.Problem == "":
+			fmt.Printf("ok     %s\n", f.Check)
+		case f.Fixed:
+			fmt.Printf("fixed  %s: %s\n", f.Check, f.Problem)
+		default:
+			fmt.Printf("FAIL   %s: %s\n", f.Check, f.Problem)
+			open++
+		}
+	}
+
+	if open > 0 {
+		return fmt.Errorf("%d problems found", open)
+	}
+
+	return nil
+}
+
+func importLegacy(args []string) error {
+	fs := newFlagSet("import", "")
+	mappingPath := fs.String("mapping", "", "mapping file describing the export (required)")
+	in := fs.String("in", "", "export to read (default stdin)")
+	dryRun := fs.Bool("dry-run", false, "check every record without writing anything")
+	report := fs.String("errors", "", "write rejected records as CSV to this file (default stderr)")
+	tenantId := tenantFlag(fs)
+
+	db, validate, err := openWithRules(fs, args)
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+
+	ctx, err := tenantContext(*tenantId)
+	if err != nil {
+		return err
+	}
+
+	if *mappingPath == "" {
+		fs.Usage()
+		return errors.New("--mapping is required")
+	}
+
+	mapping, err := legacyimport.LoadMapping(*mappingPath)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	res, err := legacyimport.Import(ctx, r, mapping, db, validate, *dryRun)
+
+	if len(res.Errors) > 0 {
+		if *report != "" {
+			if werr := writeImportErrors(*report, res.Errors); werr != nil {
+				return werr
+			}
+		} else {
+			for _, e := range res.Errors {
+				fmt.Fprintln(os.Stderr, e.Error())
+			}
+		}
+	}
+
+	verb := "imported"
+	if *dryRun {
+		verb = "would import"
+	}
+	fmt.Printf("read %d records, %s %d, rejected %d\n", res.Read, verb, res.Imported, len(res.Errors))
+
+	if err != nil {
+		return fmt.Errorf("import stopped after record %d: %w", res.Read, err)
+	}
+	if len(res.Errors) > 0 {
+		return fmt.Errorf("%d records rejected", len(res.Errors))
+	}
+
+	return nil
+}
+
+func writeImportErrors(path string, errs []legacyimport.RecordError) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cw := csv.NewWriter(f)
+	cw.Write([]string{"record", "line", "error"})
+	for _, e := range errs {
+		cw.Write([]string{strconv.Itoa(e.Record), strconv.Itoa(e.Line), e.Err.Error()})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
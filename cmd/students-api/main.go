@@ -2,23 +2,78 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/cmanish049/students-api/internal/auth"
+	"github.com/cmanish049/students-api/internal/blob"
+	"github.com/cmanish049/students-api/internal/bulk"
+	"github.com/cmanish049/students-api/internal/chaos"
 	"github.com/cmanish049/students-api/internal/config"
-	"github.com/cmanish049/students-api/internal/http/handlers/student"
+	"github.com/cmanish049/students-api/internal/email"
+	"github.com/cmanish049/students-api/internal/events"
+	"github.com/cmanish049/students-api/internal/grpc/studentpb"
+	"github.com/cmanish049/students-api/internal/grpc/studentserver"
+	"github.com/cmanish049/students-api/internal/http/handlers/docs"
+	"github.com/cmanish049/students-api/internal/http/middleware"
+	"github.com/cmanish049/students-api/internal/http/router"
+	"github.com/cmanish049/students-api/internal/jobs"
+	"github.com/cmanish049/students-api/internal/maintenance"
+	"github.com/cmanish049/students-api/internal/openapi"
+	"github.com/cmanish049/students-api/internal/publish"
+	"github.com/cmanish049/students-api/internal/replica"
+	"github.com/cmanish049/students-api/internal/schedule"
+	filesvc "github.com/cmanish049/students-api/internal/service/file"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/sms"
 	"github.com/cmanish049/students-api/internal/storage/sqlite"
+	"github.com/cmanish049/students-api/internal/storage/tenantdb"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/upgrade"
+	"github.com/cmanish049/students-api/internal/validation"
+	"github.com/cmanish049/students-api/internal/webhooks"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
+	name, args := "serve", os.Args[1:]
+	// a bare "students-api --config ..." still serves
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(args); err != nil {
+		fmt.Fprintln(os.Stderr, "students-api "+name+": "+err.Error())
+		os.Exit(1)
+	}
+}
+
+// serve runs the API until it is stopped or upgraded.
+func serve(args []string) error {
 	// load config
-	cfg := config.MustLoad()
+	cfg := config.MustLoadArgs(flag.NewFlagSet("serve", flag.ExitOnError), args)
+
+	slog.SetLogLoggerLevel(cfg.SlogLevel())
 
 	// setup database
 	db, err := sqlite.New(cfg)
@@ -29,44 +84,365 @@ func main() {
 	slog.Info("storage initialialized", slog.String("env", cfg.Env), slog.String("version", "1.0.0"))
 
 	defer db.Db.Close()
-	// setup router
-	router := http.NewServeMux()
 
-	router.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
-	})
+	// tenants with a database of their own are routed to it
+	databases := tenantdb.NewManager(db, cfg.Tenancy.Databases)
+	defer databases.Close()
+	store := tenantdb.New(databases)
+
+	if len(cfg.Tenancy.Databases) > 0 {
+		checks, stopChecks := context.WithCancel(context.Background())
+		defer stopChecks()
+		go databases.Run(checks, cfg.Tenancy.HealthInterval)
+
+		slog.Info("tenant databases configured", slog.Int("tenants", len(cfg.Tenancy.Databases)))
+	}
+
+	validate, err := validation.New(cfg.Validation)
+	if err != nil {
+		log.Fatal("invalid validation rules:", err)
+	}
+
+	// mutations made through students are published on the bus
+	bus := events.NewBus()
+	students := studentsvc.New(store, bus, validate)
+
+	// background work runs from the persistent jobs table
+	queue := jobs.New(db, cfg.Jobs.Workers, cfg.Jobs.PollInterval, cfg.Jobs.Lease)
+
+	guard := webhooks.NewGuard(cfg.Webhooks.AllowedHosts)
+	dispatcher := webhooks.New(store, queue, guard, cfg.Webhooks.MaxAttempts, cfg.Webhooks.Timeout, cfg.Webhooks.RetryBackoff)
+	bus.Subscribe(dispatcher.Handle)
 
-	router.HandleFunc("POST /api/students", student.New(db))
+	if cfg.Email.Host != "" {
+		notifier, err := email.New(email.Config{
+			Host:      cfg.Email.Host,
+			Port:      cfg.Email.Port,
+			Username:  cfg.Email.Username,
+			Password:  cfg.Email.Password,
+			From:      cfg.Email.From,
+			Templates: cfg.Email.Templates,
+			Timeout:   cfg.Email.Timeout,
+			VerifyUrl: cfg.Email.VerifyUrl,
+		}, queue, cfg.Email.MaxAttempts, cfg.Email.RetryBackoff)
+		if err != nil {
+			log.Fatal("failed to set up email notifications:", err)
+		}
+		bus.Subscribe(notifier.Handle)
+		students.SetVerifier(notifier, cfg.Email.VerificationTtl)
 
-	router.HandleFunc("GET /api/students/{id}", student.GetById(db))
-	router.HandleFunc("GET /api/students", student.GetStudentList(db))
-	router.HandleFunc("PUT /api/students/{id}", student.UpdateStudent(db))
-	router.HandleFunc("DELETE /api/students/{id}", student.DeleteStudent(db))
+		slog.Info("emailing students", slog.String("host", cfg.Email.Host))
+	}
+
+	var texts *sms.Notifier
+	if cfg.Sms.Provider != "" {
+		var provider sms.Provider
+		if cfg.Sms.Provider == "twilio" {
+			provider = sms.NewTwilio(cfg.Sms.Url, cfg.Sms.AccountSid, cfg.Sms.Token, cfg.Sms.From, cfg.Sms.Timeout)
+		} else {
+			provider = sms.NewHTTP(cfg.Sms.Url, cfg.Sms.Token, cfg.Sms.From, cfg.Sms.Timeout)
+		}
+		texts = sms.New(provider, queue, cfg.Sms.RatePerMinute, cfg.Sms.MaxAttempts, cfg.Sms.RetryBackoff)
+
+		slog.Info("sms enabled", slog.String("provider", cfg.Sms.Provider), slog.Int("rate_per_minute", cfg.Sms.RatePerMinute))
+	}
+
+	// uploaded files keep their contents in a blob store
+	var files *filesvc.Service
+	var localBlobs *blob.Local
+	if cfg.Files.Backend != "" {
+		var blobs blob.Store
+		if cfg.Files.Backend == "s3" {
+			blobs, err = blob.NewS3(blob.S3Config{
+				Endpoint:     cfg.Files.S3.Endpoint,
+				Region:       cfg.Files.S3.Region,
+				Bucket:       cfg.Files.S3.Bucket,
+				AccessKey:    cfg.Files.S3.AccessKey,
+				SecretKey:    cfg.Files.S3.SecretKey,
+				SessionToken: cfg.Files.S3.SessionToken,
+				PathStyle:    cfg.Files.S3.PathStyle,
+				Timeout:      cfg.Files.S3.Timeout,
+			})
+		} else {
+			localBlobs, err = blob.NewLocal(cfg.Files.Dir, cfg.Files.PublicUrl, cfg.Files.UrlSecret)
+			blobs = localBlobs
+		}
+		if err != nil {
+			log.Fatal("failed to set up file uploads:", err)
+		}
+		files = filesvc.New(store, store, blobs, queue, cfg.Files.AllowedTypes, cfg.Files.UrlTtl)
+
+		slog.Info("file uploads enabled", slog.String("backend", cfg.Files.Backend), slog.Any("allowed_types", cfg.Files.AllowedTypes))
+	}
+
+	// imports and exports started through the API run as jobs
+	bulkOps := bulk.Register(queue, students)
+
+	queue.Start()
+
+	// recurring work from the config
+	scheduler := schedule.New()
+	available := tasks(cfg, databases, store, students)
+	for _, sc := range cfg.Schedules {
+		if err := scheduler.Add(sc.Name, sc.Task, sc.Schedule, available[sc.Task]); err != nil {
+			log.Fatal("invalid schedule:", err)
+		}
+	}
+	scheduler.Start()
+
+	// databases are copied to a bucket as they change
+	var replicator *replica.Replicator
+	stopReplicating := func() {}
+	if cfg.Replication.Bucket != "" {
+		bucket, err := replicaBucket(cfg.Replication)
+		if err != nil {
+			log.Fatal("failed to set up replication:", err)
+		}
+		replicator = replica.New(databases, bucket, cfg.Replication.Prefix, cfg.Replication.Retention)
+
+		var replicating context.Context
+		replicating, stopReplicating = context.WithCancel(context.Background())
+		defer stopReplicating()
+		go replicator.Run(replicating, cfg.Replication.Interval)
+
+		slog.Info("replicating databases", slog.String("bucket", cfg.Replication.Bucket), slog.String("prefix", cfg.Replication.Prefix), slog.Duration("interval", cfg.Replication.Interval))
+	}
+
+	var publisher *publish.Publisher
+	if cfg.Publisher.Backend != "" {
+		publisher, err = publish.New(cfg.Publisher.Backend, cfg.Publisher.Address, cfg.Publisher.Topic, cfg.Publisher.BufferSize)
+		if err != nil {
+			log.Fatal("failed to set up event publisher:", err)
+		}
+		bus.Subscribe(publisher.Handle)
+
+		slog.Info("publishing events", slog.String("backend", cfg.Publisher.Backend), slog.String("topic", cfg.Publisher.Topic))
+	}
+
+	// read-only switch, toggled from the admin listener or by signal
+	mode := maintenance.New(cfg.Maintenance.RetryAfter)
+
+	// fault injection for resilience testing, off unless enabled
+	injector, err := chaos.New(cfg.Chaos.Enabled, cfg.Chaos.Rules)
+	if err != nil {
+		log.Fatal("invalid chaos rules:", err)
+	}
+	if injector.Enabled() {
+		slog.Warn("fault injection is enabled", slog.Int("rules", len(cfg.Chaos.Rules)))
+	}
+
+	// requests are checked against the OpenAPI document before the handlers
+	spec, err := openapi.New(docs.Spec)
+	if err != nil {
+		log.Fatal("invalid openapi document:", err)
+	}
+
+	// timeout and limiter settings can be changed by a config reload
+	timeout := middleware.NewTimeout(cfg.RequestTimeout)
+	limiter := middleware.NewLimiter(cfg.MaxInFlight, cfg.MaxQueue, cfg.QueueTimeout)
+	bodyLimit := middleware.NewBodyLimit(int64(cfg.MaxBodySize))
+
+	// every request acts for one tenant; reloads can change the tokens
+	tenants := tenant.NewResolver(cfg.Tenancy.Policy())
+	resolveTenant := func(next http.Handler) http.Handler { return middleware.Tenant(next, tenants) }
+
+	// directory users log in for a session token that acts like a tenant
+	// token, limited to the role of their groups
+	var directory *auth.Directory
+	checkRoles := func(next http.Handler) http.Handler { return next }
+	interceptors := []grpc.UnaryServerInterceptor{studentserver.Tenant(tenants), studentserver.ReadOnly(mode)}
+	if cfg.Ldap.Url != "" {
+		sessions := auth.NewSessions(cfg.Ldap.SessionSecret, cfg.Ldap.SessionTtl)
+		directory, err = auth.New(auth.Config{
+			Url:            cfg.Ldap.Url,
+			StartTls:       cfg.Ldap.StartTls,
+			CaFile:         cfg.Ldap.CaFile,
+			BindDn:         cfg.Ldap.BindDn,
+			BindPassword:   cfg.Ldap.BindPassword,
+			BaseDn:         cfg.Ldap.BaseDn,
+			UserFilter:     cfg.Ldap.UserFilter,
+			GroupAttribute: cfg.Ldap.GroupAttribute,
+			Groups:         cfg.Ldap.Groups,
+			Timeout:        cfg.Ldap.Timeout,
+		}, sessions)
+		if err != nil {
+			log.Fatal("failed to set up directory logins:", err)
+		}
+		tenants.SetSessions(sessions)
+		checkRoles = func(next http.Handler) http.Handler { return middleware.Roles(next, sessions) }
+		interceptors = append(interceptors, studentserver.Roles(sessions))
+
+		slog.Info("directory logins enabled", slog.String("url", cfg.Ldap.Url), slog.Int("groups", len(cfg.Ldap.Groups)))
+	}
+
+	// long-lived streams are ended on shutdown instead of holding it up
+	streams, stopStreams := context.WithCancel(context.Background())
+	defer stopStreams()
+
+	deps := router.Deps{
+		Students:  students,
+		Bulk:      bulkOps,
+		Jobs:      queue,
+		Webhooks:  store,
+		Guard:     guard,
+		Validate:  validate,
+		Bus:       bus,
+		Streams:   streams,
+		Mode:      mode,
+		Injector:  injector,
+		Scheduler: scheduler,
+		Sms:       texts,
+		Databases: databases,
+		Directory: directory,
+
+		Files:      files,
+		LocalBlobs: localBlobs,
+		Replicator: replicator,
+	}
+
+	// middleware, outermost first
+	requests := []router.Middleware{
+		func(next http.Handler) http.Handler { return middleware.ReadOnly(next, mode) },
+		resolveTenant,
+		checkRoles,
+		limiter.Handler,
+		timeout.Handler,
+		bodyLimit.Handler,
+		spec.Handler,
+	}
 
-	// setup server
+	// the login answers before any tenant is known and writes nothing
+	anonymous := []router.Middleware{
+		func(next http.Handler) http.Handler { return middleware.ContentType(next, "application/json") },
+		limiter.Handler,
+		timeout.Handler,
+		bodyLimit.Handler,
+		spec.Handler,
+	}
+
+	root := router.New()
+	router.Public(root, deps, requests, anonymous, []router.Middleware{resolveTenant, checkRoles, spec.Handler})
+
+	// the admin routes are only reachable on the admin address
+	adminRouter := router.New()
+	router.Admin(adminRouter, deps)
+
+	// setup server; request ids are given first so every response has one
 	server := http.Server{
 		Addr:    cfg.Addr,
-		Handler: router,
+		Handler: middleware.RequestId(middleware.Chaos(root, injector)),
+	}
+	server.RegisterOnShutdown(stopStreams)
+
+	adminServer := http.Server{
+		Addr:    cfg.AdminServer.Addr,
+		Handler: middleware.RequestId(middleware.AllTenants(bodyLimit.Handler(adminRouter))),
+	}
+
+	// listeners are inherited from the previous process after an upgrade
+	upg, err := upgrade.New(cfg.PidFile)
+	if err != nil {
+		log.Fatal("failed to setup upgrader:", err)
 	}
 
-	slog.Info("Server started", slog.String("address", cfg.Addr))
+	ln, err := upg.Listen(cfg.Addr)
+	if err != nil {
+		log.Fatal("failed to listen:", err)
+	}
+
+	adminLn, err := upg.Listen(cfg.AdminServer.Addr)
+	if err != nil {
+		log.Fatal("failed to listen on admin address:", err)
+	}
+
+	// gRPC shares the storage with the REST API
+	var grpcServer *grpc.Server
+	if cfg.GrpcServer.Addr != "" {
+		grpcLn, err := upg.Listen(cfg.GrpcServer.Addr)
+		if err != nil {
+			log.Fatal("failed to listen on grpc address:", err)
+		}
+
+		grpcServer = grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+		studentpb.RegisterStudentServiceServer(grpcServer, studentserver.New(students))
+		reflection.Register(grpcServer)
+
+		go func() {
+			if err := grpcServer.Serve(grpcLn); err != nil {
+				log.Fatal("failed to start grpc server:", err)
+			}
+		}()
+	}
 
 	// Graceful shutdown
 
 	done := make(chan os.Signal, 1)
 
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, upgrade.Signal, maintenance.Signal)
 
 	go func() {
-		err := server.ListenAndServe()
+		err := server.Serve(ln)
 
-		if err != nil {
-			log.Fatal("failed to start server")
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("failed to start server:", err)
+		}
+	}()
+
+	go func() {
+		err := adminServer.Serve(adminLn)
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("failed to start admin server:", err)
 		}
 	}()
 
-	<-done
+	// poll the remote config backend by triggering regular reloads
+	if cfg.RemoteConfig.PollInterval > 0 {
+		go func() {
+			for range time.Tick(cfg.RemoteConfig.PollInterval) {
+				done <- syscall.SIGHUP
+			}
+		}()
+	}
+
+	if err := upg.Ready(); err != nil {
+		log.Fatal("failed to signal readiness:", err)
+	}
+
+	slog.Info("Server started", slog.String("address", cfg.Addr), slog.String("admin_address", cfg.AdminServer.Addr), slog.String("grpc_address", cfg.GrpcServer.Addr), slog.Bool("upgraded", upg.HasParent()))
+
+	for {
+		sig := <-done
+		if sig == maintenance.Signal {
+			slog.Info("maintenance mode changed", slog.Bool("enabled", mode.Toggle()))
+			continue
+		}
+
+		if sig == syscall.SIGHUP {
+			newCfg, err := config.Load(cfg.Path)
+			if err != nil {
+				slog.Error("config reload failed", slog.String("error", err.Error()))
+				continue
+			}
+
+			reload(cfg, newCfg, timeout, limiter, bodyLimit, tenants, mode, injector, validate)
+			cfg = newCfg
+			continue
+		}
+
+		if sig != upgrade.Signal {
+			break
+		}
+
+		// hand the listeners over to a new process, then drain this one
+		slog.Info("upgrading server")
+		if err := upg.Upgrade(); err != nil {
+			slog.Error("upgrade failed", slog.String("error", err.Error()))
+			continue
+		}
+
+		break
+	}
 
 	slog.Info("shutting down the server")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -76,5 +452,77 @@ func main() {
 		slog.Error("failed to shutdown server", slog.String("error", err.Error()))
 	}
 
+	if err := adminServer.Shutdown(ctx); err != nil {
+		slog.Error("failed to shutdown admin server", slog.String("error", err.Error()))
+	}
+
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+	}
+
+	if err := scheduler.Stop(ctx); err != nil {
+		slog.Error("scheduled tasks did not stop in time", slog.String("error", err.Error()))
+	}
+
+	// unfinished jobs are picked up again by the next process
+	if err := queue.Stop(ctx); err != nil {
+		slog.Error("jobs still running at shutdown were cancelled", slog.String("error", err.Error()))
+	}
+
+	// a last snapshot has the writes made since the one before
+	if replicator != nil {
+		stopReplicating()
+
+		final, cancelFinal := context.WithTimeout(context.Background(), cfg.Replication.Timeout)
+		if err := replicator.Replicate(final); err != nil {
+			slog.Error("final database replication failed", slog.String("error", err.Error()))
+		}
+		cancelFinal()
+	}
+
+	// requests have finished, so nothing is published any more
+	if publisher != nil {
+		if err := publisher.Close(ctx); err != nil {
+			slog.Error("failed to close event publisher", slog.String("error", err.Error()))
+		}
+	}
+
 	slog.Info("server shoutdown successfully")
+
+	return nil
+}
+
+// reload applies the settings that can change without a restart and warns
+// about the ones that can't.
+func reload(old, cfg *config.Config, timeout *middleware.Timeout, limiter *middleware.Limiter, bodyLimit *middleware.BodyLimit, tenants *tenant.Resolver, mode *maintenance.Mode, injector *chaos.Injector, validate *validation.Validator) {
+	slog.SetLogLoggerLevel(cfg.SlogLevel())
+	timeout.Set(cfg.RequestTimeout)
+	limiter.SetLimits(cfg.MaxInFlight, cfg.MaxQueue, cfg.QueueTimeout)
+	bodyLimit.Set(int64(cfg.MaxBodySize))
+	tenants.Set(cfg.Tenancy.Policy())
+	mode.SetRetryAfter(cfg.Maintenance.RetryAfter)
+
+	// validated already, so this can't fail
+	injector.SetRules(cfg.Chaos.Rules)
+	validate.SetRules(cfg.Validation)
+	if cfg.Chaos.Enabled != old.Chaos.Enabled {
+		injector.Set(cfg.Chaos.Enabled)
+		slog.Warn("fault injection changed", slog.Bool("enabled", cfg.Chaos.Enabled))
+	}
+
+	if cfg.Addr != old.Addr || cfg.AdminServer.Addr != old.AdminServer.Addr || cfg.GrpcServer.Addr != old.GrpcServer.Addr || cfg.StoragePath != old.StoragePath || cfg.PidFile != old.PidFile || cfg.Publisher != old.Publisher || cfg.Jobs != old.Jobs || cfg.Email != old.Email || cfg.Sms != old.Sms || !reflect.DeepEqual(cfg.Webhooks, old.Webhooks) || !slices.Equal(cfg.Schedules, old.Schedules) || cfg.Backups != old.Backups || cfg.Replication != old.Replication || cfg.Retention != old.Retention || !maps.Equal(cfg.Tenancy.Databases, old.Tenancy.Databases) || cfg.Tenancy.HealthInterval != old.Tenancy.HealthInterval || !reflect.DeepEqual(cfg.Ldap, old.Ldap) || !reflect.DeepEqual(cfg.Files, old.Files) {
+		slog.Warn("listener, storage, publisher, job, webhook, email, sms, ldap, files, replication, schedule and pid file changes need a restart or upgrade to take effect")
+	}
+
+	slog.Info("config reloaded", slog.String("log_level", cfg.SlogLevel().String()))
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/config"
+	"github.com/cmanish049/students-api/internal/retention"
+	"github.com/cmanish049/students-api/internal/schedule"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/storage/sqlite"
+	"github.com/cmanish049/students-api/internal/storage/tenantdb"
+	"github.com/cmanish049/students-api/internal/tenant"
+)
+
+// snapshotPrefix names the files written by the backup task; the time stamp
+// after it sorts in creation order
+const snapshotPrefix = "students-api-"
+
+// tasks returns the scheduled tasks by the name used in the config.
+func tasks(cfg *config.Config, databases *tenantdb.Manager, store *tenantdb.Storage, students *studentsvc.Service) map[string]schedule.Task {
+	return map[string]schedule.Task{
+		"backup": func(ctx context.Context) error {
+			if err := snapshot(ctx, databases.Main(), cfg.Backups.Dir, cfg.Backups.Keep); err != nil {
+				return err
+			}
+
+			// tenant databases go to a directory per tenant, so each keeps
+			// its own newest snapshots
+			for _, id := range databases.Tenants() {
+				db, err := databases.For(tenant.With(ctx, id))
+				if err != nil {
+					return err
+				}
+
+				dir := filepath.Join(cfg.Backups.Dir, id)
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					return err
+				}
+
+				if err := snapshot(ctx, db, dir, cfg.Backups.Keep); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		"retention": func(ctx context.Context) error {
+			policy := retention.Policy{
+				Jobs:              cfg.Retention.Jobs,
+				DeadJobs:          cfg.Retention.DeadJobs,
+				WebhookDeliveries: cfg.Retention.WebhookDeliveries,
+			}
+			// every tenant's jobs and deliveries are kept as long
+			_, err := retention.Purge(tenant.All(ctx), store, policy, time.Now())
+			return err
+		},
+		"archive": func(ctx context.Context) error {
+			archived, err := students.Archive(tenant.All(ctx), time.Now().Add(-cfg.Archive.InactiveAfter))
+			slog.Info("inactive students archived", slog.Int("count", len(archived)))
+			return err
+		},
+	}
+}
+
+// snapshot writes a database snapshot to dir and removes all but the newest
+// keep snapshots.
+func snapshot(ctx context.Context, db *sqlite.Sqlite, dir string, keep int) error {
+	path := filepath.Join(dir, snapshotPrefix+time.Now().UTC().Format("20060102-150405")+".db")
+
+	if err := db.Snapshot(ctx, path); err != nil {
+		return fmt.Errorf("snapshot to %s: %w", path, err)
+	}
+
+	slog.Info("database snapshot written", slog.String("path", path))
+
+	old, err := filepath.Glob(filepath.Join(dir, snapshotPrefix+"*.db"))
+	if err != nil {
+		return err
+	}
+	slices.Sort(old)
+
+	for len(old) > keep {
+		if err := os.Remove(old[0]); err != nil {
+			return err
+		}
+		slog.Info("old database snapshot removed", slog.String("path", old[0]))
+		old = old[1:]
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+// Package auth logs users in with the credentials of a directory (LDAP or
+// Active Directory) and issues the session tokens they then authenticate
+// with like with a tenant token, limited to the role of their groups.
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/ldap"
+	"github.com/cmanish049/students-api/internal/tenant"
+)
+
+// Roles are what users may do. Tenant tokens act as editors.
+const (
+	// RoleViewer reads only; its writes other than dry runs are refused
+	RoleViewer = "viewer"
+	// RoleEditor reads and writes
+	RoleEditor = "editor"
+)
+
+// Roles lists the roles groups can map to.
+var Roles = []string{RoleViewer, RoleEditor}
+
+// ErrInvalidCredentials is a login with an unknown username or a wrong
+// password, which aren't told apart.
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// ErrNoRole is a login of a user in none of the configured groups.
+var ErrNoRole = errors.New("user has no role")
+
+// Group maps the members of a directory group, by its DN, to a role for a
+// tenant, Default if empty.
+type Group struct {
+	Dn     string `yaml:"dn"`
+	Role   string `yaml:"role"`
+	Tenant string `yaml:"tenant"`
+}
+
+// Config is the directory users log in with.
+type Config struct {
+	// Url is ldap://host[:port] or ldaps://host[:port]
+	Url string
+	// StartTls upgrades ldap:// connections to TLS
+	StartTls bool
+	// CaFile has the PEM certificates trusted for TLS instead of the system
+	// ones, for directories with a certificate of a private CA
+	CaFile string
+	// BindDn and BindPassword are the service account users are searched
+	// with; empty searches anonymously
+	BindDn       string
+	BindPassword string
+	// BaseDn is where users are searched, UserFilter how, with {username}
+	// in place of the escaped username
+	BaseDn     string
+	UserFilter string
+	// GroupAttribute of the user entry lists the DNs of its groups, as
+	// memberOf does in Active Directory
+	GroupAttribute string
+	// Groups are checked in order; the first the user is a member of gives
+	// its role and tenant
+	Groups  []Group
+	Timeout time.Duration
+}
+
+// Directory logs users in against a directory server.
+type Directory struct {
+	cfg      Config
+	tls      *tls.Config
+	sessions *Sessions
+}
+
+// New returns a directory that issues sessions for its users.
+func New(cfg Config, sessions *Sessions) (*Directory, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CaFile != "" {
+		pem, err := os.ReadFile(cfg.CaFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", cfg.CaFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if _, err := ldap.CompileFilter(userFilter(cfg.UserFilter, "user")); err != nil {
+		return nil, err
+	}
+
+	return &Directory{cfg: cfg, tls: tlsConfig, sessions: sessions}, nil
+}
+
+// Login checks the credentials of username with the directory and returns
+// a session for the role of its groups, with its token. Credentials the
+// directory refuses are ErrInvalidCredentials, and a user in none of the
+// groups is ErrNoRole.
+func (d *Directory) Login(ctx context.Context, username, password string) (Session, string, error) {
+	if username == "" || password == "" {
+		return Session{}, "", ErrInvalidCredentials
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	conn, err := ldap.Dial(ctx, d.cfg.Url, d.tls, d.cfg.StartTls)
+	if err != nil {
+		return Session{}, "", fmt.Errorf("cannot connect to the directory: %w", err)
+	}
+	defer conn.Close()
+
+	if d.cfg.BindDn != "" {
+		if err := conn.Bind(d.cfg.BindDn, d.cfg.BindPassword); err != nil {
+			return Session{}, "", fmt.Errorf("cannot bind the service account: %w", err)
+		}
+	}
+
+	// a second match is enough to know the username is ambiguous
+	entries, err := conn.Search(d.cfg.BaseDn, userFilter(d.cfg.UserFilter, username), []string{d.cfg.GroupAttribute}, 2)
+	var ldapErr *ldap.Error
+	if err != nil && !(errors.As(err, &ldapErr) && ldapErr.Code == ldap.ResultSizeLimitExceeded) {
+		return Session{}, "", fmt.Errorf("cannot search the directory: %w", err)
+	}
+	if len(entries) != 1 {
+		return Session{}, "", ErrInvalidCredentials
+	}
+	user := entries[0]
+
+	if err := conn.Bind(user.DN, password); err != nil {
+		if ldap.IsInvalidCredentials(err) {
+			return Session{}, "", ErrInvalidCredentials
+		}
+		return Session{}, "", fmt.Errorf("cannot bind as %s: %w", user.DN, err)
+	}
+
+	group, ok := d.group(user.Values(d.cfg.GroupAttribute))
+	if !ok {
+		return Session{}, "", ErrNoRole
+	}
+
+	session, token := d.sessions.Issue(username, group.Role, group.Tenant)
+
+	return session, token, nil
+}
+
+// group returns the first configured group among the DNs memberOf.
+func (d *Directory) group(memberOf []string) (Group, bool) {
+	for _, g := range d.cfg.Groups {
+		if slices.ContainsFunc(memberOf, func(dn string) bool { return sameDn(dn, g.Dn) }) {
+			if g.Tenant == "" {
+				g.Tenant = tenant.Default
+			}
+			return g, true
+		}
+	}
+
+	return Group{}, false
+}
+
+// sameDn compares DNs regardless of case and of spaces after separators,
+// as "CN=Teachers, OU=Groups" and "cn=teachers,ou=groups" name the same
+// group.
+func sameDn(a, b string) bool {
+	normalize := func(dn string) string {
+		parts := strings.Split(dn, ",")
+		for i, p := range parts {
+			parts[i] = strings.TrimSpace(p)
+		}
+		return strings.Join(parts, ",")
+	}
+
+	return strings.EqualFold(normalize(a), normalize(b))
+}
+
+func userFilter(filter, username string) string {
+	return strings.ReplaceAll(filter, "{username}", ldap.EscapeFilter(username))
+}
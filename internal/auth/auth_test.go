@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/cmanish049/students-api/internal/tenant"
+)
+
+func TestDirectoryGroup(t *testing.T) {
+	d := &Directory{cfg: Config{Groups: []Group{
+		{Dn: "CN=Admins, OU=Groups, DC=example, DC=com", Role: RoleEditor, Tenant: "north"},
+		{Dn: "cn=teachers,ou=groups,dc=example,dc=com", Role: RoleEditor},
+		{Dn: "cn=staff,ou=groups,dc=example,dc=com", Role: RoleViewer},
+	}}}
+
+	tests := []struct {
+		name     string
+		memberOf []string
+		want     Group
+		ok       bool
+	}{
+		{"none", nil, Group{}, false},
+		{"other groups", []string{"cn=students,ou=groups,dc=example,dc=com"}, Group{}, false},
+		{"case and spaces", []string{"CN=Teachers, OU=Groups,DC=Example,DC=com"}, Group{Dn: "cn=teachers,ou=groups,dc=example,dc=com", Role: RoleEditor, Tenant: tenant.Default}, true},
+		{"configured spaces", []string{"cn=admins,ou=groups,dc=example,dc=com"}, Group{Dn: "CN=Admins, OU=Groups, DC=example, DC=com", Role: RoleEditor, Tenant: "north"}, true},
+		// the first configured group wins, whatever the order of memberOf
+		{"order", []string{"cn=staff,ou=groups,dc=example,dc=com", "cn=teachers,ou=groups,dc=example,dc=com"}, Group{Dn: "cn=teachers,ou=groups,dc=example,dc=com", Role: RoleEditor, Tenant: tenant.Default}, true},
+		{"parent", []string{"ou=groups,dc=example,dc=com"}, Group{}, false},
+		{"child", []string{"cn=x,cn=staff,ou=groups,dc=example,dc=com"}, Group{}, false},
+		{"prefix", []string{"cn=staff"}, Group{}, false},
+	}
+
+	for _, tt := range tests {
+		if got, ok := d.group(tt.memberOf); got != tt.want || ok != tt.ok {
+			t.Errorf("%s: group(%q) = %+v, %v; want %+v, %v", tt.name, tt.memberOf, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestUserFilter(t *testing.T) {
+	got := userFilter("(&(objectClass=user)(sAMAccountName={username}))", "*)(objectClass=*")
+	want := `(&(objectClass=user)(sAMAccountName=\2a\29\28objectClass=\2a))`
+	if got != want {
+		t.Errorf("userFilter = %s, want %s", got, want)
+	}
+}
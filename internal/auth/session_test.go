@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	sessions := NewSessions("secret", time.Hour)
+
+	issued, token := sessions.Issue("jdoe", RoleViewer, "north")
+	if issued.User != "jdoe" || issued.Role != RoleViewer || issued.Tenant != "north" || time.Until(issued.ExpiresAt) <= 59*time.Minute {
+		t.Errorf("issued session = %+v", issued)
+	}
+
+	session, ok := sessions.Verify(token)
+	if !ok || session != issued {
+		t.Errorf("Verify = %+v, %v; want %+v", session, ok, issued)
+	}
+
+	if id, ok := sessions.Tenant(token); !ok || id != "north" {
+		t.Errorf("Tenant = %q, %v; want north", id, ok)
+	}
+}
+
+func TestSessionsVerifyRejects(t *testing.T) {
+	sessions := NewSessions("secret", time.Hour)
+	_, token := sessions.Issue("jdoe", RoleViewer, "north")
+	payload, signature, _ := strings.Cut(token, ".")
+
+	// the same session as an editor, with the viewer's signature
+	b, _ := base64.RawURLEncoding.DecodeString(payload)
+	editor := base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(b), `"role":"viewer"`, `"role":"editor"`, 1)))
+	_, otherToken := sessions.Issue("ann", RoleEditor, "south")
+	_, otherSignature, _ := strings.Cut(otherToken, ".")
+	_, expired := NewSessions("secret", -time.Minute).Issue("jdoe", RoleViewer, "north")
+	_, wrongSecret := NewSessions("other secret", time.Hour).Issue("jdoe", RoleViewer, "north")
+	notJson := base64.RawURLEncoding.EncodeToString([]byte("jdoe"))
+
+	tests := map[string]string{
+		"empty":             "",
+		"no signature":      payload,
+		"empty signature":   payload + ".",
+		"tampered payload":  editor + "." + signature,
+		"tampered sig":      payload + "." + strings.ToUpper(signature),
+		"other signature":   payload + "." + otherSignature,
+		"expired":           expired,
+		"wrong secret":      wrongSecret,
+		"not base64":        "!!!." + sessions.sign("!!!"),
+		"not json":          notJson + "." + sessions.sign(notJson),
+		"signature of none": "." + sessions.sign(""),
+	}
+
+	for name, token := range tests {
+		if session, ok := sessions.Verify(token); ok {
+			t.Errorf("%s: Verify(%q) = %+v, want it rejected", name, token, session)
+		}
+		if _, ok := sessions.Tenant(token); ok {
+			t.Errorf("%s: Tenant(%q) accepted", name, token)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+const (
+	// Format identifies a backup file
+	Format = "students-api-backup"
+
+	// Version is bumped when the layout of Dump changes incompatibly
+	Version = 1
+)
+
+// Dump is a storage-independent snapshot of everything worth restoring.
+// The webhook delivery log is not included.
+type Dump struct {
+	Format    string          `json:"format"`
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Students  []types.Student `json:"students"`
+	Webhooks  []types.Webhook `json:"webhooks"`
+}
+
+func New(students []types.Student, webhooks []types.Webhook) Dump {
+	if students == nil {
+		students = []types.Student{}
+	}
+	if webhooks == nil {
+		webhooks = []types.Webhook{}
+	}
+
+	return Dump{
+		Format:    Format,
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+		Students:  students,
+		Webhooks:  webhooks,
+	}
+}
+
+func WriteJSON(w io.Writer, d Dump) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(d)
+}
+
+func ReadJSON(r io.Reader) (Dump, error) {
+	var d Dump
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return Dump{}, fmt.Errorf("decode backup: %w", err)
+	}
+
+	if d.Format != Format {
+		return Dump{}, fmt.Errorf("not a students-api backup")
+	}
+	if d.Version != Version {
+		return Dump{}, fmt.Errorf("unsupported backup version %d (this build reads version %d)", d.Version, Version)
+	}
+
+	return d, nil
+}
+
+// WriteSQL writes the dump as plain INSERT statements in one transaction.
+// Only standard SQL is used so the file loads into SQLite and Postgres
+// alike; the schema has to exist already (students-api migrate).
+func WriteSQL(w io.Writer, d Dump) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "-- %s version %d, created %s\n", Format, Version, d.CreatedAt.Format(time.RFC3339))
+	b.WriteString("-- load into an empty database created by \"students-api migrate\"\n")
+	b.WriteString("BEGIN;\n")
+
+	for _, s := range d.Students {
+		if !s.ArchivedAt.IsZero() {
+			fmt.Fprintf(&b, "INSERT INTO archived_students (id, tenant_id, name, email, age, created_at, updated_at, email_verified, email_verified_at, archived_at) VALUES (%d, %s, %s, %s, %d, %s, %s, %d, %s, %s);\n",
+				s.Id, tenantId(s.TenantId), quote(s.Name), quote(s.Email), s.Age, timestamp(s.CreatedAt), timestamp(s.UpdatedAt), boolean(s.EmailVerified), nullTimestamp(s.EmailVerifiedAt), timestamp(s.ArchivedAt))
+			continue
+		}
+		fmt.Fprintf(&b, "INSERT INTO students (id, tenant_id, name, email, age, created_at, updated_at, email_verified, email_verified_at) VALUES (%d, %s, %s, %s, %d, %s, %s, %d, %s);\n",
+			s.Id, tenantId(s.TenantId), quote(s.Name), quote(s.Email), s.Age, timestamp(s.CreatedAt), timestamp(s.UpdatedAt), boolean(s.EmailVerified), nullTimestamp(s.EmailVerifiedAt))
+	}
+
+	for _, h := range d.Webhooks {
+		fmt.Fprintf(&b, "INSERT INTO webhooks (id, tenant_id, url, secret, events, created_at) VALUES (%d, %s, %s, %s, %s, %s);\n",
+			h.Id, tenantId(h.TenantId), quote(h.Url), quote(h.Secret), quote(strings.Join(h.Events, ",")), timestamp(h.CreatedAt))
+	}
+
+	b.WriteString("COMMIT;\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// timestamp is t as a SQL literal; records of backups from before they had
+// timestamps get the time of the restore.
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return "CURRENT_TIMESTAMP"
+	}
+
+	return quote(t.UTC().Format("2006-01-02 15:04:05.999999999"))
+}
+
+// nullTimestamp is t as a SQL literal, NULL if t is zero.
+func nullTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return "NULL"
+	}
+
+	return timestamp(t)
+}
+
+func boolean(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// tenantId is id as a SQL literal; records of backups from before tenants
+// belong to the default tenant.
+func tenantId(id string) string {
+	if id == "" {
+		return quote(tenant.Default)
+	}
+
+	return quote(id)
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,53 @@
+// Package blob keeps blobs, such as the contents of uploaded files and
+// database snapshots, in a store: the local disk or an S3 compatible
+// bucket. It hands out signed URLs that download them directly from it.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"time"
+)
+
+// ErrNotFound is a key with no blob.
+var ErrNotFound = errors.New("blob not found")
+
+// MaxUrlTtl is the longest a signed URL can be valid, the limit of S3.
+const MaxUrlTtl = 7 * 24 * time.Hour
+
+// Store keeps blobs by key. Keys are slash separated paths without "."
+// or ".." elements.
+type Store interface {
+	// Put stores the size bytes of body under key, replacing any blob
+	// there. Readers of key see the old blob or the new one, never part of
+	// it.
+	Put(ctx context.Context, key, contentType string, size int64, body io.Reader) error
+	// Get returns the blob under key, ErrNotFound if there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key; a missing one is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns a URL that downloads the blob under key for ttl, as an
+	// attachment named filename of contentType.
+	URL(ctx context.Context, key, filename, contentType string, ttl time.Duration) (string, error)
+	// List returns the blobs whose key starts with prefix, ordered by key.
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// Object is a blob returned by List.
+type Object struct {
+	Key      string
+	Size     int64
+	Modified time.Time
+}
+
+// Disposition is the Content-Disposition of a download named filename.
+func Disposition(filename string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+}
+
+func validKey(key string) bool {
+	return fs.ValidPath(key) && key != "."
+}
@@ -0,0 +1,200 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalPath is where the signed URLs of Local are served.
+const LocalPath = "/api/v1/blobs/"
+
+// Local keeps blobs as files under a directory. Its signed URLs point to
+// LocalPath, where the API serves them after checking their signature
+// (see Verify), so downloads don't need a token.
+type Local struct {
+	dir     string
+	baseUrl string
+	secret  []byte
+}
+
+// NewLocal returns a store in dir, creating it if needed, whose URLs start
+// with baseUrl (empty for URLs relative to the API) and are signed with
+// secret.
+func NewLocal(dir, baseUrl, secret string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	return &Local{dir: dir, baseUrl: strings.TrimSuffix(baseUrl, "/"), secret: []byte(secret)}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes body to a temporary file renamed to the blob once complete.
+func (l *Local) Put(ctx context.Context, key, contentType string, size int64, body io.Reader) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	n, err := io.Copy(f, body)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("blob %s is %d bytes, expected %d", key, n, size)
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return f, err
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (l *Local) List(ctx context.Context, prefix string) ([]Object, error) {
+	// only the directory the prefix is in can have matching files
+	root := l.dir
+	if i := strings.LastIndex(prefix, "/"); i > 0 {
+		if !validKey(prefix[:i]) {
+			return nil, fmt.Errorf("invalid blob prefix %q", prefix)
+		}
+		root = filepath.Join(l.dir, filepath.FromSlash(prefix[:i]))
+	}
+
+	var objects []Object
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return err
+		}
+
+		rel, err := filepath.Rel(l.dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size(), Modified: info.ModTime().UTC()})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(objects, func(a, b Object) int { return strings.Compare(a.Key, b.Key) })
+
+	return objects, nil
+}
+
+func (l *Local) URL(ctx context.Context, key, filename, contentType string, ttl time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("name", filename)
+	q.Set("type", contentType)
+	q.Set("signature", l.sign(key, expires, filename, contentType))
+
+	return l.baseUrl + LocalPath + (&url.URL{Path: key}).EscapedPath() + "?" + q.Encode(), nil
+}
+
+// Verify checks the query of a URL of key returned by URL and returns the
+// filename and content type it downloads the blob as. URLs that were
+// changed or have expired are refused.
+func (l *Local) Verify(key string, query url.Values) (filename, contentType string, ok bool) {
+	expires, filename, contentType := query.Get("expires"), query.Get("name"), query.Get("type")
+
+	signature := l.sign(key, expires, filename, contentType)
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(signature)) {
+		return "", "", false
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return "", "", false
+	}
+
+	return filename, contentType, true
+}
+
+func (l *Local) sign(key, expires, filename, contentType string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(strings.Join([]string{key, expires, filename, contentType}, "\n")))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,178 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/jobs"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// job kinds of bulk operations started through the API
+const (
+	ImportKind = "students.import"
+	ExportKind = "students.export"
+)
+
+// progressEvery is how many records are processed between progress updates
+const progressEvery = 100
+
+// Formats lists the export formats with their content types.
+var Formats = map[string]string{
+	"json": "application/json",
+	"csv":  "text/csv; charset=utf-8",
+}
+
+type importPayload struct {
+	Students []types.Student `json:"students"`
+}
+
+type exportPayload struct {
+	Format string `json:"format"`
+}
+
+// RecordError reports why one student of an import was not created.
+type RecordError struct {
+	Index int    `json:"index"`
+	Email string `json:"email,omitempty"`
+	Error string `json:"error"`
+}
+
+type ImportResult struct {
+	Total   int           `json:"total"`
+	Created int           `json:"created"`
+	Errors  []RecordError `json:"errors,omitempty"`
+}
+
+type ExportResult struct {
+	Format string `json:"format"`
+	Count  int    `json:"count"`
+}
+
+// Bulk runs imports and exports as jobs on the queue.
+type Bulk struct {
+	queue    *jobs.Queue
+	students *studentsvc.Service
+}
+
+// Register adds the bulk job kinds to queue. Imports are not retried, since
+// a second attempt would find the students of the first one; the result of
+// every import says which records failed.
+func Register(queue *jobs.Queue, students *studentsvc.Service) *Bulk {
+	b := &Bulk{queue: queue, students: students}
+
+	queue.Register(ImportKind, 1, 0, b.runImport)
+	queue.Register(ExportKind, 3, 5*time.Second, b.runExport)
+
+	return b
+}
+
+// Import queues the creation of students and returns the job id.
+func (b *Bulk) Import(ctx context.Context, students []types.Student) (int64, error) {
+	return b.queue.Enqueue(ctx, ImportKind, importPayload{Students: students})
+}
+
+// Export queues an export of all students in format and returns the job id.
+func (b *Bulk) Export(ctx context.Context, format string) (int64, error) {
+	if _, ok := Formats[format]; !ok {
+		return 0, fmt.Errorf("unknown format %q, use json or csv", format)
+	}
+
+	return b.queue.Enqueue(ctx, ExportKind, exportPayload{Format: format})
+}
+
+func (b *Bulk) runImport(ctx context.Context, job types.Job) error {
+	var p importPayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return err
+	}
+
+	res := ImportResult{Total: len(p.Students)}
+
+	for i, student := range p.Students {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if i%progressEvery == 0 {
+			b.progress(ctx, job.Id, i, res.Total)
+		}
+
+		if _, err := b.students.Create(ctx, student); err != nil {
+			res.Errors = append(res.Errors, RecordError{Index: i, Email: student.Email, Error: err.Error()})
+			continue
+		}
+		res.Created++
+	}
+
+	b.progress(ctx, job.Id, res.Total, res.Total)
+
+	slog.Info("students imported", slog.Int64("job_id", job.Id), slog.Int("created", res.Created), slog.Int("failed", len(res.Errors)))
+
+	return b.queue.SetResult(ctx, job.Id, res, nil)
+}
+
+func (b *Bulk) runExport(ctx context.Context, job types.Job) error {
+	var p exportPayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return err
+	}
+
+	students, err := b.students.List(ctx, storage.StudentFilter{})
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if p.Format == "csv" {
+		err = WriteCSV(&buf, students)
+	} else {
+		err = WriteJSON(&buf, students)
+	}
+	if err != nil {
+		return err
+	}
+
+	b.progress(ctx, job.Id, len(students), len(students))
+
+	return b.queue.SetResult(ctx, job.Id, ExportResult{Format: p.Format, Count: len(students)}, buf.Bytes())
+}
+
+func (b *Bulk) progress(ctx context.Context, id int64, done, total int) {
+	if err := b.queue.Progress(ctx, id, done, total); err != nil {
+		slog.Warn("failed to record job progress", slog.Int64("job_id", id), slog.String("error", err.Error()))
+	}
+}
+
+// WriteJSON writes students as an indented JSON array.
+func WriteJSON(w io.Writer, students []types.Student) error {
+	if students == nil {
+		students = []types.Student{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(students)
+}
+
+// WriteCSV writes students as CSV with a header row.
+func WriteCSV(w io.Writer, students []types.Student) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "name", "email", "age", "created_at", "updated_at"})
+	for _, s := range students {
+		cw.Write([]string{strconv.Itoa(s.Id), s.Name, s.Email, strconv.Itoa(s.Age), s.CreatedAt.Format(time.RFC3339), s.UpdatedAt.Format(time.RFC3339)})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
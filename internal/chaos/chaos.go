@@ -0,0 +1,156 @@
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Rule injects faults into requests matching Route, a http.ServeMux pattern
+// such as "GET /api/v1/students/{id}" or "/" for everything. Rates are
+// probabilities between 0 and 1, rolled independently per request.
+type Rule struct {
+	Route       string        `yaml:"route"`
+	Latency     time.Duration `yaml:"latency"`
+	LatencyRate float64       `yaml:"latency_rate"`
+	ErrorRate   float64       `yaml:"error_rate"`
+	ErrorStatus int           `yaml:"error_status"`
+	DropRate    float64       `yaml:"drop_rate"`
+}
+
+// Injector holds the fault injection rules and the switch that turns them
+// on. It is off unless enabled, so rules can stay configured in staging.
+type Injector struct {
+	enabled atomic.Bool
+
+	mu    sync.RWMutex
+	rules []Rule
+	mux   *http.ServeMux
+	byPat map[string]Rule
+}
+
+func New(enabled bool, rules []Rule) (*Injector, error) {
+	i := &Injector{}
+	if err := i.SetRules(rules); err != nil {
+		return nil, err
+	}
+	i.Set(enabled)
+
+	return i, nil
+}
+
+func (i *Injector) Enabled() bool {
+	return i.enabled.Load()
+}
+
+func (i *Injector) Set(enabled bool) {
+	i.enabled.Store(enabled)
+}
+
+// Rules returns the current rules.
+func (i *Injector) Rules() []Rule {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return append([]Rule(nil), i.rules...)
+}
+
+// SetRules replaces the rules; on error the old ones are kept.
+func (i *Injector) SetRules(rules []Rule) error {
+	mux, byPat, err := compile(rules)
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.rules = append([]Rule(nil), rules...)
+	i.mux = mux
+	i.byPat = byPat
+
+	return nil
+}
+
+// Match returns the rule for r, picking the most specific route like the
+// router does.
+func (i *Injector) Match(r *http.Request) (Rule, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	_, pattern := i.mux.Handler(r)
+	rule, ok := i.byPat[pattern]
+
+	return rule, ok
+}
+
+// Check reports problems with rules without applying them.
+func Check(rules []Rule) error {
+	_, _, err := compile(rules)
+	return err
+}
+
+func compile(rules []Rule) (mux *http.ServeMux, byPat map[string]Rule, err error) {
+	mux = http.NewServeMux()
+	byPat = map[string]Rule{}
+
+	var errs []error
+	for n, rule := range rules {
+		if err := rule.check(); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", n+1, err))
+			continue
+		}
+
+		if _, dup := byPat[rule.Route]; dup {
+			errs = append(errs, fmt.Errorf("rule %d: route %q is already used by another rule", n+1, rule.Route))
+			continue
+		}
+
+		if err := register(mux, rule.Route); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: route %q: %v", n+1, rule.Route, err))
+			continue
+		}
+		byPat[rule.Route] = rule
+	}
+
+	return mux, byPat, errors.Join(errs...)
+}
+
+// register adds a pattern, turning ServeMux's panics on invalid or
+// conflicting patterns into errors.
+func register(mux *http.ServeMux, pattern string) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+
+	mux.HandleFunc(pattern, func(http.ResponseWriter, *http.Request) {})
+
+	return nil
+}
+
+func (r Rule) check() error {
+	if r.Route == "" {
+		return errors.New("route is required")
+	}
+
+	for name, rate := range map[string]float64{"latency_rate": r.LatencyRate, "error_rate": r.ErrorRate, "drop_rate": r.DropRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+
+	if r.LatencyRate > 0 && r.Latency <= 0 {
+		return errors.New("latency must be positive when latency_rate is set")
+	}
+
+	if r.ErrorRate > 0 && r.ErrorStatus != 0 && (r.ErrorStatus < 500 || r.ErrorStatus > 599) {
+		return errors.New("error_status must be a 5xx status")
+	}
+
+	return nil
+}
@@ -1,48 +1,443 @@
 package config
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/cmanish049/students-api/internal/auth"
+	"github.com/cmanish049/students-api/internal/chaos"
+	"github.com/cmanish049/students-api/internal/secrets"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/validation"
 	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/joho/godotenv"
 )
 
+// Every field can be overridden by the environment variable in its env tag,
+// and the significant ones by a command-line flag (see flags.go).
+// Precedence is flag > environment > config file > default.
+
 type HttpServer struct {
-	Addr string `yaml:"address" env-requred:"true"`
+	Addr           string        `yaml:"address" env:"STUDENTS_API_ADDR" env-requred:"true"`
+	RequestTimeout time.Duration `yaml:"request_timeout" env:"STUDENTS_API_REQUEST_TIMEOUT" env-default:"30s"`
+	MaxInFlight    int           `yaml:"max_in_flight" env:"STUDENTS_API_MAX_IN_FLIGHT" env-default:"100"`
+	MaxQueue       int           `yaml:"max_queue" env:"STUDENTS_API_MAX_QUEUE" env-default:"100"`
+	QueueTimeout   time.Duration `yaml:"queue_timeout" env:"STUDENTS_API_QUEUE_TIMEOUT" env-default:"1s"`
+	MaxBodySize    int           `yaml:"max_body_size" env:"STUDENTS_API_MAX_BODY_SIZE" env-default:"10485760"`
+}
+
+// AdminServer is the listener for operational endpoints (health, pprof, ...).
+// It should be bound to localhost or a cluster-internal address.
+type AdminServer struct {
+	Addr string `yaml:"address" env:"STUDENTS_API_ADMIN_ADDR" env-default:"localhost:8083"`
+}
+
+// GrpcServer is the listener for the gRPC StudentService. Empty disables it.
+type GrpcServer struct {
+	Addr string `yaml:"address" env:"STUDENTS_API_GRPC_ADDR"`
+}
+
+// Webhooks controls delivery of events to registered webhooks. Failed
+// deliveries are retried after retry_backoff, doubling on every attempt.
+// Webhooks are never at loopback, link-local or unspecified addresses, nor
+// private ones unless allowed_hosts is set; this limits them to its hosts,
+// and with "*.example.com" to the subdomains of example.com.
+type Webhooks struct {
+	MaxAttempts  int           `yaml:"max_attempts" env:"STUDENTS_API_WEBHOOKS_MAX_ATTEMPTS" env-default:"5"`
+	Timeout      time.Duration `yaml:"timeout" env:"STUDENTS_API_WEBHOOKS_TIMEOUT" env-default:"10s"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" env:"STUDENTS_API_WEBHOOKS_RETRY_BACKOFF" env-default:"1s"`
+	AllowedHosts []string      `yaml:"allowed_hosts" env:"STUDENTS_API_WEBHOOKS_ALLOWED_HOSTS"`
+}
+
+// Jobs runs queued background work such as webhook deliveries. A job that
+// runs longer than lease is cancelled, and one whose process went away is
+// picked up again once its lease runs out.
+type Jobs struct {
+	Workers      int           `yaml:"workers" env:"STUDENTS_API_JOBS_WORKERS" env-default:"8"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"STUDENTS_API_JOBS_POLL_INTERVAL" env-default:"1s"`
+	Lease        time.Duration `yaml:"lease" env:"STUDENTS_API_JOBS_LEASE" env-default:"5m"`
+}
+
+// Email notifies students of changes to their record through an SMTP
+// server. An empty host disables it. templates is an optional directory of
+// <name>.tmpl files replacing the built-in welcome, updated and verify
+// templates. New emails are sent a token, valid for verification_ttl, that
+// verifies them; verify_url is the link sent with it, "{token}" replaced.
+type Email struct {
+	Host         string        `yaml:"host" env:"STUDENTS_API_EMAIL_HOST"`
+	Port         int           `yaml:"port" env:"STUDENTS_API_EMAIL_PORT" env-default:"587"`
+	Username     string        `yaml:"username" env:"STUDENTS_API_EMAIL_USERNAME"`
+	Password     string        `yaml:"password" env:"STUDENTS_API_EMAIL_PASSWORD"`
+	From         string        `yaml:"from" env:"STUDENTS_API_EMAIL_FROM"`
+	Templates    string        `yaml:"templates" env:"STUDENTS_API_EMAIL_TEMPLATES"`
+	Timeout      time.Duration `yaml:"timeout" env:"STUDENTS_API_EMAIL_TIMEOUT" env-default:"30s"`
+	MaxAttempts  int           `yaml:"max_attempts" env:"STUDENTS_API_EMAIL_MAX_ATTEMPTS" env-default:"5"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" env:"STUDENTS_API_EMAIL_RETRY_BACKOFF" env-default:"1m"`
+
+	VerifyUrl       string        `yaml:"verify_url" env:"STUDENTS_API_EMAIL_VERIFY_URL"`
+	VerificationTtl time.Duration `yaml:"verification_ttl" env:"STUDENTS_API_EMAIL_VERIFICATION_TTL" env-default:"48h"`
+}
+
+// Sms sends text messages through the twilio Messages API (url overrides
+// its base URL for compatible gateways) or a generic http gateway that takes
+// JSON at url. An empty provider disables it. Sending is limited to
+// rate_per_minute messages; the rest wait in the job queue.
+type Sms struct {
+	Provider      string        `yaml:"provider" env:"STUDENTS_API_SMS_PROVIDER"`
+	Url           string        `yaml:"url" env:"STUDENTS_API_SMS_URL"`
+	AccountSid    string        `yaml:"account_sid" env:"STUDENTS_API_SMS_ACCOUNT_SID"`
+	Token         string        `yaml:"token" env:"STUDENTS_API_SMS_TOKEN"`
+	From          string        `yaml:"from" env:"STUDENTS_API_SMS_FROM"`
+	RatePerMinute int           `yaml:"rate_per_minute" env:"STUDENTS_API_SMS_RATE_PER_MINUTE" env-default:"60"`
+	Timeout       time.Duration `yaml:"timeout" env:"STUDENTS_API_SMS_TIMEOUT" env-default:"10s"`
+	MaxAttempts   int           `yaml:"max_attempts" env:"STUDENTS_API_SMS_MAX_ATTEMPTS" env-default:"3"`
+	RetryBackoff  time.Duration `yaml:"retry_backoff" env:"STUDENTS_API_SMS_RETRY_BACKOFF" env-default:"30s"`
+}
+
+// Files keeps the files uploaded for students (photos, documents) in a
+// blob store: backend local writes them under dir, s3 to a bucket of S3
+// or an S3 compatible service. An empty backend disables uploads. Only
+// files of allowed_types are accepted. They are downloaded through signed
+// URLs valid for url_ttl; those of the local backend are signed with
+// url_secret and start with public_url, or are relative to the API if it
+// is empty.
+type Files struct {
+	Backend      string        `yaml:"backend" env:"STUDENTS_API_FILES_BACKEND"`
+	Dir          string        `yaml:"dir" env:"STUDENTS_API_FILES_DIR" env-default:"storage/files"`
+	PublicUrl    string        `yaml:"public_url" env:"STUDENTS_API_FILES_PUBLIC_URL"`
+	UrlSecret    string        `yaml:"url_secret" env:"STUDENTS_API_FILES_URL_SECRET"`
+	UrlTtl       time.Duration `yaml:"url_ttl" env:"STUDENTS_API_FILES_URL_TTL" env-default:"15m"`
+	AllowedTypes []string      `yaml:"allowed_types" env:"STUDENTS_API_FILES_ALLOWED_TYPES" env-default:"image/jpeg,image/png,image/webp,application/pdf"`
+	S3           S3            `yaml:"s3"`
+}
+
+// S3 is the bucket of the s3 backend. endpoint defaults to AWS in region;
+// compatible services (MinIO, Ceph...) usually need path_style. The
+// credentials fall back to the standard AWS environment variables.
+type S3 struct {
+	Endpoint     string        `yaml:"endpoint" env:"STUDENTS_API_FILES_S3_ENDPOINT"`
+	Region       string        `yaml:"region" env:"STUDENTS_API_FILES_S3_REGION,AWS_REGION"`
+	Bucket       string        `yaml:"bucket" env:"STUDENTS_API_FILES_S3_BUCKET"`
+	AccessKey    string        `yaml:"access_key" env:"STUDENTS_API_FILES_S3_ACCESS_KEY,AWS_ACCESS_KEY_ID"`
+	SecretKey    string        `yaml:"secret_key" env:"STUDENTS_API_FILES_S3_SECRET_KEY,AWS_SECRET_ACCESS_KEY"`
+	SessionToken string        `yaml:"session_token" env:"STUDENTS_API_FILES_S3_SESSION_TOKEN,AWS_SESSION_TOKEN"`
+	PathStyle    bool          `yaml:"path_style" env:"STUDENTS_API_FILES_S3_PATH_STYLE"`
+	Timeout      time.Duration `yaml:"timeout" env:"STUDENTS_API_FILES_S3_TIMEOUT" env-default:"5m"`
+}
+
+// Publisher sends every event to Kafka or NATS. An empty backend disables it.
+// For kafka, address is a comma separated broker list and topic the topic;
+// for nats, address is the server URL and events go to "<topic>.<type>".
+type Publisher struct {
+	Backend    string `yaml:"backend" env:"STUDENTS_API_PUBLISHER_BACKEND"`
+	Address    string `yaml:"address" env:"STUDENTS_API_PUBLISHER_ADDR"`
+	Topic      string `yaml:"topic" env:"STUDENTS_API_PUBLISHER_TOPIC" env-default:"students.events"`
+	BufferSize int    `yaml:"buffer_size" env:"STUDENTS_API_PUBLISHER_BUFFER_SIZE" env-default:"1000"`
+}
+
+// Chaos injects faults for resilience testing. Rules stay inert while
+// enabled is false; it can also be switched on from the admin listener.
+type Chaos struct {
+	Enabled bool         `yaml:"enabled" env:"STUDENTS_API_CHAOS_ENABLED"`
+	Rules   []chaos.Rule `yaml:"rules"`
+}
+
+// Schedule runs a task on a cron schedule ("0 2 * * *", "@daily",
+// "@every 6h"). name identifies the schedule on the admin listener.
+type Schedule struct {
+	Name     string `yaml:"name"`
+	Task     string `yaml:"task"`
+	Schedule string `yaml:"schedule"`
+}
+
+// Backups are the database snapshots written by the backup task. Only the
+// newest keep snapshots in dir are kept.
+type Backups struct {
+	Dir  string `yaml:"dir" env:"STUDENTS_API_BACKUPS_DIR" env-default:"storage/backups"`
+	Keep int    `yaml:"keep" env:"STUDENTS_API_BACKUPS_KEEP" env-default:"7"`
+}
+
+// Replication uploads a snapshot of every database to a bucket of S3 or
+// an S3 compatible service each interval the database changed in, so a
+// single node can be recovered after losing its disk (see the recover
+// command). An empty bucket disables it. Snapshots go under prefix; those
+// older than retention are deleted but for the newest of them, and 0 keeps
+// them all. The credentials fall back to the standard AWS environment
+// variables.
+type Replication struct {
+	Bucket       string        `yaml:"bucket" env:"STUDENTS_API_REPLICATION_BUCKET"`
+	Prefix       string        `yaml:"prefix" env:"STUDENTS_API_REPLICATION_PREFIX" env-default:"students-api"`
+	Interval     time.Duration `yaml:"interval" env:"STUDENTS_API_REPLICATION_INTERVAL" env-default:"1m"`
+	Retention    time.Duration `yaml:"retention" env:"STUDENTS_API_REPLICATION_RETENTION" env-default:"168h"`
+	Endpoint     string        `yaml:"endpoint" env:"STUDENTS_API_REPLICATION_ENDPOINT"`
+	Region       string        `yaml:"region" env:"STUDENTS_API_REPLICATION_REGION,AWS_REGION"`
+	AccessKey    string        `yaml:"access_key" env:"STUDENTS_API_REPLICATION_ACCESS_KEY,AWS_ACCESS_KEY_ID"`
+	SecretKey    string        `yaml:"secret_key" env:"STUDENTS_API_REPLICATION_SECRET_KEY,AWS_SECRET_ACCESS_KEY"`
+	SessionToken string        `yaml:"session_token" env:"STUDENTS_API_REPLICATION_SESSION_TOKEN,AWS_SESSION_TOKEN"`
+	PathStyle    bool          `yaml:"path_style" env:"STUDENTS_API_REPLICATION_PATH_STYLE"`
+	Timeout      time.Duration `yaml:"timeout" env:"STUDENTS_API_REPLICATION_TIMEOUT" env-default:"5m"`
+}
+
+// Retention is how long the retention task keeps finished jobs and the
+// webhook delivery log. Unset keeps them forever.
+type Retention struct {
+	Jobs              time.Duration `yaml:"jobs" env:"STUDENTS_API_RETENTION_JOBS"`
+	DeadJobs          time.Duration `yaml:"dead_jobs" env:"STUDENTS_API_RETENTION_DEAD_JOBS"`
+	WebhookDeliveries time.Duration `yaml:"webhook_deliveries" env:"STUDENTS_API_RETENTION_WEBHOOK_DELIVERIES"`
+}
+
+// Archive is when the archive task moves students to the archive: once
+// they weren't changed for InactiveAfter, two years by default.
+type Archive struct {
+	InactiveAfter time.Duration `yaml:"inactive_after" env:"STUDENTS_API_ARCHIVE_INACTIVE_AFTER" env-default:"17520h"`
+}
+
+// Tenancy separates the data of the schools (tenants) served by one
+// instance. A request acts for the tenant of its bearer token; without one,
+// for the tenant in its X-Tenant-ID header if trust_header is set, or else
+// for the "default" tenant unless required is set.
+//
+// Databases maps tenants to a database of their own, for stricter
+// isolation; the others keep their rows in storage_path, as do the jobs of
+// every tenant. Open tenant databases are pinged every health_interval.
+type Tenancy struct {
+	Required       bool              `yaml:"required" env:"STUDENTS_API_TENANCY_REQUIRED"`
+	TrustHeader    bool              `yaml:"trust_header" env:"STUDENTS_API_TENANCY_TRUST_HEADER"`
+	Tenants        []Tenant          `yaml:"tenants"`
+	Databases      map[string]string `yaml:"databases"`
+	HealthInterval time.Duration     `yaml:"health_interval" env:"STUDENTS_API_TENANCY_HEALTH_INTERVAL" env-default:"30s"`
+}
+
+// Tenant is a school whose clients authenticate with token. A tenant may
+// be listed more than once to rotate its token.
+type Tenant struct {
+	Id    string `yaml:"id"`
+	Token string `yaml:"token"`
+}
+
+// Policy returns the tenant.Policy the settings describe.
+func (t Tenancy) Policy() tenant.Policy {
+	tokens := make(map[string]string, len(t.Tenants))
+	for _, tn := range t.Tenants {
+		tokens[tn.Token] = tn.Id
+	}
+
+	return tenant.Policy{Tokens: tokens, TrustHeader: t.TrustHeader, Required: t.Required}
+}
+
+// Ldap lets users log in at /api/v1/auth/login with the credentials of a
+// directory (LDAP or Active Directory) and get a session token for the role
+// and tenant of the first of groups they are a member of. Users are
+// searched under base_dn with user_filter, {username} replaced, binding as
+// bind_dn if set. Session tokens are signed with session_secret and valid
+// for session_ttl. An empty url disables it.
+type Ldap struct {
+	Url            string        `yaml:"url" env:"STUDENTS_API_LDAP_URL"`
+	StartTls       bool          `yaml:"start_tls" env:"STUDENTS_API_LDAP_START_TLS"`
+	CaFile         string        `yaml:"ca_file" env:"STUDENTS_API_LDAP_CA_FILE"`
+	BindDn         string        `yaml:"bind_dn" env:"STUDENTS_API_LDAP_BIND_DN"`
+	BindPassword   string        `yaml:"bind_password" env:"STUDENTS_API_LDAP_BIND_PASSWORD"`
+	BaseDn         string        `yaml:"base_dn" env:"STUDENTS_API_LDAP_BASE_DN"`
+	UserFilter     string        `yaml:"user_filter" env:"STUDENTS_API_LDAP_USER_FILTER" env-default:"(sAMAccountName={username})"`
+	GroupAttribute string        `yaml:"group_attribute" env:"STUDENTS_API_LDAP_GROUP_ATTRIBUTE" env-default:"memberOf"`
+	Groups         []auth.Group  `yaml:"groups"`
+	Timeout        time.Duration `yaml:"timeout" env:"STUDENTS_API_LDAP_TIMEOUT" env-default:"10s"`
+	SessionSecret  string        `yaml:"session_secret" env:"STUDENTS_API_LDAP_SESSION_SECRET"`
+	SessionTtl     time.Duration `yaml:"session_ttl" env:"STUDENTS_API_LDAP_SESSION_TTL" env-default:"8h"`
+}
+
+type Maintenance struct {
+	RetryAfter time.Duration `yaml:"retry_after" env:"STUDENTS_API_MAINTENANCE_RETRY_AFTER" env-default:"60s"`
 }
 
 type Config struct {
-	Env         string `yaml:"env" env:"ENV" env-requred:"true" env-default:"production"`
-	StoragePath string `yaml:"storage_path" env-requred:"true"`
-	HttpServer  `yaml:"http_server"`
+	Env          string `yaml:"env" env:"STUDENTS_API_ENV,ENV" env-requred:"true" env-default:"production"`
+	LogLevel     string `yaml:"log_level" env:"STUDENTS_API_LOG_LEVEL" env-default:"info"`
+	StoragePath  string `yaml:"storage_path" env:"STUDENTS_API_STORAGE_PATH" env-requred:"true"`
+	HttpServer   `yaml:"http_server"`
+	AdminServer  AdminServer      `yaml:"admin_server"`
+	GrpcServer   GrpcServer       `yaml:"grpc_server"`
+	PidFile      string           `yaml:"pid_file" env:"STUDENTS_API_PID_FILE"`
+	Maintenance  Maintenance      `yaml:"maintenance"`
+	Tenancy      Tenancy          `yaml:"tenancy"`
+	Ldap         Ldap             `yaml:"ldap"`
+	Webhooks     Webhooks         `yaml:"webhooks"`
+	Jobs         Jobs             `yaml:"jobs"`
+	Email        Email            `yaml:"email"`
+	Sms          Sms              `yaml:"sms"`
+	Files        Files            `yaml:"files"`
+	Publisher    Publisher        `yaml:"publisher"`
+	Chaos        Chaos            `yaml:"chaos"`
+	Schedules    []Schedule       `yaml:"schedules"`
+	Backups      Backups          `yaml:"backups"`
+	Replication  Replication      `yaml:"replication"`
+	Retention    Retention        `yaml:"retention"`
+	Archive      Archive          `yaml:"archive"`
+	Validation   validation.Rules `yaml:"validation"`
+	RemoteConfig RemoteConfig     `yaml:"remote_config"`
+
+	// Path is the config file the configuration was loaded from, if any.
+	Path string `yaml:"-"`
 }
 
+// MustLoad reads the config file (if any) and applies environment overrides.
+// Without a config file the configuration comes from the environment alone.
 func MustLoad() *Config {
+	return MustLoadArgs(flag.CommandLine, os.Args[1:])
+}
+
+// MustLoadArgs is MustLoad for a subcommand: the configuration flags are
+// added to fs, which may define flags of its own, before args are parsed.
+// Positional arguments are left in fs.Args().
+func MustLoadArgs(fs *flag.FlagSet, args []string) *Config {
+	configFlag := fs.String("config", "", "path to the configuration file")
+	envFileFlag := fs.String("env-file", "", "path to a .env file (default .env, if present)")
+	registerFlags(fs)
+	fs.Parse(args)
+	collectFlags(fs)
+
+	// .env is loaded first so it can provide CONFIG_PATH and overrides too
+	loadDotEnv(*envFileFlag)
+
 	var configPath string
 
 	configPath = os.Getenv("CONFIG_PATH")
 
 	if configPath == "" {
-		flags := flag.String("config", "", "path to the configuration file")
-		flag.Parse()
-		configPath = *flags
+		configPath = *configFlag
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return cfg
+}
+
+// Load reads the config file at path and applies environment overrides.
+// An empty path reads the configuration from the environment alone.
+// It is also used to re-read the configuration on reload.
+//
+// path may list several files separated by commas; later files override the
+// settings they contain. With a single file, an overlay for the environment
+// next to it (config.yaml -> config.<env>.yaml) is merged in if it exists.
+func Load(configPath string) (*Config, error) {
+	var cfg Config
+
+	if configPath == "" {
+		if err := cleanenv.ReadEnv(&cfg); err != nil {
+			return nil, fmt.Errorf("cannot read config from environment: %w", err)
+		}
 
-		if configPath == "" {
-			log.Fatal("Config path is not set")
+		applyFlags(&cfg)
+
+		// Validate reports the settings that are missing
+		return finish(&cfg)
+	}
+
+	files := strings.Split(configPath, ",")
+
+	for _, file := range files {
+		if err := readFile(file, &cfg); err != nil {
+			return nil, err
 		}
 	}
 
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		log.Fatalf("config file does not exist: %s", configPath)
+	if len(files) == 1 {
+		overlay := overlayPath(files[0], cfg.Env)
+		if _, err := os.Stat(overlay); err == nil {
+			if err := readFile(overlay, &cfg); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	var cfg Config
-	err := cleanenv.ReadConfig(configPath, &cfg)
+	applyFlags(&cfg)
 
-	if err != nil {
-		log.Fatalf("cannot read config file: %s", err.Error())
+	cfg.Path = configPath
+
+	return finish(&cfg)
+}
+
+// readFile merges the settings in file into cfg. Settings the file doesn't
+// mention keep their current value.
+func readFile(file string, cfg *Config) error {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return fmt.Errorf("config file does not exist: %s", file)
+	}
+
+	if err := cleanenv.ReadConfig(file, cfg); err != nil {
+		return fmt.Errorf("cannot read config file %s: %w", file, err)
 	}
 
-	return &cfg
+	return nil
+}
+
+// overlayPath returns the per-environment overlay for base,
+// e.g. config/config.yaml and "prod" give config/config.prod.yaml.
+func overlayPath(base, env string) string {
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "." + env + ext
+}
+
+// finish merges the remote config, resolves secret references
+// (vault:..., aws-sm:...) and validates.
+func finish(cfg *Config) (*Config, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// resolved before the remote fetch too, the remote token may be a secret
+	if err := secrets.ResolveAll(ctx, cfg); err != nil {
+		return nil, fmt.Errorf("cannot resolve secret: %w", err)
+	}
+
+	mergeRemote(ctx, cfg)
+
+	if err := secrets.ResolveAll(ctx, cfg); err != nil {
+		return nil, fmt.Errorf("cannot resolve secret: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// SlogLevel parses LogLevel, falling back to info for unknown values.
+func (c *Config) SlogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return slog.LevelInfo
+	}
+
+	return level
+}
+
+// loadDotEnv sets variables from a .env file without overriding ones that are
+// already in the environment. The default .env is optional, an explicitly
+// requested file must exist.
+func loadDotEnv(path string) {
+	if path == "" {
+		path = os.Getenv("STUDENTS_API_ENV_FILE")
+	}
+
+	if path == "" {
+		if _, err := os.Stat(".env"); err != nil {
+			return
+		}
+		path = ".env"
+	}
+
+	if err := godotenv.Load(path); err != nil {
+		log.Fatalf("cannot load env file %s: %s", path, err.Error())
+	}
 }
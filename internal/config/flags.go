@@ -0,0 +1,63 @@
+package config
+
+import (
+	"flag"
+	"time"
+)
+
+// overrides from command-line flags, set by registerFlags and applied on
+// every load (including reloads) so flags always win over env and files
+var (
+	flagSetters   = map[string]func(*Config){}
+	flagOverrides []func(*Config)
+)
+
+func registerFlags(fs *flag.FlagSet) {
+	stringFlag(fs, "env", "environment name", func(c *Config) *string { return &c.Env })
+	stringFlag(fs, "log-level", "minimum log level: debug, info, warn or error", func(c *Config) *string { return &c.LogLevel })
+	stringFlag(fs, "storage-path", "path to the SQLite database file", func(c *Config) *string { return &c.StoragePath })
+	stringFlag(fs, "addr", "API listener: host:port, unix:/path or systemd[:name]", func(c *Config) *string { return &c.Addr })
+	durationFlag(fs, "request-timeout", "per-request deadline, 0 disables", func(c *Config) *time.Duration { return &c.RequestTimeout })
+	intFlag(fs, "max-in-flight", "maximum concurrent requests, 0 disables load shedding", func(c *Config) *int { return &c.MaxInFlight })
+	intFlag(fs, "max-queue", "requests allowed to wait for a free slot", func(c *Config) *int { return &c.MaxQueue })
+	durationFlag(fs, "queue-timeout", "how long a queued request waits before it is shed", func(c *Config) *time.Duration { return &c.QueueTimeout })
+	intFlag(fs, "max-body-size", "largest request body in bytes", func(c *Config) *int { return &c.MaxBodySize })
+	stringFlag(fs, "admin-addr", "admin listener: host:port, unix:/path or systemd[:name]", func(c *Config) *string { return &c.AdminServer.Addr })
+	stringFlag(fs, "grpc-addr", "gRPC listener, empty disables gRPC", func(c *Config) *string { return &c.GrpcServer.Addr })
+	stringFlag(fs, "pid-file", "file to write the process id to", func(c *Config) *string { return &c.PidFile })
+	durationFlag(fs, "maintenance-retry-after", "Retry-After sent while in maintenance mode", func(c *Config) *time.Duration { return &c.Maintenance.RetryAfter })
+	stringFlag(fs, "remote-config-backend", "remote config backend: consul or etcd", func(c *Config) *string { return &c.RemoteConfig.Backend })
+	stringFlag(fs, "remote-config-addr", "remote config backend address", func(c *Config) *string { return &c.RemoteConfig.Address })
+	stringFlag(fs, "remote-config-key", "key of the remote config document", func(c *Config) *string { return &c.RemoteConfig.Key })
+	durationFlag(fs, "remote-config-poll-interval", "how often to re-read the remote config, 0 disables", func(c *Config) *time.Duration { return &c.RemoteConfig.PollInterval })
+}
+
+func stringFlag(fs *flag.FlagSet, name, usage string, field func(*Config) *string) {
+	v := fs.String(name, "", usage)
+	flagSetters[name] = func(c *Config) { *field(c) = *v }
+}
+
+func intFlag(fs *flag.FlagSet, name, usage string, field func(*Config) *int) {
+	v := fs.Int(name, 0, usage)
+	flagSetters[name] = func(c *Config) { *field(c) = *v }
+}
+
+func durationFlag(fs *flag.FlagSet, name, usage string, field func(*Config) *time.Duration) {
+	v := fs.Duration(name, 0, usage)
+	flagSetters[name] = func(c *Config) { *field(c) = *v }
+}
+
+// collectFlags remembers the flags that were given on the command line.
+func collectFlags(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		if set, ok := flagSetters[f.Name]; ok {
+			flagOverrides = append(flagOverrides, set)
+		}
+	})
+}
+
+func applyFlags(cfg *Config) {
+	for _, set := range flagOverrides {
+		set(cfg)
+	}
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+// RemoteConfig points at a YAML document in a key/value store that is merged
+// over the local files. If the store can't be reached the local
+// configuration is used as is.
+type RemoteConfig struct {
+	Backend      string        `yaml:"backend" env:"STUDENTS_API_REMOTE_CONFIG_BACKEND"`
+	Address      string        `yaml:"address" env:"STUDENTS_API_REMOTE_CONFIG_ADDR"`
+	Key          string        `yaml:"key" env:"STUDENTS_API_REMOTE_CONFIG_KEY" env-default:"students-api/config"`
+	Token        string        `yaml:"token" env:"STUDENTS_API_REMOTE_CONFIG_TOKEN"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"STUDENTS_API_REMOTE_CONFIG_POLL_INTERVAL"`
+}
+
+const (
+	backendConsul = "consul"
+	backendEtcd   = "etcd"
+)
+
+var remoteClient = &http.Client{Timeout: 5 * time.Second}
+
+// mergeRemote fetches the remote document and merges it into cfg, then
+// re-applies environment variables and flags so they keep precedence.
+func mergeRemote(ctx context.Context, cfg *Config) {
+	rc := cfg.RemoteConfig
+	if rc.Backend == "" {
+		return
+	}
+
+	doc, err := fetchRemote(ctx, rc)
+	if err != nil {
+		slog.Warn("remote config unavailable, using local config", slog.String("backend", rc.Backend), slog.String("error", err.Error()))
+		return
+	}
+
+	merged := *cfg
+	if err := cleanenv.ParseYAML(bytes.NewReader(doc), &merged); err != nil {
+		slog.Warn("invalid remote config, using local config", slog.String("backend", rc.Backend), slog.String("error", err.Error()))
+		return
+	}
+
+	if err := cleanenv.ReadEnv(&merged); err != nil {
+		slog.Warn("cannot apply environment to remote config, using local config", slog.String("error", err.Error()))
+		return
+	}
+	applyFlags(&merged)
+
+	// where the remote config lives is decided locally
+	merged.RemoteConfig = rc
+	merged.Path = cfg.Path
+	*cfg = merged
+}
+
+func fetchRemote(ctx context.Context, rc RemoteConfig) ([]byte, error) {
+	switch rc.Backend {
+	case backendConsul:
+		return fetchConsul(ctx, rc)
+	case backendEtcd:
+		return fetchEtcd(ctx, rc)
+	}
+
+	return nil, fmt.Errorf("unknown backend %q", rc.Backend)
+}
+
+// fetchConsul reads a raw value from the Consul KV HTTP API.
+func fetchConsul(ctx context.Context, rc RemoteConfig) ([]byte, error) {
+	u := strings.TrimRight(rc.Address, "/") + "/v1/kv/" + strings.TrimLeft(rc.Key, "/") + "?raw"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if rc.Token != "" {
+		req.Header.Set("X-Consul-Token", rc.Token)
+	}
+
+	return doRemote(req)
+}
+
+// fetchEtcd reads a value through the etcd v3 JSON gateway.
+func fetchEtcd(ctx context.Context, rc RemoteConfig) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(rc.Key))})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(rc.Address, "/")+"/v3/kv/range", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if rc.Token != "" {
+		req.Header.Set("Authorization", rc.Token)
+	}
+
+	raw, err := doRemote(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Kvs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("invalid etcd response: %w", err)
+	}
+
+	if len(out.Kvs) == 0 {
+		return nil, fmt.Errorf("key %q not found", rc.Key)
+	}
+
+	return base64.StdEncoding.DecodeString(out.Kvs[0].Value)
+}
+
+func doRemote(req *http.Request) ([]byte, error) {
+	resp, err := remoteClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", (&url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host}).String(), resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
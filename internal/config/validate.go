@@ -0,0 +1,525 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"mime"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/auth"
+	"github.com/cmanish049/students-api/internal/blob"
+	"github.com/cmanish049/students-api/internal/chaos"
+	"github.com/cmanish049/students-api/internal/ldap"
+	"github.com/cmanish049/students-api/internal/schedule"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/validation"
+)
+
+// tasks that can be scheduled
+var scheduleTasks = []string{"backup", "retention", "archive"}
+
+// Validate checks the configuration and reports every problem at once,
+// each prefixed with the setting it is about.
+func (c *Config) Validate() error {
+	var problems []string
+
+	add := func(field, format string, args ...any) {
+		problems = append(problems, field+": "+fmt.Sprintf(format, args...))
+	}
+
+	if c.Env == "" {
+		add("env", "must not be empty")
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		add("log_level", "unknown level %q, use debug, info, warn or error", c.LogLevel)
+	}
+
+	if c.StoragePath == "" {
+		add("storage_path", "is required")
+	} else if err := checkWritable(c.StoragePath); err != nil {
+		add("storage_path", "%s", err)
+	}
+
+	if err := checkAddr(c.Addr); err != nil {
+		add("http_server.address", "%s", err)
+	}
+
+	if err := checkAddr(c.AdminServer.Addr); err != nil {
+		add("admin_server.address", "%s", err)
+	} else if c.AdminServer.Addr == c.Addr {
+		add("admin_server.address", "must differ from http_server.address")
+	}
+
+	if c.GrpcServer.Addr != "" {
+		if err := checkAddr(c.GrpcServer.Addr); err != nil {
+			add("grpc_server.address", "%s", err)
+		} else if c.GrpcServer.Addr == c.Addr || c.GrpcServer.Addr == c.AdminServer.Addr {
+			add("grpc_server.address", "must differ from the http and admin addresses")
+		}
+	}
+
+	if c.RequestTimeout < 0 {
+		add("http_server.request_timeout", "must not be negative")
+	}
+
+	if c.MaxInFlight < 0 {
+		add("http_server.max_in_flight", "must not be negative")
+	}
+
+	if c.MaxQueue < 0 {
+		add("http_server.max_queue", "must not be negative")
+	}
+
+	if c.MaxQueue > 0 && c.QueueTimeout <= 0 {
+		add("http_server.queue_timeout", "must be positive when max_queue is set")
+	}
+
+	if c.MaxBodySize < 1 {
+		add("http_server.max_body_size", "must be positive")
+	}
+
+	if c.Maintenance.RetryAfter < 0 {
+		add("maintenance.retry_after", "must not be negative")
+	}
+
+	tokens := map[string]bool{}
+	for i, tn := range c.Tenancy.Tenants {
+		field := fmt.Sprintf("tenancy.tenants[%d]", i)
+
+		if !tenant.Valid(tn.Id) {
+			add(field+".id", "invalid id %q, use up to 63 lowercase letters, digits, - and _", tn.Id)
+		}
+
+		switch {
+		case tn.Token == "":
+			add(field+".token", "is required")
+		case tokens[tn.Token]:
+			add(field+".token", "is used twice")
+		}
+		tokens[tn.Token] = true
+	}
+
+	if c.Tenancy.Required && len(c.Tenancy.Tenants) == 0 && !c.Tenancy.TrustHeader && c.Ldap.Url == "" {
+		add("tenancy.required", "needs tenants, trust_header or ldap, or no request can name a tenant")
+	}
+
+	paths := map[string]bool{c.StoragePath: true}
+	for _, id := range slices.Sorted(maps.Keys(c.Tenancy.Databases)) {
+		field := "tenancy.databases." + id
+		path := c.Tenancy.Databases[id]
+
+		if !tenant.Valid(id) {
+			add(field, "invalid tenant id, use up to 63 lowercase letters, digits, - and _")
+		}
+
+		switch {
+		case path == "":
+			add(field, "is required")
+		case paths[path]:
+			add(field, "must differ from storage_path and the other tenant databases")
+		}
+		paths[path] = true
+	}
+
+	if len(c.Tenancy.Databases) > 0 && c.Tenancy.HealthInterval <= 0 {
+		add("tenancy.health_interval", "must be positive when databases are set")
+	}
+
+	if c.Ldap.Url != "" {
+		if u, err := url.Parse(c.Ldap.Url); err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+			add("ldap.url", "%q is not an ldap:// or ldaps:// URL", c.Ldap.Url)
+		} else if c.Ldap.StartTls && u.Scheme == "ldaps" {
+			add("ldap.start_tls", "is only for ldap:// URLs, ldaps:// is TLS already")
+		}
+		if c.Ldap.CaFile != "" {
+			if _, err := os.Stat(c.Ldap.CaFile); err != nil {
+				add("ldap.ca_file", "file %s does not exist", c.Ldap.CaFile)
+			}
+		}
+		if c.Ldap.BindDn != "" && c.Ldap.BindPassword == "" {
+			add("ldap.bind_password", "is required with bind_dn")
+		}
+		if c.Ldap.BaseDn == "" {
+			add("ldap.base_dn", "is required")
+		}
+		if !strings.Contains(c.Ldap.UserFilter, "{username}") {
+			add("ldap.user_filter", "must contain {username}")
+		} else if _, err := ldap.CompileFilter(c.Ldap.UserFilter); err != nil {
+			add("ldap.user_filter", "%s", err)
+		}
+		if c.Ldap.GroupAttribute == "" {
+			add("ldap.group_attribute", "must not be empty")
+		}
+		if len(c.Ldap.Groups) == 0 {
+			add("ldap.groups", "needs at least one group, or no user can log in")
+		}
+		for i, g := range c.Ldap.Groups {
+			field := fmt.Sprintf("ldap.groups[%d]", i)
+			if g.Dn == "" {
+				add(field+".dn", "is required")
+			}
+			if !slices.Contains(auth.Roles, g.Role) {
+				add(field+".role", "unknown role %q, use %s", g.Role, strings.Join(auth.Roles, " or "))
+			}
+			if g.Tenant != "" && !tenant.Valid(g.Tenant) {
+				add(field+".tenant", "invalid tenant id %q, use up to 63 lowercase letters, digits, - and _", g.Tenant)
+			}
+		}
+		if c.Ldap.Timeout <= 0 {
+			add("ldap.timeout", "must be positive")
+		}
+		if len(c.Ldap.SessionSecret) < 32 {
+			add("ldap.session_secret", "must be at least 32 characters")
+		}
+		if c.Ldap.SessionTtl <= 0 {
+			add("ldap.session_ttl", "must be positive")
+		}
+	}
+
+	if c.Webhooks.MaxAttempts < 1 {
+		add("webhooks.max_attempts", "must be at least 1")
+	}
+
+	if c.Webhooks.Timeout <= 0 {
+		add("webhooks.timeout", "must be positive")
+	}
+
+	if c.Webhooks.RetryBackoff < 0 {
+		add("webhooks.retry_backoff", "must not be negative")
+	}
+
+	for _, host := range c.Webhooks.AllowedHosts {
+		// only a leading "*." is a wildcard, for the subdomains
+		name := strings.TrimPrefix(host, "*.")
+		if name == "" || strings.ContainsAny(name, "/:@*") {
+			add("webhooks.allowed_hosts", "%q is not a host name or *. followed by one", host)
+		}
+	}
+
+	if c.Jobs.Workers < 1 {
+		add("jobs.workers", "must be at least 1")
+	}
+
+	if c.Jobs.PollInterval <= 0 {
+		add("jobs.poll_interval", "must be positive")
+	}
+
+	if c.Jobs.Lease <= c.Webhooks.Timeout {
+		add("jobs.lease", "must be longer than webhooks.timeout")
+	}
+
+	if c.Email.Host != "" {
+		if _, err := mail.ParseAddress(c.Email.From); err != nil {
+			add("email.from", "%q is not an email address", c.Email.From)
+		}
+		if c.Email.Port < 1 || c.Email.Port > 65535 {
+			add("email.port", "invalid port %d", c.Email.Port)
+		}
+		if c.Email.Templates != "" {
+			if info, err := os.Stat(c.Email.Templates); err != nil || !info.IsDir() {
+				add("email.templates", "directory %s does not exist", c.Email.Templates)
+			}
+		}
+		if c.Email.Timeout <= 0 || c.Email.Timeout >= c.Jobs.Lease {
+			add("email.timeout", "must be positive and shorter than jobs.lease")
+		}
+		if c.Email.MaxAttempts < 1 {
+			add("email.max_attempts", "must be at least 1")
+		}
+		if c.Email.RetryBackoff < 0 {
+			add("email.retry_backoff", "must not be negative")
+		}
+		if c.Email.VerifyUrl != "" {
+			if u, err := url.Parse(c.Email.VerifyUrl); err != nil || u.Host == "" || !strings.Contains(c.Email.VerifyUrl, "{token}") {
+				add("email.verify_url", "must be an absolute URL containing {token}")
+			}
+		}
+		if c.Email.VerificationTtl <= 0 {
+			add("email.verification_ttl", "must be positive")
+		}
+	}
+
+	switch c.Sms.Provider {
+	case "":
+	case "twilio", "http":
+		if c.Sms.Provider == "twilio" && c.Sms.AccountSid == "" {
+			add("sms.account_sid", "is required for twilio")
+		}
+		if c.Sms.Provider == "http" && c.Sms.Url == "" {
+			add("sms.url", "is required for the http provider")
+		}
+		if c.Sms.Url != "" {
+			if u, err := url.Parse(c.Sms.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add("sms.url", "%q is not an http(s) URL", c.Sms.Url)
+			}
+		}
+		if c.Sms.From == "" {
+			add("sms.from", "is required when a provider is set")
+		}
+		if c.Sms.RatePerMinute < 1 {
+			add("sms.rate_per_minute", "must be at least 1")
+		}
+		if c.Sms.Timeout <= 0 || c.Sms.Timeout >= c.Jobs.Lease {
+			add("sms.timeout", "must be positive and shorter than jobs.lease")
+		}
+		if c.Sms.MaxAttempts < 1 {
+			add("sms.max_attempts", "must be at least 1")
+		}
+		if c.Sms.RetryBackoff < 0 {
+			add("sms.retry_backoff", "must not be negative")
+		}
+	default:
+		add("sms.provider", "unknown provider %q, use twilio or http", c.Sms.Provider)
+	}
+
+	switch c.Files.Backend {
+	case "":
+	case "local", "s3":
+		if c.Files.Backend == "local" {
+			if c.Files.Dir == "" {
+				add("files.dir", "is required for the local backend")
+			}
+			if c.Files.PublicUrl != "" {
+				if u, err := url.Parse(c.Files.PublicUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					add("files.public_url", "%q is not an http(s) URL", c.Files.PublicUrl)
+				}
+			}
+			if len(c.Files.UrlSecret) < 32 {
+				add("files.url_secret", "must be at least 32 characters for the local backend")
+			}
+		}
+		if c.Files.Backend == "s3" {
+			if c.Files.S3.Endpoint != "" {
+				if u, err := url.Parse(c.Files.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					add("files.s3.endpoint", "%q is not an http(s) URL", c.Files.S3.Endpoint)
+				}
+			}
+			if c.Files.S3.Region == "" {
+				add("files.s3.region", "is required for the s3 backend")
+			}
+			if c.Files.S3.Bucket == "" {
+				add("files.s3.bucket", "is required for the s3 backend")
+			}
+			if c.Files.S3.AccessKey == "" || c.Files.S3.SecretKey == "" {
+				add("files.s3", "access_key and secret_key are required for the s3 backend")
+			}
+			if c.Files.S3.Timeout <= 0 {
+				add("files.s3.timeout", "must be positive")
+			}
+		}
+		if c.Files.UrlTtl <= 0 || c.Files.UrlTtl > blob.MaxUrlTtl {
+			add("files.url_ttl", "must be positive and at most %s", blob.MaxUrlTtl)
+		}
+		if len(c.Files.AllowedTypes) == 0 {
+			add("files.allowed_types", "needs at least one type, or no file can be uploaded")
+		}
+		for i, t := range c.Files.AllowedTypes {
+			if mediaType, _, err := mime.ParseMediaType(t); err != nil || mediaType != t || !strings.Contains(t, "/") {
+				add(fmt.Sprintf("files.allowed_types[%d]", i), "%q is not a media type such as image/png", t)
+			}
+		}
+	default:
+		add("files.backend", "unknown backend %q, use local or s3", c.Files.Backend)
+	}
+
+	switch c.Publisher.Backend {
+	case "":
+	case "kafka", "nats":
+		if c.Publisher.Address == "" {
+			add("publisher.address", "is required when a backend is set")
+		}
+		if c.Publisher.Topic == "" {
+			add("publisher.topic", "must not be empty")
+		}
+		if c.Publisher.BufferSize < 1 {
+			add("publisher.buffer_size", "must be at least 1")
+		}
+	default:
+		add("publisher.backend", "unknown backend %q, use kafka or nats", c.Publisher.Backend)
+	}
+
+	if err := chaos.Check(c.Chaos.Rules); err != nil {
+		add("chaos.rules", "%s", strings.ReplaceAll(err.Error(), "\n", "; "))
+	}
+
+	names := map[string]bool{}
+	for i, sc := range c.Schedules {
+		field := fmt.Sprintf("schedules[%d]", i)
+
+		switch {
+		case sc.Name == "":
+			add(field+".name", "is required")
+		case names[sc.Name]:
+			add(field+".name", "%q is used twice", sc.Name)
+		}
+		names[sc.Name] = true
+
+		if !slices.Contains(scheduleTasks, sc.Task) {
+			add(field+".task", "unknown task %q, use %s", sc.Task, strings.Join(scheduleTasks, ", "))
+		}
+
+		if _, err := schedule.Parse(sc.Schedule); err != nil {
+			add(field+".schedule", "%s", err)
+		}
+	}
+
+	if slices.ContainsFunc(c.Schedules, func(sc Schedule) bool { return sc.Task == "backup" }) {
+		if c.Backups.Keep < 1 {
+			add("backups.keep", "must be at least 1")
+		}
+		if err := checkDir(c.Backups.Dir); err != nil {
+			add("backups.dir", "%s", err)
+		}
+	}
+
+	if c.Replication.Bucket != "" {
+		if c.Replication.Endpoint != "" {
+			if u, err := url.Parse(c.Replication.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add("replication.endpoint", "%q is not an http(s) URL", c.Replication.Endpoint)
+			}
+		}
+		if c.Replication.Region == "" {
+			add("replication.region", "is required for replication")
+		}
+		if c.Replication.AccessKey == "" || c.Replication.SecretKey == "" {
+			add("replication", "access_key and secret_key are required for replication")
+		}
+		if !fs.ValidPath(c.Replication.Prefix) || c.Replication.Prefix == "." {
+			add("replication.prefix", "%q is not a path such as students-api/node-1", c.Replication.Prefix)
+		}
+		if c.Replication.Interval < 10*time.Second {
+			add("replication.interval", "must be at least 10s")
+		}
+		if c.Replication.Retention < 0 {
+			add("replication.retention", "must not be negative")
+		}
+		if c.Replication.Timeout <= 0 {
+			add("replication.timeout", "must be positive")
+		}
+	}
+
+	if c.Retention.Jobs < 0 {
+		add("retention.jobs", "must not be negative")
+	}
+
+	if c.Retention.DeadJobs < 0 {
+		add("retention.dead_jobs", "must not be negative")
+	}
+
+	if c.Retention.WebhookDeliveries < 0 {
+		add("retention.webhook_deliveries", "must not be negative")
+	}
+
+	// everyone would be archived at once
+	if slices.ContainsFunc(c.Schedules, func(sc Schedule) bool { return sc.Task == "archive" }) && c.Archive.InactiveAfter <= 0 {
+		add("archive.inactive_after", "must be positive")
+	}
+
+	if err := validation.Check(c.Validation); err != nil {
+		add("validation", "%s", strings.ReplaceAll(err.Error(), "\n", "; "))
+	}
+
+	if c.PidFile != "" {
+		if err := checkWritable(c.PidFile); err != nil {
+			add("pid_file", "%s", err)
+		}
+	}
+
+	switch c.RemoteConfig.Backend {
+	case "":
+	case backendConsul, backendEtcd:
+		if c.RemoteConfig.Address == "" {
+			add("remote_config.address", "is required when a backend is set")
+		}
+	default:
+		add("remote_config.backend", "unknown backend %q, use consul or etcd", c.RemoteConfig.Backend)
+	}
+
+	if c.RemoteConfig.PollInterval < 0 {
+		add("remote_config.poll_interval", "must not be negative")
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+}
+
+// checkAddr accepts host:port, unix socket paths and systemd socket names.
+func checkAddr(addr string) error {
+	if addr == "" {
+		return fmt.Errorf("is required")
+	}
+
+	if addr == "systemd" || strings.HasPrefix(addr, "systemd:") {
+		return nil
+	}
+
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok || strings.HasPrefix(addr, "/") {
+		if !ok {
+			path = addr
+		}
+		return checkDir(filepath.Dir(path))
+	}
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%q is not host:port, unix:/path or systemd[:name]", addr)
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+
+	return nil
+}
+
+// checkWritable verifies that path can be created or written to.
+func checkWritable(path string) error {
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", path)
+		}
+
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("%s is not writable: %w", path, err)
+		}
+		return f.Close()
+	}
+
+	return checkDir(filepath.Dir(path))
+}
+
+// checkDir verifies that dir exists and files can be created in it.
+func checkDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("directory %s does not exist", dir)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	f, err := os.CreateTemp(dir, ".students-api-check-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	f.Close()
+
+	return os.Remove(f.Name())
+}
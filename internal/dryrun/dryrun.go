@@ -0,0 +1,57 @@
+// Package dryrun marks requests whose mutations must be checked but not
+// kept. Storages run them in a transaction that is rolled back, and no
+// events are published for them.
+package dryrun
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Header requests a dry run, like the dry_run query parameter.
+const Header = "Dry-Run"
+
+type key struct{}
+
+// With returns a context whose mutations are dry runs.
+func With(ctx context.Context) context.Context {
+	return context.WithValue(ctx, key{}, true)
+}
+
+// Enabled reports whether mutations made with ctx are dry runs.
+func Enabled(ctx context.Context) bool {
+	v, _ := ctx.Value(key{}).(bool)
+	return v
+}
+
+// Requested reports whether r asks for a dry run with ?dry_run=true or a
+// "Dry-Run: true" header.
+func Requested(r *http.Request) bool {
+	for _, v := range []string{r.URL.Query().Get("dry_run"), r.Header.Get(Header)} {
+		if ok, err := strconv.ParseBool(v); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+type routeKey struct{}
+
+// Route serves h as the handler of a route that honors dry runs if
+// supported, for Allowed. Every route is marked, so a request dispatched
+// again from a handler, like those of a batch, gets its own route's mark.
+func Route(h http.Handler, supported bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeKey{}, supported)))
+	})
+}
+
+// Allowed reports whether r is a dry run its route honors (see Route), so
+// it writes nothing and can be let through where writes are refused. Dry
+// runs of other routes would write.
+func Allowed(r *http.Request) bool {
+	supported, _ := r.Context().Value(routeKey{}).(bool)
+	return supported && Requested(r)
+}
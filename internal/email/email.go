@@ -0,0 +1,292 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/events"
+	"github.com/cmanish049/students-api/internal/jobs"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// Kind is the job kind of one email
+const Kind = "email"
+
+// templates sent for each event type, by template name
+var templates = map[events.Type]string{
+	events.StudentCreated: "welcome",
+	events.StudentUpdated: "updated",
+}
+
+// verifyTemplate is sent with the token that verifies a student's email
+const verifyTemplate = "verify"
+
+//go:embed templates/*.tmpl
+var builtin embed.FS
+
+// Config is where and how mail is sent.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// Templates is a directory whose <name>.tmpl files replace the built-in
+	// templates of the same name.
+	Templates string
+	Timeout   time.Duration
+	// VerifyUrl is the link sent to verify an email, with {token} in place
+	// of the token. Without one the token itself is sent.
+	VerifyUrl string
+}
+
+// Data is what templates are executed with.
+type Data struct {
+	Student types.Student
+	Event   events.Type
+	Time    time.Time
+	// Token and Link (if email.verify_url is set) verify the email; only
+	// in the verify template
+	Token string
+	Link  string
+}
+
+// message is the job payload.
+type message struct {
+	Template string `json:"template"`
+	To       string `json:"to"`
+	Data     Data   `json:"data"`
+}
+
+// Notifier emails students when their record is created or changed. Mail is
+// sent by jobs on the queue, so a slow or unreachable mail server never holds
+// up a request, and failed sends are retried.
+type Notifier struct {
+	cfg       Config
+	from      *mail.Address
+	queue     *jobs.Queue
+	templates map[string]*template.Template
+}
+
+// New loads the templates and registers the email job kind on queue.
+func New(cfg Config, queue *jobs.Queue, maxAttempts int, backoff time.Duration) (*Notifier, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", cfg.From, err)
+	}
+
+	n := &Notifier{cfg: cfg, from: from, queue: queue, templates: map[string]*template.Template{}}
+
+	for _, name := range append(slices.Collect(maps.Values(templates)), verifyTemplate) {
+		t, err := load(name, cfg.Templates)
+		if err != nil {
+			return nil, err
+		}
+		n.templates[name] = t
+	}
+
+	queue.Register(Kind, maxAttempts, backoff, n.send)
+
+	return n, nil
+}
+
+// load parses the template name from dir if it has one, else the built-in one.
+// A template defines "subject" and "body".
+func load(name, dir string) (*template.Template, error) {
+	file := name + ".tmpl"
+
+	if dir != "" {
+		path := filepath.Join(dir, file)
+		if _, err := os.Stat(path); err == nil {
+			t, err := template.ParseFiles(path)
+			if err != nil {
+				return nil, fmt.Errorf("email template %s: %w", path, err)
+			}
+			return t, check(t, path)
+		}
+	}
+
+	t, err := template.ParseFS(builtin, "templates/"+file)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, check(t, file)
+}
+
+func check(t *template.Template, name string) error {
+	for _, part := range []string{"subject", "body"} {
+		if t.Lookup(part) == nil {
+			return fmt.Errorf("email template %s does not define %q", name, part)
+		}
+	}
+
+	return nil
+}
+
+// Handle is an events.Bus subscriber; mail is queued in the background.
+func (n *Notifier) Handle(e events.Event) {
+	name, ok := templates[e.Type]
+	if !ok {
+		return
+	}
+
+	student, ok := e.Data.(types.Student)
+	if !ok || student.Email == "" {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(tenant.With(context.Background(), e.Tenant), 10*time.Second)
+		defer cancel()
+
+		msg := message{Template: name, To: student.Email, Data: Data{Student: student, Event: e.Type, Time: e.Time}}
+		if _, err := n.queue.Enqueue(ctx, Kind, msg); err != nil {
+			slog.Error("failed to queue email", slog.String("template", name), slog.Int("student_id", student.Id), slog.String("error", err.Error()))
+		}
+	}()
+}
+
+// SendVerification queues the mail with the token that verifies the email
+// of student, see student.Verifier.
+func (n *Notifier) SendVerification(ctx context.Context, student types.Student, token string) error {
+	data := Data{Student: student, Time: time.Now(), Token: token}
+	if n.cfg.VerifyUrl != "" {
+		data.Link = strings.ReplaceAll(n.cfg.VerifyUrl, "{token}", url.QueryEscape(token))
+	}
+
+	_, err := n.queue.Enqueue(ctx, Kind, message{Template: verifyTemplate, To: student.Email, Data: data})
+	return err
+}
+
+func (n *Notifier) send(ctx context.Context, job types.Job) error {
+	var msg message
+	if err := json.Unmarshal(job.Payload, &msg); err != nil {
+		return err
+	}
+
+	// the address comes from a student record, never put it in headers unchecked
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+
+	t, ok := n.templates[msg.Template]
+	if !ok {
+		return fmt.Errorf("unknown email template %q", msg.Template)
+	}
+
+	var subject, body bytes.Buffer
+	if err := t.ExecuteTemplate(&subject, "subject", msg.Data); err != nil {
+		return err
+	}
+	if err := t.ExecuteTemplate(&body, "body", msg.Data); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.Timeout)
+	defer cancel()
+
+	if err := n.deliver(ctx, to.Address, compose(n.from, to, strings.TrimSpace(subject.String()), body.String())); err != nil {
+		return fmt.Errorf("send to %s: %w", msg.To, err)
+	}
+
+	slog.Info("email sent", slog.String("template", msg.Template), slog.Int64("job_id", job.Id))
+
+	return nil
+}
+
+// deliver talks SMTP to the configured server, upgrading to TLS whenever
+// the server offers STARTTLS.
+func (n *Notifier) deliver(ctx context.Context, to string, msg []byte) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(n.cfg.Host, fmt.Sprint(n.cfg.Port)))
+	if err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: n.cfg.Host}); err != nil {
+			return err
+		}
+	}
+
+	if n.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(n.from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// compose builds a plain text message with CRLF line endings.
+func compose(from, to *mail.Address, subject, body string) []byte {
+	id := make([]byte, 12)
+	rand.Read(id)
+
+	_, host, _ := strings.Cut(from.Address, "@")
+
+	var b strings.Builder
+	b.WriteString("From: " + from.String() + "\r\n")
+	b.WriteString("To: " + to.String() + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("Message-ID: <" + hex.EncodeToString(id) + "@" + host + ">\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return []byte(b.String())
+}
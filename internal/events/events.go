@@ -0,0 +1,140 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+type Type string
+
+const (
+	StudentCreated Type = "student.created"
+	StudentUpdated Type = "student.updated"
+	StudentDeleted Type = "student.deleted"
+	// StudentEmailVerified is published when a student verifies their email
+	StudentEmailVerified Type = "student.email_verified"
+	// StudentArchived and StudentUnarchived are published when a student
+	// is moved to the archive and back
+	StudentArchived   Type = "student.archived"
+	StudentUnarchived Type = "student.unarchived"
+)
+
+// Types lists every event type that is published.
+var Types = []Type{StudentCreated, StudentUpdated, StudentDeleted, StudentEmailVerified, StudentArchived, StudentUnarchived}
+
+type Event struct {
+	Id   int64     `json:"id"`
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+	// Tenant is the tenant the change was made for; subscribers acting on
+	// an event must act for it too
+	Tenant string `json:"tenant"`
+	Data   any    `json:"data"`
+}
+
+// Deleted is the data of a StudentDeleted event; the others carry the
+// types.Student as written.
+type Deleted struct {
+	Id int64 `json:"id"`
+}
+
+// StudentId returns the id of the student an event is about.
+func (e Event) StudentId() (int64, bool) {
+	switch d := e.Data.(type) {
+	case types.Student:
+		return int64(d.Id), true
+	case Deleted:
+		return d.Id, true
+	}
+
+	return 0, false
+}
+
+// historySize is how many recent events are kept for resuming subscribers
+const historySize = 1000
+
+// Bus fans mutation events out to in-process subscribers. Subscribers are
+// called synchronously from Publish and must hand work off if it can block.
+type Bus struct {
+	seq atomic.Int64
+
+	mu      sync.Mutex
+	nextId  int
+	subs    map[int]func(Event)
+	history []Event
+}
+
+func NewBus() *Bus {
+	b := &Bus{subs: map[int]func(Event){}}
+
+	// ids start from the current time so they keep increasing across restarts
+	b.seq.Store(time.Now().UnixMicro())
+
+	return b
+}
+
+// Subscribe registers fn for all future events and returns a function
+// that removes it again.
+func (b *Bus) Subscribe(fn func(Event)) func() {
+	_, unsubscribe := b.SubscribeSince(-1, fn)
+	return unsubscribe
+}
+
+// SubscribeSince is Subscribe for a client resuming after event lastId. It
+// also returns the retained events newer than lastId; none are missed or
+// duplicated between those and the ones passed to fn. A negative lastId
+// returns no history.
+func (b *Bus) SubscribeSince(lastId int64, fn func(Event)) ([]Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []Event
+	if lastId >= 0 {
+		for _, e := range b.history {
+			if e.Id > lastId {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+
+	id := b.nextId
+	b.nextId++
+	b.subs[id] = fn
+
+	return backlog, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// Publish assigns the event an id and time and delivers it to subscribers.
+// The event is for the tenant of ctx.
+func (b *Bus) Publish(ctx context.Context, t Type, data any) Event {
+	e := Event{
+		Id:     b.seq.Add(1),
+		Type:   t,
+		Time:   time.Now().UTC(),
+		Tenant: tenant.From(ctx),
+		Data:   data,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.history = append(b.history, e)
+	if len(b.history) > historySize {
+		b.history = b.history[len(b.history)-historySize:]
+	}
+
+	for _, fn := range b.subs {
+		fn(e)
+	}
+
+	return e
+}
@@ -0,0 +1,42 @@
+package studentpb
+
+import (
+	"time"
+
+	"github.com/cmanish049/students-api/internal/types"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FromStudent converts a stored student to its wire message.
+func FromStudent(student types.Student) *Student {
+	return &Student{
+		Id:              int64(student.Id),
+		Name:            student.Name,
+		Email:           student.Email,
+		Age:             int32(student.Age),
+		CreatedAt:       timestamp(student.CreatedAt),
+		UpdatedAt:       timestamp(student.UpdatedAt),
+		EmailVerified:   student.EmailVerified,
+		EmailVerifiedAt: timestamp(student.EmailVerifiedAt),
+		ArchivedAt:      timestamp(student.ArchivedAt),
+	}
+}
+
+// FromStudents converts a list of stored students to its wire message.
+func FromStudents(students []types.Student) *ListStudentsResponse {
+	resp := &ListStudentsResponse{}
+	for _, student := range students {
+		resp.Students = append(resp.Students, FromStudent(student))
+	}
+
+	return resp
+}
+
+// timestamp converts t to its wire message, unset if t is zero.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
@@ -0,0 +1,3 @@
+package studentpb
+
+//go:generate protoc -I ../../../proto --go_out=../../.. --go_opt=module=github.com/cmanish049/students-api --go-grpc_out=../../.. --go-grpc_opt=module=github.com/cmanish049/students-api students/v1/student.proto
@@ -0,0 +1,80 @@
+package studentserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/auth"
+	"github.com/cmanish049/students-api/internal/grpc/studentpb"
+	"github.com/cmanish049/students-api/internal/maintenance"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var mutating = map[string]bool{
+	studentpb.StudentService_CreateStudent_FullMethodName: true,
+	studentpb.StudentService_UpdateStudent_FullMethodName: true,
+	studentpb.StudentService_DeleteStudent_FullMethodName: true,
+}
+
+// ReadOnly rejects mutating calls while maintenance mode is on, like the
+// REST middleware does.
+func ReadOnly(mode *maintenance.Mode) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if mode.Enabled() && mutating[info.FullMethod] {
+			return nil, status.Error(codes.Unavailable, "service is in maintenance mode, try again later")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Roles rejects the mutating calls of viewers logged in with a session
+// token, like the REST middleware does.
+func Roles(sessions *auth.Sessions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if v := md.Get("authorization"); len(v) > 0 && mutating[info.FullMethod] {
+			if session, ok := sessions.Verify(tenant.BearerToken(v[0])); ok && session.Role == auth.RoleViewer {
+				return nil, status.Errorf(codes.PermissionDenied, "role %s can only read", session.Role)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Tenant makes every call act for the tenant of its "authorization: Bearer"
+// or x-tenant-id metadata, like the REST middleware does.
+func Tenant(resolver *tenant.Resolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		first := func(key string) string {
+			if v := md.Get(key); len(v) > 0 {
+				return v[0]
+			}
+			return ""
+		}
+
+		id, err := resolver.Resolve(tenant.BearerToken(first("authorization")), first(strings.ToLower(tenant.Header)))
+
+		var tenantErr *tenant.Error
+		if errors.As(err, &tenantErr) {
+			code := codes.InvalidArgument
+			switch tenantErr.Status {
+			case http.StatusUnauthorized:
+				code = codes.Unauthenticated
+			case http.StatusForbidden:
+				code = codes.PermissionDenied
+			}
+			return nil, status.Error(code, tenantErr.Error())
+		}
+
+		return handler(tenant.With(ctx, id), req)
+	}
+}
@@ -0,0 +1,96 @@
+package studentserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/cmanish049/students-api/internal/grpc/studentpb"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/types"
+	"github.com/cmanish049/students-api/internal/validation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements studentpb.StudentServiceServer on top of the same
+// service as the REST handlers.
+type Server struct {
+	studentpb.UnimplementedStudentServiceServer
+
+	students *studentsvc.Service
+}
+
+func New(students *studentsvc.Service) *Server {
+	return &Server{students: students}
+}
+
+func (s *Server) CreateStudent(ctx context.Context, req *studentpb.CreateStudentRequest) (*studentpb.CreateStudentResponse, error) {
+	student := types.Student{Name: req.GetName(), Email: req.GetEmail(), Age: int(req.GetAge())}
+
+	id, err := s.students.Create(ctx, student)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	slog.Info("student created", slog.Int64("id", id), slog.String("via", "grpc"))
+
+	return &studentpb.CreateStudentResponse{Id: id}, nil
+}
+
+func (s *Server) GetStudent(ctx context.Context, req *studentpb.GetStudentRequest) (*studentpb.Student, error) {
+	student, err := s.students.Get(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return studentpb.FromStudent(student), nil
+}
+
+func (s *Server) ListStudents(ctx context.Context, req *studentpb.ListStudentsRequest) (*studentpb.ListStudentsResponse, error) {
+	students, err := s.students.List(ctx, storage.StudentFilter{})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return studentpb.FromStudents(students), nil
+}
+
+func (s *Server) UpdateStudent(ctx context.Context, req *studentpb.UpdateStudentRequest) (*studentpb.UpdateStudentResponse, error) {
+	student := types.Student{Name: req.GetName(), Email: req.GetEmail(), Age: int(req.GetAge())}
+
+	if err := s.students.Update(ctx, req.GetId(), student); err != nil {
+		return nil, statusError(err)
+	}
+
+	slog.Info("student updated", slog.Int64("id", req.GetId()), slog.String("via", "grpc"))
+
+	return &studentpb.UpdateStudentResponse{}, nil
+}
+
+func (s *Server) DeleteStudent(ctx context.Context, req *studentpb.DeleteStudentRequest) (*studentpb.DeleteStudentResponse, error) {
+	if err := s.students.Delete(ctx, req.GetId()); err != nil {
+		return nil, statusError(err)
+	}
+
+	slog.Info("student deleted", slog.Int64("id", req.GetId()), slog.String("via", "grpc"))
+
+	return &studentpb.DeleteStudentResponse{}, nil
+}
+
+// statusError maps an error from the service to a gRPC status.
+func statusError(err error) error {
+	var validationErr *validation.Error
+
+	switch {
+	case errors.As(err, &validationErr):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, studentsvc.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, studentsvc.ErrEmailTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
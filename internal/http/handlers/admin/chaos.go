@@ -0,0 +1,69 @@
+package admin
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/chaos"
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+type chaosRule struct {
+	Route       string  `json:"route"`
+	Latency     string  `json:"latency,omitempty"`
+	LatencyRate float64 `json:"latency_rate,omitempty"`
+	ErrorRate   float64 `json:"error_rate,omitempty"`
+	ErrorStatus int     `json:"error_status,omitempty"`
+	DropRate    float64 `json:"drop_rate,omitempty"`
+}
+
+type chaosStatus struct {
+	Enabled bool        `json:"enabled"`
+	Rules   []chaosRule `json:"rules,omitempty"`
+}
+
+func GetChaos(injector *chaos.Injector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJson(w, r, http.StatusOK, currentChaos(injector))
+	}
+}
+
+// SetChaos switches fault injection on or off; the rules come from the
+// configuration.
+func SetChaos(injector *chaos.Injector) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		var status chaosStatus
+		if err := handlers.DecodeJSON(r, &status); err != nil {
+			return err
+		}
+
+		injector.Set(status.Enabled)
+
+		slog.Warn("fault injection changed", slog.Bool("enabled", status.Enabled))
+
+		response.WriteJson(w, r, http.StatusOK, currentChaos(injector))
+
+		return nil
+	})
+}
+
+func currentChaos(injector *chaos.Injector) chaosStatus {
+	status := chaosStatus{Enabled: injector.Enabled()}
+
+	for _, rule := range injector.Rules() {
+		r := chaosRule{
+			Route:       rule.Route,
+			LatencyRate: rule.LatencyRate,
+			ErrorRate:   rule.ErrorRate,
+			ErrorStatus: rule.ErrorStatus,
+			DropRate:    rule.DropRate,
+		}
+		if rule.Latency > 0 {
+			r.Latency = rule.Latency.String()
+		}
+		status.Rules = append(status.Rules, r)
+	}
+
+	return status
+}
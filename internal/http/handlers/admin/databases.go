@@ -0,0 +1,16 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/storage/tenantdb"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// GetDatabases lists the tenant databases with the result of their last
+// health check.
+func GetDatabases(databases *tenantdb.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJson(w, r, http.StatusOK, databases.Status())
+	}
+}
@@ -0,0 +1,102 @@
+package admin
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	"github.com/cmanish049/students-api/internal/jobs"
+	"github.com/cmanish049/students-api/internal/types"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// jobLimit is the default and maximum number of jobs listed
+const jobLimit = 100
+
+var jobStatuses = []string{types.JobQueued, types.JobRunning, types.JobDone, types.JobDead}
+
+type jobList struct {
+	Counts map[string]int `json:"counts"`
+	Jobs   []types.Job    `json:"jobs"`
+}
+
+// GetJobs lists the most recent jobs with the number of jobs per status.
+// ?status= and ?kind= filter the list, ?limit= shortens it.
+func GetJobs(queue *jobs.Queue) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		query := r.URL.Query()
+
+		status := query.Get("status")
+		if status != "" && !slices.Contains(jobStatuses, status) {
+			return handlers.Errorf(http.StatusBadRequest, "unknown status %q", status)
+		}
+
+		limit := jobLimit
+		if v := query.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > jobLimit {
+				return handlers.Errorf(http.StatusBadRequest, "limit must be between 1 and %d", jobLimit)
+			}
+			limit = n
+		}
+
+		counts, err := queue.Counts(r.Context())
+		if err != nil {
+			return err
+		}
+
+		list, err := queue.List(r.Context(), status, query.Get("kind"), limit)
+		if err != nil {
+			return err
+		}
+
+		response.WriteJson(w, r, http.StatusOK, response.Page{Data: jobList{Counts: counts, Jobs: list}, Pagination: response.Pagination{Limit: limit}})
+
+		return nil
+	})
+}
+
+func GetJob(queue *jobs.Queue) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := handlers.PathId(r)
+		if err != nil {
+			return err
+		}
+
+		job, err := queue.Get(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		response.WriteJson(w, r, http.StatusOK, job)
+
+		return nil
+	})
+}
+
+// RetryJob puts a dead-lettered job back on the queue.
+func RetryJob(queue *jobs.Queue) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := handlers.PathId(r)
+		if err != nil {
+			return err
+		}
+
+		if err := queue.Retry(r.Context(), id); err != nil {
+			return err
+		}
+
+		slog.Info("dead job requeued", slog.Int64("id", id))
+
+		job, err := queue.Get(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		response.WriteJson(w, r, http.StatusOK, job)
+
+		return nil
+	})
+}
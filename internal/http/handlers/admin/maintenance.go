@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	"github.com/cmanish049/students-api/internal/maintenance"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+type maintenanceStatus struct {
+	Enabled bool `json:"enabled"`
+}
+
+func GetMaintenance(mode *maintenance.Mode) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJson(w, r, http.StatusOK, maintenanceStatus{Enabled: mode.Enabled()})
+	}
+}
+
+func SetMaintenance(mode *maintenance.Mode) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		var status maintenanceStatus
+		if err := handlers.DecodeJSON(r, &status); err != nil {
+			return err
+		}
+
+		mode.Set(status.Enabled)
+
+		slog.Info("maintenance mode changed", slog.Bool("enabled", status.Enabled))
+
+		response.WriteJson(w, r, http.StatusOK, status)
+
+		return nil
+	})
+}
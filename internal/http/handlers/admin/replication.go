@@ -0,0 +1,16 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/replica"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// GetReplication lists the databases with their last replicated snapshot
+// and the result of their last pass.
+func GetReplication(replicator *replica.Replicator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJson(w, r, http.StatusOK, replicator.Status())
+	}
+}
@@ -0,0 +1,41 @@
+package admin
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	"github.com/cmanish049/students-api/internal/schedule"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// GetSchedules lists the configured schedules with their last run.
+func GetSchedules(scheduler *schedule.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJson(w, r, http.StatusOK, scheduler.Status())
+	}
+}
+
+// RunSchedule runs a schedule's task right away, e.g. a backup before a
+// risky change.
+func RunSchedule(scheduler *schedule.Scheduler) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		name := r.PathValue("name")
+
+		err := scheduler.Run(name)
+		if errors.Is(err, schedule.ErrRunning) {
+			return handlers.WithStatus(http.StatusConflict, err)
+		}
+
+		if err != nil {
+			return handlers.WithStatus(http.StatusNotFound, err)
+		}
+
+		slog.Info("scheduled task started by request", slog.String("schedule", name))
+
+		response.WriteJson(w, r, http.StatusAccepted, map[string]string{"message": "task started"})
+
+		return nil
+	})
+}
@@ -0,0 +1,41 @@
+package admin
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	"github.com/cmanish049/students-api/internal/sms"
+	"github.com/cmanish049/students-api/internal/utils/response"
+	"github.com/cmanish049/students-api/internal/validation"
+)
+
+type smsRequest struct {
+	To   string `json:"to" validate:"required,phone"`
+	Body string `json:"body" validate:"required"`
+}
+
+// SendSms queues a text message, e.g. to check the provider settings.
+func SendSms(notifier *sms.Notifier, validate *validation.Validator) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		var req smsRequest
+		if err := handlers.DecodeJSON(r, &req); err != nil {
+			return err
+		}
+
+		if err := validate.Struct(req); err != nil {
+			return handlers.WithStatus(http.StatusBadRequest, err)
+		}
+
+		id, err := notifier.Notify(r.Context(), req.To, req.Body)
+		if err != nil {
+			return handlers.WithStatus(http.StatusBadRequest, err)
+		}
+
+		slog.Info("sms queued by request", slog.Int64("job_id", id))
+
+		response.WriteJson(w, r, http.StatusAccepted, map[string]int64{"job_id": id})
+
+		return nil
+	})
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DecodeJSON decodes the request body into v. Fields v doesn't have and
+// data after the JSON value are rejected, and decoding errors are answered
+// as bad requests naming the field at fault (see JSONError).
+func DecodeJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return JSONError(err)
+	}
+
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			return JSONError(err)
+		}
+		return Errorf(http.StatusBadRequest, "body must be a single JSON value")
+	}
+
+	return nil
+}
+
+// JSONError turns an error reading or decoding a JSON body into one that is
+// answered with a clear message: 413 for a body over the limit of
+// http.MaxBytesReader, and 400 with the field for unknown fields and values
+// of the wrong type, or with the offset for malformed JSON.
+func JSONError(err error) error {
+	var maxBytes *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return Errorf(http.StatusBadRequest, "empty body")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return Errorf(http.StatusBadRequest, "malformed JSON, the body ends early")
+	case errors.As(err, &maxBytes):
+		return Errorf(http.StatusRequestEntityTooLarge, "body is larger than %d bytes", maxBytes.Limit)
+	case errors.As(err, &syntaxErr):
+		return Errorf(http.StatusBadRequest, "malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		errs := &InvalidFields{}
+		if typeErr.Field == "" {
+			errs.Add("", "type", "body must be of type %s", jsonType(typeErr.Type))
+		} else {
+			errs.Add(typeErr.Field, "type", "field %s must be of type %s", typeErr.Field, jsonType(typeErr.Type))
+		}
+		return errs
+	}
+
+	// the decoder has no error type for unknown fields
+	if quoted, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field, unquoteErr := strconv.Unquote(quoted)
+		if unquoteErr == nil {
+			errs := &InvalidFields{}
+			errs.Add(field, "unknown", "field %s is unknown", field)
+			return errs
+		}
+	}
+
+	return WithStatus(http.StatusBadRequest, err)
+}
+
+// jsonType returns the JSON type values of t are decoded from.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	}
+
+	return "object"
+}
@@ -0,0 +1,31 @@
+package docs
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// Spec is the hand-maintained OpenAPI document for the API. Keep it in sync
+// with the handlers when changing routes, payloads or error responses;
+// requests are checked against it, see package openapi.
+//
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed docs.html
+var page []byte
+
+func OpenAPI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(Spec)
+	}
+}
+
+// SwaggerUI serves a page rendering Spec with Swagger UI.
+func SwaggerUI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	}
+}
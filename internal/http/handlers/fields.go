@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/i18n"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+type invalidField struct {
+	field  string
+	rule   string
+	format string
+	args   []any
+}
+
+// InvalidFields is a FieldsError built a field at a time, with messages
+// translated from the i18n catalog.
+type InvalidFields struct {
+	fields []invalidField
+}
+
+// Add records that field failed rule, with the formatted message.
+func (e *InvalidFields) Add(field, rule, format string, args ...any) {
+	e.fields = append(e.fields, invalidField{field: field, rule: rule, format: format, args: args})
+}
+
+// Len returns the number of invalid fields.
+func (e *InvalidFields) Len() int {
+	return len(e.fields)
+}
+
+func (e *InvalidFields) Error() string {
+	return e.Translate(i18n.English)
+}
+
+func (e *InvalidFields) Translate(lang string) string {
+	msgs := make([]string, len(e.fields))
+	for i, f := range e.fields {
+		msgs[i] = i18n.Sprintf(lang, f.format, f.args...)
+	}
+
+	return strings.Join(msgs, ", ")
+}
+
+func (e *InvalidFields) Fields(lang string) []response.FieldError {
+	fields := make([]response.FieldError, len(e.fields))
+	for i, f := range e.fields {
+		fields[i] = response.FieldError{Field: f.field, Rule: f.rule, Message: i18n.Sprintf(lang, f.format, f.args...)}
+	}
+
+	return fields
+}
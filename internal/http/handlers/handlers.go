@@ -0,0 +1,151 @@
+// Package handlers has what the HTTP handlers of every API version share:
+// handlers return their errors, and Handle answers them with the status
+// that matches the error, so every handler reports failures the same way.
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/cmanish049/students-api/internal/i18n"
+	"github.com/cmanish049/students-api/internal/requestid"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/utils/response"
+	"github.com/cmanish049/students-api/internal/validation"
+	"github.com/go-playground/validator/v10"
+)
+
+// Func is a handler that returns an error instead of answering it. It must
+// not have written a response when it returns one.
+type Func func(w http.ResponseWriter, r *http.Request) error
+
+// Error is an error answered with a given status.
+type Error struct {
+	Status int
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithStatus makes err be answered with status.
+func WithStatus(status int, err error) error {
+	return &Error{Status: status, Err: err}
+}
+
+// Errorf returns an error with the formatted message, answered with status.
+// The message is translated if format is in the i18n catalog.
+func Errorf(status int, format string, args ...any) error {
+	return &Error{Status: status, Err: i18n.Errorf(format, args...)}
+}
+
+// Handle adapts fn to an http.HandlerFunc that answers its errors.
+func Handle(fn Func) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := fn(w, r); err != nil {
+			WriteError(w, r, err)
+		}
+	}
+}
+
+// FieldsError is an error about invalid fields of a request, answered as a
+// validation problem listing them. *validation.Error is one.
+type FieldsError interface {
+	error
+	// Translate returns the message of the error in lang
+	Translate(lang string) string
+	// Fields returns the invalid fields with their messages in lang
+	Fields(lang string) []response.FieldError
+}
+
+// Coded is implemented by errors with a more specific code than the one of
+// their status.
+type Coded interface {
+	error
+	Code() response.Code
+}
+
+// StatusOf returns the status an error is answered with: the one it was
+// given, 400 for validation errors, 404 and 409 for the storage's missing
+// records and conflicts, and 500 for anything else.
+func StatusOf(err error) int {
+	var statusErr *Error
+	var fieldsErr FieldsError
+	var validateErrs validator.ValidationErrors
+
+	switch {
+	case errors.As(err, &statusErr):
+		return statusErr.Status
+	case errors.As(err, &fieldsErr), errors.As(err, &validateErrs):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrConflict):
+		return http.StatusConflict
+	}
+
+	return http.StatusInternalServerError
+}
+
+// WriteError logs err and answers it with a problem+json body, in the
+// language of the request's Accept-Language header. Server errors are
+// logged as errors, client errors only at debug level.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status := StatusOf(err)
+
+	log := slog.With(slog.String("method", r.Method), slog.String("path", r.URL.Path), slog.Int("status", status),
+		slog.String("request_id", requestid.From(r.Context())), slog.String("error", err.Error()))
+	if status >= http.StatusInternalServerError {
+		log.Error("request failed")
+	} else {
+		log.Debug("request rejected")
+	}
+
+	lang := i18n.FromRequest(r)
+	w.Header().Set("Content-Language", lang)
+
+	p := response.NewProblem(r, status, i18n.Translate(lang, err))
+	p.Title = i18n.Text(lang, p.Title)
+
+	var fieldsErr FieldsError
+	var validateErrs validator.ValidationErrors
+	if !errors.As(err, &fieldsErr) && errors.As(err, &validateErrs) {
+		fieldsErr = &validation.Error{Errors: validateErrs}
+	}
+
+	switch {
+	case fieldsErr != nil:
+		p.Type = response.ProblemValidation
+		p.Code = response.CodeValidation
+		p.Detail = fieldsErr.Translate(lang)
+		p.Errors = fieldsErr.Fields(lang)
+	case status == http.StatusNotFound:
+		p.Type = response.ProblemNotFound
+	case status == http.StatusConflict:
+		p.Type = response.ProblemConflict
+	}
+
+	var coded Coded
+	if errors.As(err, &coded) {
+		p.Code = coded.Code()
+	}
+
+	response.WriteProblem(w, r, p)
+}
+
+// PathId parses the {id} path value.
+func PathId(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return 0, Errorf(http.StatusBadRequest, "invalid id format")
+	}
+
+	return id, nil
+}
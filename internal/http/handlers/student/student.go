@@ -1,170 +0,0 @@
-package student
-
-import (
-	"encoding/json"
-	"errors"
-	"fmt"
-	"io"
-	"log/slog"
-	"net/http"
-	"strconv"
-
-	"github.com/cmanish049/students-api/internal/storage"
-	"github.com/cmanish049/students-api/internal/types"
-	"github.com/cmanish049/students-api/internal/utils/response"
-	"github.com/go-playground/validator/v10"
-)
-
-func New(storage storage.Storage) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		slog.Info("create a student")
-
-		var student types.Student
-		err := json.NewDecoder(r.Body).Decode(&student)
-
-		if errors.Is(err, io.EOF) {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("empty body")))
-			return
-		}
-
-		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
-			return
-		}
-
-		// request validation
-		if err = validator.New().Struct(student); err != nil {
-			validateErrs := err.(validator.ValidationErrors)
-			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrs))
-			return
-		}
-
-		studentId, err := storage.CreateStudent(student.Name, student.Email, student.Age)
-		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
-			return
-		}
-
-		slog.Info("student created", slog.Int64("id", studentId))
-
-		response.WriteJson(w, http.StatusCreated, map[string]int64{"id": studentId})
-	}
-}
-
-func GetById(storage storage.Storage) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		id := r.PathValue("id")
-
-		if id == "" {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("id is required")))
-			return
-		}
-
-		// Implementation to get student by ID goes here
-
-		idInt64, err := strconv.ParseInt(id, 10, 64)
-		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid id format")))
-			return
-		}
-
-		student, err := storage.GetStudentById(idInt64)
-
-		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
-			return
-		}
-
-		response.WriteJson(w, http.StatusOK, student)
-	}
-}
-
-func GetStudentList(storage storage.Storage) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Implementation to get list of students goes here
-		slog.Info("get student list")
-
-		students, err := storage.GetStudentList()
-		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
-			return
-		}
-
-		// For now, just return an empty list
-		response.WriteJson(w, http.StatusOK, students)
-	}
-}
-
-func UpdateStudent(storage storage.Storage) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		id := r.PathValue("id")
-
-		if id == "" {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("id is required")))
-			return
-		}
-
-		idInt64, err := strconv.ParseInt(id, 10, 64)
-		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid id format")))
-			return
-		}
-
-		var student types.Student
-		err = json.NewDecoder(r.Body).Decode(&student)
-
-		if errors.Is(err, io.EOF) {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("empty body")))
-			return
-		}
-
-		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
-			return
-		}
-
-		// request validation
-		if err = validator.New().Struct(student); err != nil {
-			validateErrs := err.(validator.ValidationErrors)
-			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrs))
-			return
-		}
-
-		err = storage.UpdateStudent(idInt64, student.Name, student.Email, student.Age)
-		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
-			return
-		}
-
-		slog.Info("student updated", slog.Int64("id", idInt64))
-
-		response.WriteJson(w, http.StatusOK, map[string]string{"message": "student updated successfully"})
-	}
-}
-
-func DeleteStudent(storage storage.Storage) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		id := r.PathValue("id")
-
-		if id == "" {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("id is required")))
-			return
-		}
-
-		idInt64, err := strconv.ParseInt(id, 10, 64)
-		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid id format")))
-			return
-		}
-
-		err = storage.DeleteStudent(idInt64)
-		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
-			return
-		}
-
-		slog.Info("student deleted", slog.Int64("id", idInt64))
-
-		response.WriteJson(w, http.StatusOK, map[string]string{"message": "student deleted successfully"})
-	}
-}
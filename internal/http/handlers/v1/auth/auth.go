@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/auth"
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// SessionResponse is a session, with its token when it was just issued.
+type SessionResponse struct {
+	Token     string `json:"token,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	auth.Session
+}
+
+// Login checks the directory credentials of the body and answers a session
+// token to send as "Authorization: Bearer <token>".
+func Login(directory *auth.Directory) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		var req loginRequest
+		if err := handlers.DecodeJSON(r, &req); err != nil {
+			return err
+		}
+
+		session, token, err := directory.Login(r.Context(), req.Username, req.Password)
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			slog.Warn("login refused", slog.String("user", req.Username))
+			return handlers.Errorf(http.StatusUnauthorized, "invalid username or password")
+		case errors.Is(err, auth.ErrNoRole):
+			slog.Warn("login refused, no role", slog.String("user", req.Username))
+			return handlers.Errorf(http.StatusForbidden, "user %s has no role in this API", req.Username)
+		case err != nil:
+			// the cause is logged only, it may name the directory's entries
+			slog.Error("directory login failed", slog.String("user", req.Username), slog.String("error", err.Error()))
+			return handlers.Errorf(http.StatusBadGateway, "the directory server is unavailable")
+		}
+
+		slog.Info("user logged in", slog.String("user", session.User), slog.String("role", session.Role), slog.String("tenant", session.Tenant))
+
+		response.WriteJson(w, r, http.StatusOK, SessionResponse{Token: token, TokenType: "Bearer", Session: session})
+
+		return nil
+	})
+}
+
+// GetSession answers the session the request is authenticated by.
+func GetSession() http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		session, ok := auth.From(r.Context())
+		if !ok {
+			return handlers.Errorf(http.StatusNotFound, "the request is not authenticated by a session")
+		}
+
+		response.WriteJson(w, r, http.StatusOK, SessionResponse{Session: session})
+
+		return nil
+	})
+}
@@ -0,0 +1,174 @@
+// Package batch serves several API requests sent in one, for clients on
+// links where every round trip is slow.
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// MaxRequests is how many requests a batch may have
+const MaxRequests = 50
+
+// Request is one request of a batch. It is sent with the headers of the
+// batch, such as Authorization, overridden by Headers.
+type Request struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// Response is the answer to one request of a batch. Body is the JSON body
+// the request was answered with, envelope included.
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// methods are those a request of a batch may use
+var methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Batch serves the requests of the body, in order, through api and
+// answers their responses in the same order. A failing request doesn't
+// stop the ones after it; each is answered as if sent on its own, through
+// the same middleware, except that they are served in the in-flight slot
+// of the batch and within its timeout.
+func Batch(api http.Handler) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		var reqs []Request
+		if err := handlers.DecodeJSON(r, &reqs); err != nil {
+			return err
+		}
+
+		if len(reqs) == 0 || len(reqs) > MaxRequests {
+			return handlers.Errorf(http.StatusBadRequest, "a batch must have between 1 and %d requests", MaxRequests)
+		}
+
+		res := make([]Response, len(reqs))
+		for i, req := range reqs {
+			res[i] = serve(api, r, req)
+		}
+
+		slog.Info("batch served", slog.Int("requests", len(reqs)))
+
+		response.WriteJson(w, r, http.StatusOK, res)
+
+		return nil
+	})
+}
+
+// serve serves one request of the batch r through api.
+func serve(api http.Handler, r *http.Request, req Request) Response {
+	rec := &recorder{header: http.Header{}}
+
+	sub, err := newRequest(r, req)
+	if err != nil {
+		// answered like a request the API rejects
+		handlers.WriteError(rec, r, err)
+	} else {
+		api.ServeHTTP(rec, sub)
+	}
+
+	return rec.response()
+}
+
+// newRequest builds the request req of the batch r, in its context.
+func newRequest(r *http.Request, req Request) (*http.Request, error) {
+	method := strings.ToUpper(req.Method)
+	if !slices.Contains(methods, method) {
+		return nil, handlers.Errorf(http.StatusBadRequest, "unsupported method %q", req.Method)
+	}
+
+	if !strings.HasPrefix(req.Path, "/api/") {
+		return nil, handlers.Errorf(http.StatusBadRequest, "path %q is not an API path", req.Path)
+	}
+
+	var body []byte
+	if len(req.Body) > 0 && string(req.Body) != "null" {
+		body = req.Body
+	}
+
+	sub, err := http.NewRequestWithContext(r.Context(), method, req.Path, bytes.NewReader(body))
+	if err != nil {
+		return nil, handlers.Errorf(http.StatusBadRequest, "invalid path %q", req.Path)
+	}
+
+	if strings.TrimSuffix(sub.URL.Path, "/") == strings.TrimSuffix(r.URL.Path, "/") {
+		return nil, handlers.Errorf(http.StatusBadRequest, "batches cannot be nested")
+	}
+
+	sub.Header = r.Header.Clone()
+	sub.Header.Del("Content-Length")
+	sub.Header.Set("Accept", "application/json")
+	sub.Header.Set("Content-Type", "application/json")
+	for name, value := range req.Headers {
+		sub.Header.Set(name, value)
+	}
+
+	sub.Host, sub.RemoteAddr = r.Host, r.RemoteAddr
+	sub.RequestURI = sub.URL.RequestURI()
+	sub.ContentLength = int64(len(body))
+
+	return sub, nil
+}
+
+// recorder keeps the response to a request of a batch. It can't be
+// flushed or hijacked, so event streams answer that they aren't supported.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+
+	return rec.body.Write(p)
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *recorder) response() Response {
+	res := Response{Status: rec.status}
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+
+	for name, values := range rec.header {
+		if name == "Content-Length" || name == "Content-Type" {
+			continue
+		}
+		if res.Headers == nil {
+			res.Headers = map[string]string{}
+		}
+		res.Headers[name] = strings.Join(values, ", ")
+	}
+
+	if body := bytes.TrimSpace(rec.body.Bytes()); json.Valid(body) {
+		res.Body = body
+	} else if len(body) > 0 {
+		// the body of a response that isn't JSON is kept as a string
+		res.Body, _ = json.Marshal(string(body))
+	}
+
+	return res
+}
@@ -0,0 +1,250 @@
+package file
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/blob"
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	filesvc "github.com/cmanish049/students-api/internal/service/file"
+	"github.com/cmanish049/students-api/internal/types"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// FileResponse is a file with a URL that downloads it without a token
+// until UrlExpiresAt.
+type FileResponse struct {
+	types.File
+	Url          string    `json:"url"`
+	UrlExpiresAt time.Time `json:"url_expires_at"`
+}
+
+func newFileResponse(r *http.Request, files *filesvc.Service, f types.File) (FileResponse, error) {
+	url, expires, err := files.URL(r.Context(), f)
+	if err != nil {
+		return FileResponse{}, err
+	}
+
+	return FileResponse{File: f, Url: url, UrlExpiresAt: expires}, nil
+}
+
+// Upload stores the body, of any allowed content type, as a file of
+// student {id} named ?name=. The body is streamed to the blob store, so
+// its Content-Length must be known.
+func Upload(files *filesvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := handlers.PathId(r)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case r.ContentLength < 0:
+			return handlers.Errorf(http.StatusLengthRequired, "Content-Length is required")
+		case r.ContentLength == 0:
+			return handlers.Errorf(http.StatusBadRequest, "empty body")
+		}
+
+		ctx := r.Context()
+		dryRun := dryrun.Requested(r)
+		if dryRun {
+			w.Header().Set(dryrun.Header, "true")
+			ctx = dryrun.With(ctx)
+		}
+
+		contentType := r.Header.Get("Content-Type")
+		f, err := files.Upload(ctx, id, r.URL.Query().Get("name"), contentType, r.ContentLength, r.Body)
+		var maxBytes *http.MaxBytesError
+		switch {
+		case errors.Is(err, filesvc.ErrUnsupportedType):
+			return handlers.Errorf(http.StatusUnsupportedMediaType, "unsupported content type %q, expected %s", contentType, strings.Join(files.AllowedTypes(), " or "))
+		case errors.Is(err, filesvc.ErrNoName):
+			return handlers.Errorf(http.StatusBadRequest, "file name is required")
+		case errors.As(err, &maxBytes):
+			return handlers.Errorf(http.StatusRequestEntityTooLarge, "body is larger than %d bytes", maxBytes.Limit)
+		case err != nil:
+			return err
+		}
+
+		if dryRun {
+			response.WriteJson(w, r, http.StatusOK, map[string]any{"dry_run": true, "message": "file would be uploaded", "file": f})
+			return nil
+		}
+
+		slog.Info("file uploaded", slog.Int64("student_id", id), slog.Int64("id", f.Id), slog.String("content_type", f.ContentType), slog.Int64("size", f.Size))
+
+		res, err := newFileResponse(r, files, f)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Location", "/api/v1/students/"+strconv.FormatInt(id, 10)+"/files/"+strconv.FormatInt(f.Id, 10))
+		response.WriteJson(w, r, http.StatusCreated, res)
+
+		return nil
+	})
+}
+
+// GetFiles lists the files of student {id}, oldest first.
+func GetFiles(files *filesvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := handlers.PathId(r)
+		if err != nil {
+			return err
+		}
+
+		list, err := files.List(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		res := make([]FileResponse, len(list))
+		for i, f := range list {
+			if res[i], err = newFileResponse(r, files, f); err != nil {
+				return err
+			}
+		}
+
+		response.WriteJson(w, r, http.StatusOK, res)
+
+		return nil
+	})
+}
+
+// GetFile answers file {fileId} of student {id}.
+func GetFile(files *filesvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		f, err := pathFile(r, files)
+		if err != nil {
+			return err
+		}
+
+		res, err := newFileResponse(r, files, f)
+		if err != nil {
+			return err
+		}
+
+		response.WriteJson(w, r, http.StatusOK, res)
+
+		return nil
+	})
+}
+
+// Download redirects to a URL that downloads file {fileId} of student
+// {id}, for clients that follow redirects with the token they have.
+func Download(files *filesvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		f, err := pathFile(r, files)
+		if err != nil {
+			return err
+		}
+
+		url, _, err := files.URL(r.Context(), f)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, url, http.StatusFound)
+
+		return nil
+	})
+}
+
+// DeleteFile deletes file {fileId} of student {id} and its contents.
+func DeleteFile(files *filesvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, fileId, err := filePath(r)
+		if err != nil {
+			return err
+		}
+
+		ctx := r.Context()
+		dryRun := dryrun.Requested(r)
+		if dryRun {
+			w.Header().Set(dryrun.Header, "true")
+			ctx = dryrun.With(ctx)
+		}
+
+		if err := files.Delete(ctx, id, fileId); err != nil {
+			return err
+		}
+
+		if dryRun {
+			response.WriteJson(w, r, http.StatusOK, map[string]any{"dry_run": true, "message": "file would be deleted"})
+			return nil
+		}
+
+		slog.Info("file deleted", slog.Int64("student_id", id), slog.Int64("id", fileId))
+
+		response.WriteJson(w, r, http.StatusOK, map[string]string{"message": "file deleted successfully"})
+
+		return nil
+	})
+}
+
+// Blob serves the downloads of the URLs of the local blob store, which
+// carry their own signature instead of a token.
+func Blob(local *blob.Local) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		key := r.PathValue("key")
+
+		filename, contentType, ok := local.Verify(key, r.URL.Query())
+		if !ok {
+			return handlers.Errorf(http.StatusForbidden, "the download link is invalid or has expired")
+		}
+
+		body, err := local.Get(r.Context(), key)
+		if errors.Is(err, blob.ErrNotFound) {
+			return handlers.Errorf(http.StatusNotFound, "file not found")
+		}
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", blob.Disposition(filename))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "private, no-cache")
+
+		// files on disk can answer ranges
+		if rs, ok := body.(io.ReadSeeker); ok {
+			http.ServeContent(w, r, "", time.Time{}, rs)
+			return nil
+		}
+
+		io.Copy(w, body)
+
+		return nil
+	})
+}
+
+func pathFile(r *http.Request, files *filesvc.Service) (types.File, error) {
+	id, fileId, err := filePath(r)
+	if err != nil {
+		return types.File{}, err
+	}
+
+	return files.Get(r.Context(), id, fileId)
+}
+
+func filePath(r *http.Request) (int64, int64, error) {
+	id, err := handlers.PathId(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fileId, err := strconv.ParseInt(r.PathValue("fileId"), 10, 64)
+	if err != nil {
+		return 0, 0, handlers.Errorf(http.StatusBadRequest, "invalid id format")
+	}
+
+	return id, fileId, nil
+}
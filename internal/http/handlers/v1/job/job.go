@@ -0,0 +1,118 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/bulk"
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	"github.com/cmanish049/students-api/internal/jobs"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/types"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// kinds of jobs clients can see; the rest is only shown on the admin listener
+var kinds = []string{bulk.ImportKind, bulk.ExportKind}
+
+type jobStatus struct {
+	Id        int64             `json:"id"`
+	Kind      string            `json:"kind"`
+	Status    string            `json:"status"`
+	Progress  types.JobProgress `json:"progress"`
+	Result    json.RawMessage   `json:"result,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	ResultUrl string            `json:"result_url,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// GetById reports the progress of a job started through the API, and its
+// result once it is done.
+func GetById(queue *jobs.Queue) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		job, err := lookup(r, queue)
+		if err != nil {
+			return err
+		}
+
+		status := jobStatus{
+			Id:        job.Id,
+			Kind:      job.Kind,
+			Status:    job.Status,
+			Progress:  job.Progress,
+			Result:    job.Result,
+			CreatedAt: job.CreatedAt,
+			UpdatedAt: job.UpdatedAt,
+		}
+
+		if job.Status == types.JobDead {
+			status.Error = job.LastError
+		}
+
+		if job.Status == types.JobDone && job.Kind == bulk.ExportKind {
+			status.ResultUrl = "/api/v1/jobs/" + strconv.FormatInt(job.Id, 10) + "/result"
+		}
+
+		response.WriteJson(w, r, http.StatusOK, status)
+
+		return nil
+	})
+}
+
+// GetResult downloads the output of a finished export.
+func GetResult(queue *jobs.Queue) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		job, err := lookup(r, queue)
+		if err != nil {
+			return err
+		}
+
+		if job.Kind != bulk.ExportKind {
+			return handlers.Errorf(http.StatusNotFound, "job %d has no result to download", job.Id)
+		}
+
+		if job.Status != types.JobDone {
+			return handlers.Errorf(http.StatusConflict, "job %d is %s", job.Id, job.Status)
+		}
+
+		output, err := queue.Output(r.Context(), job.Id)
+		if err != nil {
+			return err
+		}
+
+		var result bulk.ExportResult
+		json.Unmarshal(job.Result, &result)
+
+		w.Header().Set("Content-Type", bulk.Formats[result.Format])
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="students-%d.%s"`, job.Id, result.Format))
+		w.WriteHeader(http.StatusOK)
+		w.Write(output)
+
+		return nil
+	})
+}
+
+// lookup returns the job of the {id} path value if clients may see it.
+func lookup(r *http.Request, queue *jobs.Queue) (types.Job, error) {
+	id, err := handlers.PathId(r)
+	if err != nil {
+		return types.Job{}, err
+	}
+
+	job, err := queue.Get(r.Context(), id)
+	if err != nil {
+		return types.Job{}, err
+	}
+
+	// jobs of other kinds are not the client's business
+	if !slices.Contains(kinds, job.Kind) {
+		return types.Job{}, storage.NotFound("no job found with id %d", id)
+	}
+
+	return job, nil
+}
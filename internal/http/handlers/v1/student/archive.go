@@ -0,0 +1,40 @@
+package student
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// Unarchive moves archived student {id} back among the students and
+// answers it.
+func Unarchive(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := handlers.PathId(r)
+		if err != nil {
+			return err
+		}
+
+		ctx, dryRun := dryRunContext(w, r)
+
+		student, err := students.Unarchive(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		after := newStudentResponse(student)
+		if dryRun {
+			response.WriteJson(w, r, http.StatusOK, dryRunResult{DryRun: true, Message: "student would be unarchived", After: &after})
+			return nil
+		}
+
+		slog.Info("student unarchived", slog.Int64("id", id))
+
+		response.WriteJson(w, r, http.StatusOK, after)
+
+		return nil
+	})
+}
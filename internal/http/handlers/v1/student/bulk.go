@@ -0,0 +1,84 @@
+package student
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/cmanish049/students-api/internal/bulk"
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	"github.com/cmanish049/students-api/internal/types"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// accepted is the answer to a request that started a job.
+type accepted struct {
+	JobId     int64  `json:"job_id"`
+	Status    string `json:"status"`
+	StatusUrl string `json:"status_url"`
+}
+
+func writeAccepted(w http.ResponseWriter, r *http.Request, id int64) {
+	url := "/api/v1/jobs/" + strconv.FormatInt(id, 10)
+
+	w.Header().Set("Location", url)
+	response.WriteJson(w, r, http.StatusAccepted, accepted{JobId: id, Status: types.JobQueued, StatusUrl: url})
+}
+
+// Import queues the creation of a JSON array of students and answers 202
+// with the job to follow. Records are validated when the job runs, and the
+// job result lists the ones that failed.
+func Import(b *bulk.Bulk) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		var reqs []CreateStudentRequest
+		if err := handlers.DecodeJSON(r, &reqs); err != nil {
+			return err
+		}
+
+		if len(reqs) == 0 {
+			return handlers.Errorf(http.StatusBadRequest, "no students to import")
+		}
+
+		students := make([]types.Student, len(reqs))
+		for i, req := range reqs {
+			students[i] = req.student()
+		}
+
+		id, err := b.Import(r.Context(), students)
+		if err != nil {
+			return err
+		}
+
+		slog.Info("student import queued", slog.Int64("job_id", id), slog.Int("students", len(students)))
+
+		writeAccepted(w, r, id)
+
+		return nil
+	})
+}
+
+// Export queues an export of all students, ?format=json (default) or csv,
+// and answers 202 with the job to follow.
+func Export(b *bulk.Bulk) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+
+		if _, ok := bulk.Formats[format]; !ok {
+			return handlers.Errorf(http.StatusBadRequest, "unknown format %q, use json or csv", format)
+		}
+
+		id, err := b.Export(r.Context(), format)
+		if err != nil {
+			return err
+		}
+
+		slog.Info("student export queued", slog.Int64("job_id", id), slog.String("format", format))
+
+		writeAccepted(w, r, id)
+
+		return nil
+	})
+}
@@ -0,0 +1,69 @@
+package student
+
+import (
+	"time"
+
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// The v1 API's view of students. They are converted to and from
+// types.Student at the handler, so the API and the storage model can change
+// separately.
+
+// CreateStudentRequest is the body of a create or of a record in an import.
+type CreateStudentRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Age   int    `json:"age"`
+}
+
+func (req CreateStudentRequest) student() types.Student {
+	return types.Student{Name: req.Name, Email: req.Email, Age: req.Age}
+}
+
+// UpdateStudentRequest is the body of an update; it replaces every field.
+type UpdateStudentRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Age   int    `json:"age"`
+}
+
+func (req UpdateStudentRequest) student() types.Student {
+	return types.Student{Name: req.Name, Email: req.Email, Age: req.Age}
+}
+
+// StudentResponse is a student as the API answers it.
+type StudentResponse struct {
+	Id              int       `json:"id"`
+	Name            string    `json:"name"`
+	Email           string    `json:"email"`
+	Age             int       `json:"age"`
+	EmailVerified   bool      `json:"email_verified"`
+	EmailVerifiedAt time.Time `json:"email_verified_at,omitzero"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	ArchivedAt      time.Time `json:"archived_at,omitzero"`
+}
+
+func newStudentResponse(student types.Student) StudentResponse {
+	return StudentResponse{
+		Id:              student.Id,
+		Name:            student.Name,
+		Email:           student.Email,
+		Age:             student.Age,
+		EmailVerified:   student.EmailVerified,
+		EmailVerifiedAt: student.EmailVerifiedAt,
+		CreatedAt:       student.CreatedAt,
+		UpdatedAt:       student.UpdatedAt,
+		ArchivedAt:      student.ArchivedAt,
+	}
+}
+
+func newStudentResponses(students []types.Student) []StudentResponse {
+	res := make([]StudentResponse, len(students))
+	for i, student := range students {
+		res[i] = newStudentResponse(student)
+	}
+
+	return res
+}
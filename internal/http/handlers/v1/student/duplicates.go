@@ -0,0 +1,98 @@
+package student
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// DuplicateResponse is a pair of students that look like the same person.
+type DuplicateResponse struct {
+	Student        StudentResponse `json:"student"`
+	Other          StudentResponse `json:"other"`
+	EmailMatch     bool            `json:"email_match"`
+	NameSimilarity float64         `json:"name_similarity"`
+}
+
+// GetDuplicates lists the pairs of students with the same normalized email
+// or names at least ?min_similarity= (0 to 1) alike.
+func GetDuplicates(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		minSimilarity := studentsvc.DefaultSimilarity
+		if v := r.URL.Query().Get("min_similarity"); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 || f > 1 {
+				return handlers.Errorf(http.StatusBadRequest, "min_similarity must be between 0 and 1")
+			}
+			minSimilarity = f
+		}
+
+		dups, err := students.Duplicates(r.Context(), minSimilarity)
+		if err != nil {
+			return err
+		}
+
+		res := make([]DuplicateResponse, len(dups))
+		for i, d := range dups {
+			res[i] = DuplicateResponse{
+				Student:        newStudentResponse(d.Student),
+				Other:          newStudentResponse(d.Other),
+				EmailMatch:     d.EmailMatch,
+				NameSimilarity: d.NameSimilarity,
+			}
+		}
+
+		response.WriteJson(w, r, http.StatusOK, res)
+
+		return nil
+	})
+}
+
+// Merge folds student {otherId} into {id} and answers the merged student.
+func Merge(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := handlers.PathId(r)
+		if err != nil {
+			return err
+		}
+
+		otherId, err := strconv.ParseInt(r.PathValue("otherId"), 10, 64)
+		if err != nil {
+			return handlers.Errorf(http.StatusBadRequest, "invalid id format")
+		}
+
+		if id == otherId {
+			return handlers.Errorf(http.StatusBadRequest, "cannot merge student %d with itself", id)
+		}
+
+		ctx, dryRun := dryRunContext(w, r)
+
+		var before StudentResponse
+		if dryRun {
+			// a missing student is reported by the merge below
+			student, _ := students.Get(ctx, id)
+			before = newStudentResponse(student)
+		}
+
+		merged, err := students.Merge(ctx, id, otherId)
+		if err != nil {
+			return err
+		}
+
+		after := newStudentResponse(merged)
+		if dryRun {
+			response.WriteJson(w, r, http.StatusOK, dryRunResult{DryRun: true, Message: "students would be merged", Before: &before, After: &after})
+			return nil
+		}
+
+		slog.Info("students merged", slog.Int64("id", id), slog.Int64("other_id", otherId))
+
+		response.WriteJson(w, r, http.StatusOK, after)
+
+		return nil
+	})
+}
@@ -0,0 +1,117 @@
+package student
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/events"
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	"github.com/cmanish049/students-api/internal/tenant"
+)
+
+const (
+	// heartbeat keeps idle connections from being closed by proxies
+	heartbeat = 15 * time.Second
+
+	// clientBuffer is how many events a slow client may lag behind before it is dropped
+	clientBuffer = 64
+)
+
+// Events streams student mutations as Server-Sent Events. Clients resume
+// after a reconnect with the Last-Event-ID header (or ?last_event_id=).
+// Streams end when stop is cancelled.
+func Events(stop context.Context, bus *events.Bus) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			return handlers.Errorf(http.StatusInternalServerError, "streaming not supported")
+		}
+
+		lastId := int64(-1)
+		if v := r.Header.Get("Last-Event-ID"); v != "" {
+			lastId, _ = strconv.ParseInt(v, 10, 64)
+		} else if v := r.URL.Query().Get("last_event_id"); v != "" {
+			lastId, _ = strconv.ParseInt(v, 10, 64)
+		}
+
+		backlog, ch, overflow, unsubscribe := subscribe(bus, tenant.From(r.Context()), lastId)
+		defer unsubscribe()
+
+		slog.Info("event stream opened", slog.Int64("last_event_id", lastId))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprint(w, "retry: 3000\n\n")
+		for _, e := range backlog {
+			writeEvent(w, e)
+		}
+		flusher.Flush()
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return nil
+			case <-stop.Done():
+				return nil
+			case <-overflow:
+				slog.Warn("event stream client too slow, closing")
+				return nil
+			case e := <-ch:
+				writeEvent(w, e)
+				flusher.Flush()
+			case <-ticker.C:
+				fmt.Fprint(w, ": ping\n\n")
+				flusher.Flush()
+			}
+		}
+	})
+}
+
+// subscribe queues the events of tenantId for one streaming client.
+// overflow is closed once the client falls clientBuffer events behind;
+// publishers never block.
+func subscribe(bus *events.Bus, tenantId string, lastId int64) ([]events.Event, <-chan events.Event, <-chan struct{}, func()) {
+	ch := make(chan events.Event, clientBuffer)
+	overflow := make(chan struct{})
+
+	backlog, unsubscribe := bus.SubscribeSince(lastId, func(e events.Event) {
+		if e.Tenant != tenantId {
+			return
+		}
+
+		select {
+		case ch <- e:
+		default:
+			select {
+			case <-overflow:
+			default:
+				close(overflow)
+			}
+		}
+	})
+
+	backlog = slices.DeleteFunc(backlog, func(e events.Event) bool { return e.Tenant != tenantId })
+
+	return backlog, ch, overflow, unsubscribe
+}
+
+func writeEvent(w http.ResponseWriter, e events.Event) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Id, e.Type, data)
+}
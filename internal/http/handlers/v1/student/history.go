@@ -0,0 +1,151 @@
+package student
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// VersionResponse is a version of a student with its changes from an
+// earlier one.
+type VersionResponse struct {
+	Version   int              `json:"version"`
+	Name      string           `json:"name"`
+	Email     string           `json:"email"`
+	Age       int              `json:"age"`
+	CreatedAt time.Time        `json:"created_at"`
+	Changes   []ChangeResponse `json:"changes"`
+}
+
+// ChangeResponse is a field that differs between two versions.
+type ChangeResponse struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+func newVersionResponse(v studentsvc.Version) VersionResponse {
+	changes := make([]ChangeResponse, len(v.Changes))
+	for i, c := range v.Changes {
+		changes[i] = ChangeResponse{Field: c.Field, From: c.From, To: c.To}
+	}
+
+	return VersionResponse{
+		Version:   v.Version,
+		Name:      v.Name,
+		Email:     v.Email,
+		Age:       v.Age,
+		CreatedAt: v.CreatedAt,
+		Changes:   changes,
+	}
+}
+
+// GetHistory lists the versions of student {id}, oldest first, each with
+// its changes from the one before.
+func GetHistory(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := handlers.PathId(r)
+		if err != nil {
+			return err
+		}
+
+		versions, err := students.History(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		res := make([]VersionResponse, len(versions))
+		for i, v := range versions {
+			res[i] = newVersionResponse(v)
+		}
+
+		response.WriteJson(w, r, http.StatusOK, res)
+
+		return nil
+	})
+}
+
+// GetVersion answers version {version} of student {id} with its changes
+// from version ?compare=, by default the one before it.
+func GetVersion(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, version, err := versionPath(r)
+		if err != nil {
+			return err
+		}
+
+		var compare int
+		if v := r.URL.Query().Get("compare"); v != "" {
+			compare, err = strconv.Atoi(v)
+			if err != nil || compare < 1 {
+				return handlers.Errorf(http.StatusBadRequest, "invalid compare %q, use a version number", v)
+			}
+		}
+
+		v, err := students.Version(r.Context(), id, version, compare)
+		if err != nil {
+			return err
+		}
+
+		response.WriteJson(w, r, http.StatusOK, newVersionResponse(v))
+
+		return nil
+	})
+}
+
+// RestoreVersion updates student {id} back to version {version} and
+// answers the student.
+func RestoreVersion(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, version, err := versionPath(r)
+		if err != nil {
+			return err
+		}
+
+		ctx, dryRun := dryRunContext(w, r)
+
+		var before StudentResponse
+		if dryRun {
+			// a missing student is reported by the restore below
+			student, _ := students.Get(ctx, id)
+			before = newStudentResponse(student)
+		}
+
+		restored, err := students.Restore(ctx, id, version)
+		if err != nil {
+			return err
+		}
+
+		after := newStudentResponse(restored)
+		if dryRun {
+			response.WriteJson(w, r, http.StatusOK, dryRunResult{DryRun: true, Message: "student would be restored", Before: &before, After: &after})
+			return nil
+		}
+
+		slog.Info("student restored", slog.Int64("id", id), slog.Int("version", version))
+
+		response.WriteJson(w, r, http.StatusOK, after)
+
+		return nil
+	})
+}
+
+// versionPath parses the {id} and {version} path values.
+func versionPath(r *http.Request) (int64, int, error) {
+	id, err := handlers.PathId(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil || version < 1 {
+		return 0, 0, handlers.Errorf(http.StatusBadRequest, "invalid version format")
+	}
+
+	return id, version, nil
+}
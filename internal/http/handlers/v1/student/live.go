@@ -0,0 +1,182 @@
+package student
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/events"
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/utils/response"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// pongWait is how long a silent client is kept before it counts as gone
+	pongWait = 60 * time.Second
+
+	// writeWait bounds a single write to a client
+	writeWait = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// filter selects the events a live client receives. Empty lists match everything.
+type filter struct {
+	Events []events.Type `json:"events"`
+	Ids    []int64       `json:"ids"`
+}
+
+func (f filter) match(e events.Event) bool {
+	if len(f.Events) > 0 && !slices.Contains(f.Events, e.Type) {
+		return false
+	}
+
+	if len(f.Ids) > 0 {
+		id, ok := e.StudentId()
+		if !ok || !slices.Contains(f.Ids, id) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f filter) validate() error {
+	for _, t := range f.Events {
+		if !slices.Contains(events.Types, t) {
+			return fmt.Errorf("unknown event type %q", t)
+		}
+	}
+
+	return nil
+}
+
+// Live upgrades to a WebSocket and pushes student mutations as JSON events.
+// The initial filter comes from ?events= and ?ids= (comma separated); the
+// client replaces it at any time by sending {"events":[...],"ids":[...]}.
+// ?last_event_id= replays retained events missed since a previous session.
+// Connections end when stop is cancelled.
+func Live(stop context.Context, bus *events.Bus) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		q := r.URL.Query()
+
+		var f filter
+		if v := q.Get("events"); v != "" {
+			for _, t := range strings.Split(v, ",") {
+				f.Events = append(f.Events, events.Type(strings.TrimSpace(t)))
+			}
+		}
+		if v := q.Get("ids"); v != "" {
+			for _, s := range strings.Split(v, ",") {
+				id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+				if err != nil {
+					return handlers.Errorf(http.StatusBadRequest, "invalid id %q", s)
+				}
+				f.Ids = append(f.Ids, id)
+			}
+		}
+		if err := f.validate(); err != nil {
+			return handlers.WithStatus(http.StatusBadRequest, err)
+		}
+
+		lastId := int64(-1)
+		if v := q.Get("last_event_id"); v != "" {
+			lastId, _ = strconv.ParseInt(v, 10, 64)
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// the upgrader has already replied
+			slog.Warn("websocket upgrade failed", slog.String("error", err.Error()))
+			return nil
+		}
+		defer conn.Close()
+
+		backlog, ch, overflow, unsubscribe := subscribe(bus, tenant.From(r.Context()), lastId)
+		defer unsubscribe()
+
+		slog.Info("live client connected", slog.String("remote", r.RemoteAddr))
+
+		// the reader goroutine owns reads; all writes happen below
+		filters := make(chan filter)
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+
+			conn.SetReadLimit(4096)
+			conn.SetReadDeadline(time.Now().Add(pongWait))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(pongWait))
+			})
+
+			for {
+				var nf filter
+				if err := conn.ReadJSON(&nf); err != nil {
+					return
+				}
+				select {
+				case filters <- nf:
+				case <-stop.Done():
+					return
+				}
+			}
+		}()
+
+		send := func(v any) bool {
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			return conn.WriteJSON(v) == nil
+		}
+
+		for _, e := range backlog {
+			if f.match(e) && !send(e) {
+				return nil
+			}
+		}
+
+		ticker := time.NewTicker(pongWait * 9 / 10)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-closed:
+				return nil
+			case <-stop.Done():
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(writeWait))
+				return nil
+			case <-overflow:
+				slog.Warn("live client too slow, closing")
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"), time.Now().Add(writeWait))
+				return nil
+			case nf := <-filters:
+				if err := nf.validate(); err != nil {
+					if !send(response.Problem{Type: response.ProblemValidation, Title: http.StatusText(http.StatusBadRequest), Status: http.StatusBadRequest, Detail: err.Error()}) {
+						return nil
+					}
+					continue
+				}
+				f = nf
+				if !send(map[string]any{"type": "subscribed", "filter": f}) {
+					return nil
+				}
+			case e := <-ch:
+				if f.match(e) && !send(e) {
+					return nil
+				}
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+					return nil
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package student
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/types"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// defaultBucketSize is the width of the age ranges when none is given
+const defaultBucketSize = 10
+
+// ReportResponse is a student report: the aggregates of each group.
+type ReportResponse struct {
+	GroupBy    string                `json:"group_by"`
+	BucketSize int                   `json:"bucket_size,omitempty"`
+	Total      int                   `json:"total"`
+	Groups     []ReportGroupResponse `json:"groups"`
+}
+
+// ReportGroupResponse is one group of a report.
+type ReportGroupResponse struct {
+	Group      string  `json:"group"`
+	Count      int     `json:"count"`
+	Verified   int     `json:"verified"`
+	AverageAge float64 `json:"average_age"`
+	MinAge     int     `json:"min_age"`
+	MaxAge     int     `json:"max_age"`
+}
+
+// GetReport groups the students passing the list filters by ?group_by=
+// (age_bucket, in ranges of ?bucket_size= years, or status) and answers
+// the aggregates of each group, or with ?format=csv a CSV download of them.
+func GetReport(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		query, err := reportQuery(r)
+		if err != nil {
+			return err
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "csv" {
+			return handlers.Errorf(http.StatusBadRequest, "unknown format %q, use json or csv", format)
+		}
+
+		groups, err := students.Report(r.Context(), query)
+		if err != nil {
+			return err
+		}
+
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="students-by-%s.csv"`, query.GroupBy))
+			w.WriteHeader(http.StatusOK)
+
+			return writeReportCSV(w, groups)
+		}
+
+		res := ReportResponse{GroupBy: query.GroupBy, Groups: make([]ReportGroupResponse, len(groups))}
+		if query.GroupBy == storage.ReportByAgeBucket {
+			res.BucketSize = query.BucketSize
+		}
+		for i, g := range groups {
+			res.Total += g.Count
+			res.Groups[i] = ReportGroupResponse(g)
+		}
+
+		response.WriteJson(w, r, http.StatusOK, res)
+
+		return nil
+	})
+}
+
+// reportQuery reads ?group_by=, ?bucket_size= and the list filters.
+func reportQuery(r *http.Request) (storage.ReportQuery, error) {
+	filter, err := listFilter(r)
+	if err != nil {
+		return storage.ReportQuery{}, err
+	}
+
+	query := storage.ReportQuery{GroupBy: r.URL.Query().Get("group_by"), BucketSize: defaultBucketSize, Filter: filter}
+	switch query.GroupBy {
+	case storage.ReportByAgeBucket, storage.ReportByStatus:
+	case "":
+		return storage.ReportQuery{}, handlers.Errorf(http.StatusBadRequest, "group_by is required")
+	default:
+		return storage.ReportQuery{}, handlers.Errorf(http.StatusBadRequest, "unknown group_by %q, use age_bucket or status", query.GroupBy)
+	}
+
+	if v := r.URL.Query().Get("bucket_size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 1 || size > 100 {
+			return storage.ReportQuery{}, handlers.Errorf(http.StatusBadRequest, "bucket_size must be between 1 and 100")
+		}
+		query.BucketSize = size
+	}
+
+	return query, nil
+}
+
+// writeReportCSV writes groups as CSV with a header row.
+func writeReportCSV(w http.ResponseWriter, groups []types.ReportGroup) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"group", "count", "verified", "average_age", "min_age", "max_age"})
+	for _, g := range groups {
+		cw.Write([]string{g.Group, strconv.Itoa(g.Count), strconv.Itoa(g.Verified), strconv.FormatFloat(g.AverageAge, 'f', 2, 64), strconv.Itoa(g.MinAge), strconv.Itoa(g.MaxAge)})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
@@ -0,0 +1,86 @@
+package student
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// the default and the largest number of matches answered at once
+const (
+	searchLimit    = 20
+	maxSearchLimit = 100
+)
+
+// MatchResponse is a student found by a search.
+type MatchResponse struct {
+	Student StudentResponse `json:"student"`
+	Score   float64         `json:"score"`
+}
+
+// Search answers the students whose names match ?q= at least ?min_score=
+// (0 to 1), typos tolerated, best match first, the archived ones too with
+// ?include_archived=true. ?limit= and ?offset= page through the matches.
+func Search(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		query := r.URL.Query()
+
+		q := strings.TrimSpace(query.Get("q"))
+		if q == "" {
+			return handlers.Errorf(http.StatusBadRequest, "q is required")
+		}
+
+		minScore := studentsvc.DefaultMinScore
+		if v := query.Get("min_score"); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 || f > 1 {
+				return handlers.Errorf(http.StatusBadRequest, "min_score must be between 0 and 1")
+			}
+			minScore = f
+		}
+
+		limit := searchLimit
+		if v := query.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxSearchLimit {
+				return handlers.Errorf(http.StatusBadRequest, "limit must be between 1 and %d", maxSearchLimit)
+			}
+			limit = n
+		}
+
+		var offset int
+		if v := query.Get("offset"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return handlers.Errorf(http.StatusBadRequest, "offset must not be negative")
+			}
+			offset = n
+		}
+
+		include, err := includeArchived(r)
+		if err != nil {
+			return err
+		}
+
+		matches, err := students.Search(r.Context(), q, minScore, include)
+		if err != nil {
+			return err
+		}
+
+		total := len(matches)
+		page := matches[min(offset, total):min(offset+limit, total)]
+
+		res := make([]MatchResponse, len(page))
+		for i, m := range page {
+			res[i] = MatchResponse{Student: newStudentResponse(m.Student), Score: m.Score}
+		}
+
+		response.WriteJson(w, r, http.StatusOK, response.Page{Data: res, Pagination: response.Pagination{Limit: limit, Offset: offset, Total: &total}})
+
+		return nil
+	})
+}
@@ -0,0 +1,268 @@
+package student
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/grpc/studentpb"
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// dryRunResult reports what a dry-run mutation would have done.
+type dryRunResult struct {
+	DryRun  bool             `json:"dry_run"`
+	Message string           `json:"message"`
+	Before  *StudentResponse `json:"before,omitempty"`
+	After   *StudentResponse `json:"after,omitempty"`
+}
+
+// dryRunContext returns the context to mutate with, marked as a dry run if
+// the request asks for one.
+func dryRunContext(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	if !dryrun.Requested(r) {
+		return r.Context(), false
+	}
+
+	w.Header().Set(dryrun.Header, "true")
+
+	return dryrun.With(r.Context()), true
+}
+
+func New(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		slog.Info("create a student")
+
+		var req CreateStudentRequest
+		if err := handlers.DecodeJSON(r, &req); err != nil {
+			return err
+		}
+
+		ctx, dryRun := dryRunContext(w, r)
+
+		student := req.student()
+		studentId, err := students.Create(ctx, student)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			now := time.Now().UTC()
+			student.Id, student.CreatedAt, student.UpdatedAt = int(studentId), now, now
+			after := newStudentResponse(student)
+			response.WriteJson(w, r, http.StatusOK, dryRunResult{DryRun: true, Message: "student would be created", After: &after})
+			return nil
+		}
+
+		slog.Info("student created", slog.Int64("id", studentId))
+
+		response.WriteJson(w, r, http.StatusCreated, map[string]int64{"id": studentId})
+
+		return nil
+	})
+}
+
+func GetById(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := handlers.PathId(r)
+		if err != nil {
+			return err
+		}
+
+		include, err := includeArchived(r)
+		if err != nil {
+			return err
+		}
+
+		student, err := students.Get(r.Context(), id)
+		if include && errors.Is(err, studentsvc.ErrNotFound) {
+			student, err = students.GetArchived(r.Context(), id)
+		}
+		if err != nil {
+			return err
+		}
+
+		response.Write(w, r, http.StatusOK, newStudentResponse(student), studentpb.FromStudent(student))
+
+		return nil
+	})
+}
+
+func GetStudentList(service *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		slog.Info("get student list")
+
+		filter, err := listFilter(r)
+		if err != nil {
+			return err
+		}
+
+		students, err := service.List(r.Context(), filter)
+		if err != nil {
+			return err
+		}
+
+		res := newStudentResponses(students)
+
+		// dashboards poll the list; let them revalidate instead of
+		// downloading it again. Only by its contents: no time of the last
+		// change is known for deletions made elsewhere, such as by another
+		// instance or the CLI, so there is no Last-Modified
+		etag, err := response.ETag(res, response.Negotiate(r.Header.Get("Accept"), true))
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Cache-Control", "private, no-cache")
+		response.Vary(w, "Accept", "Authorization", tenant.Header)
+		if response.NotModified(w, r, etag, time.Time{}) {
+			return nil
+		}
+
+		response.Write(w, r, http.StatusOK, res, studentpb.FromStudents(students))
+
+		return nil
+	})
+}
+
+// listFilter reads the ?created_after= and ?created_before= bounds, each an
+// RFC 3339 time or a date (midnight UTC), ?email_verified=true|false and
+// ?include_archived=true|false.
+func listFilter(r *http.Request) (storage.StudentFilter, error) {
+	var filter storage.StudentFilter
+
+	bounds := []struct {
+		name string
+		t    *time.Time
+	}{{"created_after", &filter.CreatedAfter}, {"created_before", &filter.CreatedBefore}}
+
+	for _, bound := range bounds {
+		name := bound.name
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t, err = time.Parse(time.DateOnly, value)
+		}
+		if err != nil {
+			return storage.StudentFilter{}, handlers.Errorf(http.StatusBadRequest, "invalid %s %q, use an RFC 3339 time or a date", name, value)
+		}
+		*bound.t = t
+	}
+
+	if value := r.URL.Query().Get("email_verified"); value != "" {
+		verified, err := strconv.ParseBool(value)
+		if err != nil {
+			return storage.StudentFilter{}, handlers.Errorf(http.StatusBadRequest, "invalid email_verified %q, use true or false", value)
+		}
+		filter.EmailVerified = &verified
+	}
+
+	include, err := includeArchived(r)
+	if err != nil {
+		return storage.StudentFilter{}, err
+	}
+	filter.IncludeArchived = include
+
+	return filter, nil
+}
+
+// includeArchived reads ?include_archived=true|false, false if absent.
+func includeArchived(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("include_archived")
+	if value == "" {
+		return false, nil
+	}
+
+	include, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, handlers.Errorf(http.StatusBadRequest, "invalid include_archived %q, use true or false", value)
+	}
+
+	return include, nil
+}
+
+func UpdateStudent(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := handlers.PathId(r)
+		if err != nil {
+			return err
+		}
+
+		var req UpdateStudentRequest
+		if err := handlers.DecodeJSON(r, &req); err != nil {
+			return err
+		}
+
+		ctx, dryRun := dryRunContext(w, r)
+
+		student := req.student()
+
+		var before types.Student
+		if dryRun {
+			// a missing student is reported by the update below
+			before, _ = students.Get(ctx, id)
+		}
+
+		if err := students.Update(ctx, id, student); err != nil {
+			return err
+		}
+
+		if dryRun {
+			student.Id, student.CreatedAt, student.UpdatedAt = int(id), before.CreatedAt, time.Now().UTC()
+			was, after := newStudentResponse(before), newStudentResponse(student)
+			response.WriteJson(w, r, http.StatusOK, dryRunResult{DryRun: true, Message: "student would be updated", Before: &was, After: &after})
+			return nil
+		}
+
+		slog.Info("student updated", slog.Int64("id", id))
+
+		response.WriteJson(w, r, http.StatusOK, map[string]string{"message": "student updated successfully"})
+
+		return nil
+	})
+}
+
+func DeleteStudent(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := handlers.PathId(r)
+		if err != nil {
+			return err
+		}
+
+		ctx, dryRun := dryRunContext(w, r)
+
+		var before types.Student
+		if dryRun {
+			before, _ = students.Get(ctx, id)
+		}
+
+		if err := students.Delete(ctx, id); err != nil {
+			return err
+		}
+
+		if dryRun {
+			was := newStudentResponse(before)
+			response.WriteJson(w, r, http.StatusOK, dryRunResult{DryRun: true, Message: "student would be deleted", Before: &was})
+			return nil
+		}
+
+		slog.Info("student deleted", slog.Int64("id", id))
+
+		response.WriteJson(w, r, http.StatusOK, map[string]string{"message": "student deleted successfully"})
+
+		return nil
+	})
+}
@@ -0,0 +1,90 @@
+package student_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	studentv1 "github.com/cmanish049/students-api/internal/http/handlers/v1/student"
+	"github.com/cmanish049/students-api/pkg/apitest"
+	"github.com/cmanish049/students-api/pkg/storagetest"
+)
+
+func TestStudents(t *testing.T) {
+	store := storagetest.NewMemory()
+	h := apitest.Handler(store, nil)
+
+	var created struct {
+		Id int64 `json:"id"`
+	}
+	apitest.Do(t, h, http.MethodPost, "/api/v1/students", map[string]any{"name": "Ann Lee", "email": "ann@example.com", "age": 21}).
+		ExpectStatus(http.StatusCreated).JSON(&created)
+
+	var student studentv1.StudentResponse
+	apitest.Do(t, h, http.MethodGet, "/api/v1/students/1", nil).ExpectStatus(http.StatusOK).JSON(&student)
+	if student.Id != int(created.Id) || student.Name != "Ann Lee" || student.Email != "ann@example.com" || student.Age != 21 || student.CreatedAt.IsZero() {
+		t.Errorf("student = %+v", student)
+	}
+
+	if err := apitest.Do(t, h, http.MethodPost, "/api/v1/students", map[string]any{"name": "Ann Again", "email": "ann@example.com", "age": 21}).
+		ExpectStatus(http.StatusConflict).Error(); err.Code != "email_taken" {
+		t.Errorf("error code = %q, want email_taken", err.Code)
+	}
+
+	err := apitest.Do(t, h, http.MethodPost, "/api/v1/students", map[string]any{"name": "Bob", "email": "bob@example.com"}).
+		ExpectStatus(http.StatusBadRequest).Error()
+	// without the middleware validating requests, fields have their Go names
+	if len(err.Fields) != 1 || !strings.EqualFold(err.Fields[0].Field, "age") {
+		t.Errorf("invalid fields = %+v, want age", err.Fields)
+	}
+
+	apitest.Do(t, h, http.MethodDelete, "/api/v1/students/1", nil).ExpectStatus(http.StatusOK)
+	apitest.Do(t, h, http.MethodGet, "/api/v1/students/1", nil).ExpectStatus(http.StatusNotFound)
+
+	var list []studentv1.StudentResponse
+	apitest.Do(t, h, http.MethodGet, "/api/v1/students", nil).ExpectStatus(http.StatusOK).JSON(&list)
+	if len(list) != 0 {
+		t.Errorf("students after deleting = %+v", list)
+	}
+}
+
+func TestListRevalidation(t *testing.T) {
+	store := storagetest.NewMemory()
+	storagetest.SeedN(t, store, 2)
+	h := apitest.Handler(store, nil)
+
+	res := apitest.Do(t, h, http.MethodGet, "/api/v1/students", nil).ExpectStatus(http.StatusOK)
+	etag := res.Header.Get("ETag")
+	if etag == "" || res.Header.Get("Last-Modified") != "" {
+		t.Fatalf("validators = ETag %q, Last-Modified %q; want only an ETag", etag, res.Header.Get("Last-Modified"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/students", nil)
+	req.Header.Set("If-None-Match", etag)
+	apitest.Serve(t, h, req).ExpectStatus(http.StatusNotModified)
+
+	// deleted elsewhere, as by the CLI, without this handler's knowledge
+	if err := store.DeleteStudent(context.Background(), 1); err != nil {
+		t.Fatalf("DeleteStudent: %v", err)
+	}
+
+	apitest.Serve(t, h, req).ExpectStatus(http.StatusOK)
+
+	since := httptest.NewRequest(http.MethodGet, "/api/v1/students", nil)
+	since.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	apitest.Serve(t, h, since).ExpectStatus(http.StatusOK)
+}
+
+func TestStorageFailure(t *testing.T) {
+	store := storagetest.NewMemory()
+	store.FailWith("GetStudentList", errors.New("disk on fire"))
+
+	apitest.Do(t, apitest.Handler(store, nil), http.MethodGet, "/api/v1/students", nil).ExpectStatus(http.StatusInternalServerError)
+	if n := store.Calls("GetStudentList"); n != 1 {
+		t.Errorf("GetStudentList called %d times, want 1", n)
+	}
+}
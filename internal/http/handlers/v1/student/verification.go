@@ -0,0 +1,81 @@
+package student
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// VerifyEmailRequest is the body of an email verification.
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// VerifyEmail verifies the email of the student the token was sent to and
+// answers the student.
+func VerifyEmail(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		var req VerifyEmailRequest
+		if err := handlers.DecodeJSON(r, &req); err != nil {
+			return err
+		}
+
+		if req.Token == "" {
+			return handlers.Errorf(http.StatusBadRequest, "token is required")
+		}
+
+		ctx, dryRun := dryRunContext(w, r)
+
+		student, err := students.Verify(ctx, req.Token)
+		if err != nil {
+			return err
+		}
+
+		after := newStudentResponse(student)
+		if dryRun {
+			response.WriteJson(w, r, http.StatusOK, dryRunResult{DryRun: true, Message: "email would be verified", After: &after})
+			return nil
+		}
+
+		slog.Info("student email verified", slog.Int("id", student.Id))
+
+		response.WriteJson(w, r, http.StatusOK, after)
+
+		return nil
+	})
+}
+
+// SendVerification sends student {id} a new verification token.
+func SendVerification(students *studentsvc.Service) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := handlers.PathId(r)
+		if err != nil {
+			return err
+		}
+
+		ctx, dryRun := dryRunContext(w, r)
+
+		err = students.SendVerification(ctx, id)
+		if errors.Is(err, studentsvc.ErrVerificationDisabled) {
+			return handlers.Errorf(http.StatusConflict, "email verification is not enabled")
+		}
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			response.WriteJson(w, r, http.StatusOK, dryRunResult{DryRun: true, Message: "verification would be sent"})
+			return nil
+		}
+
+		slog.Info("student email verification sent", slog.Int64("id", id))
+
+		response.WriteJson(w, r, http.StatusAccepted, map[string]string{"message": "verification sent"})
+
+		return nil
+	})
+}
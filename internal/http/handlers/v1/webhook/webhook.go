@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/types"
+	"github.com/cmanish049/students-api/internal/utils/response"
+	"github.com/cmanish049/students-api/internal/validation"
+	"github.com/cmanish049/students-api/internal/webhooks"
+)
+
+// deliveryLimit is the number of most recent deliveries returned
+const deliveryLimit = 100
+
+// New registers a webhook, at a URL guard allows.
+func New(storage storage.WebhookStorage, guard *webhooks.Guard, validate *validation.Validator) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		slog.Info("register a webhook")
+
+		var webhook types.Webhook
+		if err := handlers.DecodeJSON(r, &webhook); err != nil {
+			return err
+		}
+
+		// request validation
+		if err := validate.Struct(webhook); err != nil {
+			return handlers.WithStatus(http.StatusBadRequest, err)
+		}
+
+		if err := guard.CheckURL(r.Context(), webhook.Url); err != nil {
+			errs := &handlers.InvalidFields{}
+			switch {
+			case errors.Is(err, webhooks.ErrHostNotAllowed):
+				errs.Add("url", "webhook_host", "field %s is not an allowed webhook host", "url")
+			case errors.Is(err, webhooks.ErrAddressBlocked):
+				errs.Add("url", "webhook_address", "field %s must not be a loopback, private, link-local or unspecified address", "url")
+			default:
+				errs.Add("url", "url", "field %s must be an http or https URL", "url")
+			}
+			return handlers.WithStatus(http.StatusBadRequest, errs)
+		}
+
+		// without a secret of their own integrators get a generated one, returned only here
+		if webhook.Secret == "" {
+			buf := make([]byte, 32)
+			rand.Read(buf)
+			webhook.Secret = hex.EncodeToString(buf)
+		}
+
+		webhookId, err := storage.CreateWebhook(r.Context(), webhook.Url, webhook.Secret, webhook.Events)
+		if err != nil {
+			return err
+		}
+
+		slog.Info("webhook registered", slog.Int64("id", webhookId))
+
+		response.WriteJson(w, r, http.StatusCreated, map[string]any{"id": webhookId, "secret": webhook.Secret})
+
+		return nil
+	})
+}
+
+func GetWebhookList(storage storage.WebhookStorage) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		webhooks, err := storage.GetWebhookList(r.Context())
+		if err != nil {
+			return err
+		}
+
+		// secrets are never returned after registration
+		for i := range webhooks {
+			webhooks[i].Secret = ""
+		}
+
+		response.WriteJson(w, r, http.StatusOK, webhooks)
+
+		return nil
+	})
+}
+
+func DeleteWebhook(storage storage.WebhookStorage) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := handlers.PathId(r)
+		if err != nil {
+			return err
+		}
+
+		if err := storage.DeleteWebhook(r.Context(), id); err != nil {
+			return err
+		}
+
+		slog.Info("webhook deleted", slog.Int64("id", id))
+
+		response.WriteJson(w, r, http.StatusOK, map[string]string{"message": "webhook deleted successfully"})
+
+		return nil
+	})
+}
+
+func GetDeliveries(storage storage.WebhookStorage) http.HandlerFunc {
+	return handlers.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := handlers.PathId(r)
+		if err != nil {
+			return err
+		}
+
+		deliveries, err := storage.GetWebhookDeliveries(r.Context(), id, deliveryLimit)
+		if err != nil {
+			return err
+		}
+
+		response.WriteJson(w, r, http.StatusOK, deliveries)
+
+		return nil
+	})
+}
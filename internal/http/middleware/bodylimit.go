@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// BodyLimit caps the size of request bodies. Reading past the limit fails
+// with a *http.MaxBytesError, which handlers.DecodeJSON answers with 413,
+// so the body is never buffered whole before it is rejected.
+type BodyLimit struct {
+	n atomic.Int64
+}
+
+// NewBodyLimit returns a BodyLimit of n bytes.
+func NewBodyLimit(n int64) *BodyLimit {
+	l := &BodyLimit{}
+	l.Set(n)
+	return l
+}
+
+// Set changes the limit for requests that start from now on.
+func (l *BodyLimit) Set(n int64) {
+	l.n.Store(n)
+}
+
+func (l *BodyLimit) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, l.n.Load())
+
+		next.ServeHTTP(w, r)
+	})
+}
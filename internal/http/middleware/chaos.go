@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/chaos"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// Chaos injects latency, 5xx errors and dropped connections according to
+// the injector's rules while it is enabled. Injected faults are marked with
+// an X-Chaos header (except drops, which have no response).
+func Chaos(next http.Handler, injector *chaos.Injector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !injector.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rule, ok := injector.Match(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if roll(rule.LatencyRate) {
+			w.Header().Add("X-Chaos", "latency")
+
+			select {
+			case <-time.After(rule.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if roll(rule.DropRate) {
+			slog.Debug("chaos: dropping connection", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+			// closes the connection without a response
+			panic(http.ErrAbortHandler)
+		}
+
+		if roll(rule.ErrorRate) {
+			status := rule.ErrorStatus
+			if status == 0 {
+				status = http.StatusServiceUnavailable
+			}
+
+			w.Header().Add("X-Chaos", "error")
+			writeError(w, r, status, response.CodeFaultInjected, "injected fault")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func roll(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
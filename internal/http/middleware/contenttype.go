@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// ContentType rejects POST, PUT and PATCH bodies whose media type is not one
+// of allowed with 415, instead of letting handlers try to decode them.
+func ContentType(next http.Handler, allowed ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !takesBody(r.Method) || r.ContentLength == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || !slices.Contains(allowed, mediaType) {
+			writeError(w, r, http.StatusUnsupportedMediaType, response.CodeUnsupportedMediaType, "unsupported content type %q, expected %s", r.Header.Get("Content-Type"), strings.Join(allowed, " or "))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func takesBody(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/i18n"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// writeError answers with an error with code whose message is the
+// formatted one, translated into the language of the request.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code response.Code, format string, args ...any) {
+	lang := i18n.FromRequest(r)
+	w.Header().Set("Content-Language", lang)
+
+	p := response.NewProblem(r, status, i18n.Sprintf(lang, format, args...))
+	p.Title = i18n.Text(lang, p.Title)
+	p.Code = code
+
+	response.WriteProblem(w, r, p)
+}
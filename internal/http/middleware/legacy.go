@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Legacy serves requests made to an old, unversioned path prefix by
+// rewriting them onto the new prefix and dispatching them to next.
+// Responses are marked as deprecated so clients know to migrate.
+func Legacy(next http.Handler, oldPrefix, newPrefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, oldPrefix)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = newPrefix + rest
+		r2.URL.RawPath = ""
+		r2.RequestURI = r2.URL.RequestURI()
+
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+r2.URL.Path+`>; rel="successor-version"`)
+
+		next.ServeHTTP(w, r2)
+	})
+}
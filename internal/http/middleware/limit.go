@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// Limiter caps the number of requests served at once. Up to maxQueue extra
+// requests wait (in arrival order) at most wait for a free slot; everything
+// beyond that is shed with 503 so a traffic spike can't pile up on the
+// database.
+type Limiter struct {
+	mu          sync.Mutex
+	maxInFlight int
+	maxQueue    int
+	wait        time.Duration
+	inFlight    int
+	queue       []chan struct{}
+}
+
+// NewLimiter returns a Limiter. A maxInFlight of zero disables the limit.
+func NewLimiter(maxInFlight, maxQueue int, wait time.Duration) *Limiter {
+	l := &Limiter{}
+	l.SetLimits(maxInFlight, maxQueue, wait)
+	return l
+}
+
+// SetLimits changes the limits; queued requests are admitted right away if
+// the new limit leaves room for them.
+func (l *Limiter) SetLimits(maxInFlight, maxQueue int, wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.maxInFlight = maxInFlight
+	l.maxQueue = maxQueue
+	l.wait = wait
+
+	for len(l.queue) > 0 && l.hasRoom() {
+		l.admitNext()
+	}
+}
+
+// admittedKey marks the context of a request holding a slot; the requests
+// of a batch are served in the slot of the batch rather than waiting for
+// one of their own, which could never come with every slot taken by
+// batches.
+type admittedKey struct{}
+
+func (l *Limiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Value(admittedKey{}) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if reason := l.acquire(r); reason != "" {
+			overloaded(w, r, reason)
+			return
+		}
+		defer l.release()
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), admittedKey{}, true)))
+	})
+}
+
+// acquire takes a slot, or returns why the request has to be shed.
+func (l *Limiter) acquire(r *http.Request) string {
+	l.mu.Lock()
+
+	if l.hasRoom() {
+		l.inFlight++
+		l.mu.Unlock()
+		return ""
+	}
+
+	if len(l.queue) >= l.maxQueue {
+		l.mu.Unlock()
+		return "queue full"
+	}
+
+	ready := make(chan struct{})
+	l.queue = append(l.queue, ready)
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+	l.mu.Unlock()
+
+	reason := ""
+	select {
+	case <-ready:
+		return ""
+	case <-timer.C:
+		reason = "queue timeout"
+	case <-r.Context().Done():
+		reason = "client gone"
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if i := slices.Index(l.queue, ready); i >= 0 {
+		l.queue = slices.Delete(l.queue, i, i+1)
+		return reason
+	}
+
+	// admitted while we were giving up, keep the slot
+	return ""
+}
+
+func (l *Limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+
+	if len(l.queue) > 0 && l.hasRoom() {
+		l.admitNext()
+	}
+}
+
+func (l *Limiter) hasRoom() bool {
+	return l.maxInFlight <= 0 || l.inFlight < l.maxInFlight
+}
+
+func (l *Limiter) admitNext() {
+	ready := l.queue[0]
+	l.queue = l.queue[1:]
+	l.inFlight++
+	close(ready)
+}
+
+func overloaded(w http.ResponseWriter, r *http.Request, reason string) {
+	slog.Warn("request shed", slog.String("method", r.Method), slog.String("path", r.URL.Path), slog.String("reason", reason))
+
+	w.Header().Set("Retry-After", "1")
+	writeError(w, r, http.StatusServiceUnavailable, response.CodeOverloaded, "server is overloaded, try again later")
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/maintenance"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// ReadOnly rejects mutating requests with 503 while maintenance mode is on.
+// Dry runs of the routes that honor them are let through since they don't
+// write anything (see dryrun.Allowed).
+func ReadOnly(next http.Handler, mode *maintenance.Mode) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mode.Enabled() && isMutating(r.Method) && !dryrun.Allowed(r) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(mode.RetryAfter().Seconds())))
+			writeError(w, r, http.StatusServiceUnavailable, response.CodeMaintenance, "service is in maintenance mode, try again later")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
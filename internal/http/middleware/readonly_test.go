@@ -0,0 +1,54 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/http/middleware"
+	"github.com/cmanish049/students-api/internal/maintenance"
+	"github.com/cmanish049/students-api/pkg/apitest"
+	"github.com/cmanish049/students-api/pkg/storagetest"
+)
+
+func TestReadOnlyDryRuns(t *testing.T) {
+	mode := maintenance.New(time.Minute)
+	mode.Set(true)
+
+	store := storagetest.NewMemory()
+	h := api(store, func(next http.Handler) http.Handler { return middleware.ReadOnly(next, mode) })
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		header string
+		code   int
+	}{
+		{"student create", http.MethodPost, "/api/v1/students", `{"name":"Ann Lee","email":"ann@example.com","age":21}`, "", http.StatusServiceUnavailable},
+		{"student dry run", http.MethodPost, "/api/v1/students", `{"name":"Ann Lee","email":"ann@example.com","age":21}`, "true", http.StatusOK},
+		{"webhook dry run", http.MethodPost, "/api/v1/webhooks", `{"url":"http://203.0.113.10/hook","events":["student.created"]}`, "true", http.StatusServiceUnavailable},
+		{"webhook delete dry run", http.MethodDelete, "/api/v1/webhooks/1", "", "true", http.StatusServiceUnavailable},
+		{"student list", http.MethodGet, "/api/v1/students", "", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.header != "" {
+				req.Header.Set(dryrun.Header, tt.header)
+			}
+
+			apitest.Serve(t, h, req).ExpectStatus(tt.code)
+		})
+	}
+
+	if n := webhooksOf(t, store); n != 0 {
+		t.Errorf("%d webhooks registered in maintenance mode", n)
+	}
+}
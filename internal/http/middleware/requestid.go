@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/requestid"
+)
+
+// RequestId gives every request an id, the client's X-Request-Id if it sent
+// a usable one, and echoes it on the response.
+func RequestId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestid.FromRequest(r)
+
+		w.Header().Set(requestid.Header, id)
+
+		next.ServeHTTP(w, r.WithContext(requestid.With(r.Context(), id)))
+	})
+}
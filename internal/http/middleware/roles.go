@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/auth"
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// Roles gives the requests authenticated by a session token their session
+// (see auth.From) and rejects with 403 the mutating requests of viewers,
+// dry runs of the routes that honor them excepted (see dryrun.Allowed). It
+// goes after Tenant, which rejects invalid tokens.
+func Roles(next http.Handler, sessions *auth.Sessions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, ok := sessions.Verify(tenant.BearerToken(r.Header.Get("Authorization")))
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if session.Role == auth.RoleViewer && isMutating(r.Method) && !dryrun.Allowed(r) {
+			writeError(w, r, http.StatusForbidden, response.CodeForbidden, "role %s can only read", session.Role)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.With(r.Context(), session)))
+	})
+}
@@ -0,0 +1,80 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/auth"
+	"github.com/cmanish049/students-api/internal/http/middleware"
+	"github.com/cmanish049/students-api/internal/http/router"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/validation"
+	"github.com/cmanish049/students-api/internal/webhooks"
+	"github.com/cmanish049/students-api/pkg/apitest"
+	"github.com/cmanish049/students-api/pkg/storagetest"
+)
+
+// api serves the student and webhook routes on store through mw.
+func api(store *storagetest.Memory, mw router.Middleware) http.Handler {
+	validate, _ := validation.New(validation.Rules{})
+
+	v1 := router.New().Group("/api/v1", mw)
+	router.Students(v1, studentsvc.New(store, nil, validate))
+	router.Webhooks(v1, store, webhooks.NewGuard(nil), validate)
+
+	return v1
+}
+
+// webhooksOf returns how many webhooks store has.
+func webhooksOf(t *testing.T, store *storagetest.Memory) int {
+	t.Helper()
+
+	list, err := store.GetWebhookList(context.Background())
+	if err != nil {
+		t.Fatalf("GetWebhookList: %v", err)
+	}
+
+	return len(list)
+}
+
+func TestRolesViewerDryRuns(t *testing.T) {
+	sessions := auth.NewSessions("secret", time.Hour)
+	_, viewer := sessions.Issue("ann", auth.RoleViewer, "")
+
+	store := storagetest.NewMemory()
+	h := api(store, func(next http.Handler) http.Handler { return middleware.Roles(next, sessions) })
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{"student create", http.MethodPost, "/api/v1/students", `{"name":"Ann Lee","email":"ann@example.com","age":21}`, http.StatusForbidden},
+		{"student dry run", http.MethodPost, "/api/v1/students?dry_run=true", `{"name":"Ann Lee","email":"ann@example.com","age":21}`, http.StatusOK},
+		{"webhook create", http.MethodPost, "/api/v1/webhooks", `{"url":"http://203.0.113.10/hook","events":["student.created"]}`, http.StatusForbidden},
+		// webhooks ignore dry runs, so these would really write
+		{"webhook dry run", http.MethodPost, "/api/v1/webhooks?dry_run=true", `{"url":"http://203.0.113.10/hook","events":["student.created"]}`, http.StatusForbidden},
+		{"webhook delete dry run", http.MethodDelete, "/api/v1/webhooks/1?dry_run=true", "", http.StatusForbidden},
+		{"student list", http.MethodGet, "/api/v1/students", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+viewer)
+
+			apitest.Serve(t, h, req).ExpectStatus(tt.code)
+		})
+	}
+
+	if n := webhooksOf(t, store); n != 0 {
+		t.Errorf("viewer registered %d webhooks", n)
+	}
+}
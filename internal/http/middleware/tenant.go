@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/utils/response"
+)
+
+// Tenant gives every request the tenant it acts for, see tenant.Resolver,
+// and rejects those whose tenant can't be resolved.
+func Tenant(next http.Handler, resolver *tenant.Resolver) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := resolver.ResolveRequest(r)
+
+		var tenantErr *tenant.Error
+		if errors.As(err, &tenantErr) {
+			if tenantErr.Status == http.StatusUnauthorized {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			format, args := tenantErr.Message()
+			writeError(w, r, tenantErr.Status, response.CodeOf(tenantErr.Status), format, args...)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(tenant.With(r.Context(), id)))
+	})
+}
+
+// AllTenants lets the requests of next read the data of every tenant, for
+// the admin listener.
+func AllTenants(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(tenant.All(r.Context())))
+	})
+}
@@ -0,0 +1,77 @@
+// Package router registers the API's routes in groups that share a path
+// prefix and a middleware stack, so a resource is added by registering its
+// routes on the group it belongs to.
+package router
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/dryrun"
+)
+
+// Middleware wraps a handler, like middleware.Timeout.Handler.
+type Middleware func(http.Handler) http.Handler
+
+// Router is a group of routes on a http.ServeMux. Groups of a router share
+// its mux, extending its prefix and middleware.
+type Router struct {
+	mux        *http.ServeMux
+	prefix     string
+	middleware []Middleware
+	// dryRuns is whether the handlers of the group honor dry runs
+	dryRuns bool
+}
+
+// New returns a router with no prefix or middleware.
+func New() *Router {
+	return &Router{mux: http.NewServeMux()}
+}
+
+// Group returns a group of routes under r's prefix followed by prefix,
+// served through r's middleware and then mw, outermost first.
+func (r *Router) Group(prefix string, mw ...Middleware) *Router {
+	return &Router{
+		mux:        r.mux,
+		prefix:     r.prefix + prefix,
+		middleware: append(slices.Clone(r.middleware), mw...),
+		dryRuns:    r.dryRuns,
+	}
+}
+
+// DryRuns returns the group of r's routes whose handlers honor dry runs, so
+// middleware refusing writes lets their dry runs through (see
+// dryrun.Allowed). Only routes checking dryrun.Requested belong to it.
+func (r *Router) DryRuns() *Router {
+	g := r.Group("")
+	g.dryRuns = true
+
+	return g
+}
+
+// Handle registers h for a ServeMux pattern whose path is relative to the
+// group: "GET /students/{id}" on the group "/api/v1" is served at
+// "GET /api/v1/students/{id}".
+func (r *Router) Handle(pattern string, h http.Handler) {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		method, path = "", pattern
+	}
+
+	for _, mw := range slices.Backward(r.middleware) {
+		h = mw(h)
+	}
+	h = dryrun.Route(h, r.dryRuns)
+
+	r.mux.Handle(strings.TrimSpace(method+" "+r.prefix+strings.TrimSpace(path)), h)
+}
+
+func (r *Router) HandleFunc(pattern string, h http.HandlerFunc) {
+	r.Handle(pattern, h)
+}
+
+// ServeHTTP dispatches requests to the routes of every group.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
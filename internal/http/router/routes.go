@@ -0,0 +1,191 @@
+package router
+
+import (
+	"context"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/cmanish049/students-api/internal/auth"
+	"github.com/cmanish049/students-api/internal/blob"
+	"github.com/cmanish049/students-api/internal/bulk"
+	"github.com/cmanish049/students-api/internal/chaos"
+	"github.com/cmanish049/students-api/internal/events"
+	"github.com/cmanish049/students-api/internal/http/handlers/admin"
+	"github.com/cmanish049/students-api/internal/http/handlers/docs"
+	authv1 "github.com/cmanish049/students-api/internal/http/handlers/v1/auth"
+	batchv1 "github.com/cmanish049/students-api/internal/http/handlers/v1/batch"
+	filev1 "github.com/cmanish049/students-api/internal/http/handlers/v1/file"
+	jobv1 "github.com/cmanish049/students-api/internal/http/handlers/v1/job"
+	studentv1 "github.com/cmanish049/students-api/internal/http/handlers/v1/student"
+	webhookv1 "github.com/cmanish049/students-api/internal/http/handlers/v1/webhook"
+	"github.com/cmanish049/students-api/internal/http/middleware"
+	"github.com/cmanish049/students-api/internal/jobs"
+	"github.com/cmanish049/students-api/internal/maintenance"
+	"github.com/cmanish049/students-api/internal/replica"
+	"github.com/cmanish049/students-api/internal/schedule"
+	filesvc "github.com/cmanish049/students-api/internal/service/file"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/sms"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/storage/tenantdb"
+	"github.com/cmanish049/students-api/internal/utils/response"
+	"github.com/cmanish049/students-api/internal/validation"
+	"github.com/cmanish049/students-api/internal/webhooks"
+)
+
+// Deps are what the routes are served from.
+type Deps struct {
+	Students *studentsvc.Service
+	Bulk     *bulk.Bulk
+	Jobs     *jobs.Queue
+	Webhooks storage.WebhookStorage
+	// Guard checks the URLs webhooks are registered at
+	Guard    *webhooks.Guard
+	Validate *validation.Validator
+	Bus      *events.Bus
+	// Streams ends the event streams when it is done
+	Streams context.Context
+
+	Mode      *maintenance.Mode
+	Injector  *chaos.Injector
+	Scheduler *schedule.Scheduler
+	// Sms is nil if texts aren't enabled
+	Sms       *sms.Notifier
+	Databases *tenantdb.Manager
+	// Directory is nil if directory logins aren't enabled
+	Directory *auth.Directory
+	// Files is nil if uploads aren't enabled, and LocalBlobs unless their
+	// contents are on the local disk
+	Files      *filesvc.Service
+	LocalBlobs *blob.Local
+	// Replicator is nil if replication isn't enabled
+	Replicator *replica.Replicator
+}
+
+// Public registers the routes of the public listener on root. The v1 API
+// and the docs are served through requests, the middleware of ordinary
+// requests; the login and the local blob downloads through anonymous, as
+// they come before any token (or without one); and the long-lived event
+// streams through streams, so they can bypass the request timeout and the
+// in-flight limit.
+func Public(root *Router, d Deps, requests, anonymous, streams []Middleware) {
+	api := root.Group("", requests...)
+
+	// bodies are JSON, but those of uploads
+	v1 := api.Group("/api/v1", func(next http.Handler) http.Handler { return middleware.ContentType(next, "application/json") })
+	Students(v1, d.Students)
+	Bulk(v1, d.Bulk)
+	Jobs(v1, d.Jobs)
+	Webhooks(v1, d.Webhooks, d.Guard, d.Validate)
+	v1.HandleFunc("POST /batch", batchv1.Batch(root))
+	if d.Directory != nil {
+		root.Group("/api/v1", anonymous...).HandleFunc("POST /auth/login", authv1.Login(d.Directory))
+		v1.HandleFunc("GET /auth/session", authv1.GetSession())
+	}
+	if d.Files != nil {
+		Files(v1, api.Group("/api/v1"), d.Files)
+	}
+	if d.LocalBlobs != nil {
+		root.Group("/api/v1", anonymous...).HandleFunc("GET /blobs/{key...}", filev1.Blob(d.LocalBlobs))
+	}
+
+	// API documentation
+	api.HandleFunc("GET /openapi.json", docs.OpenAPI())
+	api.HandleFunc("GET /docs", docs.SwaggerUI())
+
+	Streams(root.Group("/api/v1", streams...), d.Streams, d.Bus)
+
+	// unversioned routes are kept for existing clients and forwarded to v1
+	legacy := middleware.Legacy(root, "/api", "/api/v1")
+	root.Handle("/api/students", legacy)
+	root.Handle("/api/students/", legacy)
+	root.Handle("/api/jobs/", legacy)
+}
+
+// Students registers the students; their mutations honor dry runs.
+func Students(g *Router, students *studentsvc.Service) {
+	mutations := g.DryRuns()
+	mutations.HandleFunc("POST /students", studentv1.New(students))
+	g.HandleFunc("GET /students/{id}", studentv1.GetById(students))
+	g.HandleFunc("GET /students", studentv1.GetStudentList(students))
+	mutations.HandleFunc("PUT /students/{id}", studentv1.UpdateStudent(students))
+	mutations.HandleFunc("DELETE /students/{id}", studentv1.DeleteStudent(students))
+	g.HandleFunc("GET /students/search", studentv1.Search(students))
+	g.HandleFunc("GET /students/duplicates", studentv1.GetDuplicates(students))
+	mutations.HandleFunc("POST /students/{id}/merge/{otherId}", studentv1.Merge(students))
+	mutations.HandleFunc("POST /students/verify-email", studentv1.VerifyEmail(students))
+	mutations.HandleFunc("POST /students/{id}/verify-email", studentv1.SendVerification(students))
+	g.HandleFunc("GET /students/{id}/history", studentv1.GetHistory(students))
+	g.HandleFunc("GET /students/{id}/history/{version}", studentv1.GetVersion(students))
+	mutations.HandleFunc("POST /students/{id}/history/{version}/restore", studentv1.RestoreVersion(students))
+	mutations.HandleFunc("POST /students/{id}/unarchive", studentv1.Unarchive(students))
+	g.HandleFunc("GET /reports/students", studentv1.GetReport(students))
+}
+
+// Files registers the files of students on g, and their uploads, whose
+// bodies are of any content type, on uploads. Uploads and deletions honor
+// dry runs.
+func Files(g, uploads *Router, files *filesvc.Service) {
+	uploads.DryRuns().HandleFunc("POST /students/{id}/files", filev1.Upload(files))
+	g.HandleFunc("GET /students/{id}/files", filev1.GetFiles(files))
+	g.HandleFunc("GET /students/{id}/files/{fileId}", filev1.GetFile(files))
+	g.HandleFunc("GET /students/{id}/files/{fileId}/download", filev1.Download(files))
+	g.DryRuns().HandleFunc("DELETE /students/{id}/files/{fileId}", filev1.DeleteFile(files))
+}
+
+// Bulk registers the student imports and exports, which run as jobs.
+func Bulk(g *Router, b *bulk.Bulk) {
+	g.HandleFunc("POST /students/import", studentv1.Import(b))
+	g.HandleFunc("POST /students/export", studentv1.Export(b))
+}
+
+func Jobs(g *Router, queue *jobs.Queue) {
+	g.HandleFunc("GET /jobs/{id}", jobv1.GetById(queue))
+	g.HandleFunc("GET /jobs/{id}/result", jobv1.GetResult(queue))
+}
+
+func Webhooks(g *Router, store storage.WebhookStorage, guard *webhooks.Guard, validate *validation.Validator) {
+	g.HandleFunc("POST /webhooks", webhookv1.New(store, guard, validate))
+	g.HandleFunc("GET /webhooks", webhookv1.GetWebhookList(store))
+	g.HandleFunc("DELETE /webhooks/{id}", webhookv1.DeleteWebhook(store))
+	g.HandleFunc("GET /webhooks/{id}/deliveries", webhookv1.GetDeliveries(store))
+}
+
+// Streams registers the student event streams, which end when stop is done.
+func Streams(g *Router, stop context.Context, bus *events.Bus) {
+	g.HandleFunc("GET /students/events", studentv1.Events(stop, bus))
+	g.HandleFunc("GET /students/live", studentv1.Live(stop, bus))
+}
+
+// Admin registers the routes of the admin listener on root.
+func Admin(root *Router, d Deps) {
+	root.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJson(w, r, http.StatusOK, map[string]string{"status": "healthy"})
+	})
+
+	root.HandleFunc("GET /maintenance", admin.GetMaintenance(d.Mode))
+	root.HandleFunc("PUT /maintenance", admin.SetMaintenance(d.Mode))
+	root.HandleFunc("GET /chaos", admin.GetChaos(d.Injector))
+	root.HandleFunc("PUT /chaos", admin.SetChaos(d.Injector))
+	root.HandleFunc("GET /jobs", admin.GetJobs(d.Jobs))
+	root.HandleFunc("GET /jobs/{id}", admin.GetJob(d.Jobs))
+	root.HandleFunc("POST /jobs/{id}/retry", admin.RetryJob(d.Jobs))
+	if d.Sms != nil {
+		root.HandleFunc("POST /sms", admin.SendSms(d.Sms, d.Validate))
+	}
+	root.HandleFunc("GET /schedules", admin.GetSchedules(d.Scheduler))
+	root.HandleFunc("POST /schedules/{name}/run", admin.RunSchedule(d.Scheduler))
+	root.HandleFunc("GET /databases", admin.GetDatabases(d.Databases))
+	if d.Replicator != nil {
+		root.HandleFunc("GET /replication", admin.GetReplication(d.Replicator))
+	}
+
+	debug := root.Group("/debug")
+	debug.Handle("GET /vars", expvar.Handler())
+	debug.HandleFunc("/pprof/", pprof.Index)
+	debug.HandleFunc("/pprof/cmdline", pprof.Cmdline)
+	debug.HandleFunc("/pprof/profile", pprof.Profile)
+	debug.HandleFunc("/pprof/symbol", pprof.Symbol)
+	debug.HandleFunc("/pprof/trace", pprof.Trace)
+}
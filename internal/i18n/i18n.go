@@ -0,0 +1,136 @@
+// Package i18n picks the language of a request from its Accept-Language
+// header and translates the API's messages into it. Messages are written in
+// English; the catalog for each other language maps their format strings to
+// translated ones, so a message missing from it is answered in English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/text/language"
+)
+
+// English is the language of the messages as written, and the one a request
+// gets when it accepts none of Languages.
+const English = "en"
+
+// Languages are the languages messages are translated into.
+var Languages = []string{English, "es", "fr", "de"}
+
+//go:embed catalog/*.json
+var files embed.FS
+
+var (
+	matcher  = language.NewMatcher(tags())
+	catalogs = load()
+)
+
+func tags() []language.Tag {
+	tags := make([]language.Tag, len(Languages))
+	for i, lang := range Languages {
+		tags[i] = language.MustParse(lang)
+	}
+
+	return tags
+}
+
+func load() map[string]map[string]string {
+	catalogs := map[string]map[string]string{}
+
+	for _, lang := range Languages[1:] {
+		data, err := files.ReadFile("catalog/" + lang + ".json")
+		if err != nil {
+			panic(err)
+		}
+
+		messages := map[string]string{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("catalog %s: %v", lang, err))
+		}
+		catalogs[lang] = messages
+	}
+
+	return catalogs
+}
+
+// FromRequest returns the one of Languages that best matches the request's
+// Accept-Language header, English if none does.
+func FromRequest(r *http.Request) string {
+	accepted, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil || len(accepted) == 0 {
+		return English
+	}
+
+	_, i, confidence := matcher.Match(accepted...)
+	if confidence == language.No {
+		return English
+	}
+
+	return Languages[i]
+}
+
+// Text returns the translation of s into lang, s itself if there is none.
+func Text(lang, s string) string {
+	if translated, ok := catalogs[lang][s]; ok {
+		return translated
+	}
+
+	return s
+}
+
+// Sprintf formats the translation of format into lang.
+func Sprintf(lang, format string, args ...any) string {
+	return fmt.Sprintf(Text(lang, format), args...)
+}
+
+// Message is implemented by errors whose message can be translated: it
+// returns the English format of the message and its arguments.
+type Message interface {
+	Message() (format string, args []any)
+}
+
+type message struct {
+	format string
+	args   []any
+	err    error
+}
+
+func (m *message) Error() string {
+	return m.err.Error()
+}
+
+func (m *message) Unwrap() error {
+	return errors.Unwrap(m.err)
+}
+
+func (m *message) Message() (string, []any) {
+	return m.format, m.args
+}
+
+// Errorf is fmt.Errorf for messages in the catalog.
+func Errorf(format string, args ...any) error {
+	return &message{format: format, args: args, err: fmt.Errorf(format, args...)}
+}
+
+// Translate returns the message of err in lang. Errors that only wrap a
+// translatable one, keeping its message, are translated as it is; any
+// other message is returned as is.
+func Translate(lang string, err error) string {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if m, ok := e.(Message); ok {
+			format, args := m.Message()
+			return Sprintf(lang, format, args...)
+		}
+
+		inner := errors.Unwrap(e)
+		if inner == nil || inner.Error() != e.Error() {
+			break
+		}
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,333 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// Handler runs one attempt of a job. An error retries the job after a
+// backoff until its attempts are used up; it is then dead-lettered.
+type Handler func(ctx context.Context, job types.Job) error
+
+// postponed is returned by a handler that can't run the job yet.
+type postponed struct {
+	delay time.Duration
+}
+
+func (p postponed) Error() string {
+	return fmt.Sprintf("postponed for %s", p.delay)
+}
+
+// Postpone returns an error that makes the queue run the job again after
+// delay without using up an attempt, e.g. when a rate limit is reached.
+func Postpone(delay time.Duration) error {
+	return postponed{delay: delay}
+}
+
+// maxBackoff bounds the delay before retrying a job, however many attempts
+// failed, unless the backoff of its kind is longer already.
+const maxBackoff = time.Hour
+
+type kind struct {
+	handler     Handler
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// Queue runs jobs from the persistent jobs table on a pool of workers.
+// Jobs survive restarts; one left running by a process that went away is
+// picked up again once its lease runs out.
+type Queue struct {
+	store   storage.JobStorage
+	workers int
+	poll    time.Duration
+	lease   time.Duration
+
+	mu    sync.RWMutex
+	kinds map[string]kind
+
+	wake     chan struct{}
+	stop     context.CancelFunc
+	abort    context.CancelFunc
+	stopped  chan struct{}
+	inFlight sync.WaitGroup
+}
+
+// New returns a queue with the given number of workers. Due jobs are looked
+// for every poll interval, and right away when one is enqueued. A job that
+// runs longer than lease is cancelled.
+func New(store storage.JobStorage, workers int, poll, lease time.Duration) *Queue {
+	return &Queue{
+		store:   store,
+		workers: workers,
+		poll:    poll,
+		lease:   lease,
+		kinds:   map[string]kind{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler for jobs of the given kind. Failed attempts are
+// retried after backoff, doubling every time up to an hour.
+func (q *Queue) Register(name string, maxAttempts int, backoff time.Duration, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.kinds[name] = kind{handler: h, maxAttempts: maxAttempts, backoff: backoff}
+}
+
+// Enqueue stores a job of a registered kind; payload is encoded as JSON.
+// The job runs for the tenant of ctx.
+func (q *Queue) Enqueue(ctx context.Context, name string, payload any) (int64, error) {
+	q.mu.RLock()
+	k, ok := q.kinds[name]
+	q.mu.RUnlock()
+
+	if !ok {
+		return 0, fmt.Errorf("unknown job kind %q", name)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("encode job payload: %w", err)
+	}
+
+	id, err := q.store.CreateJob(ctx, types.Job{Kind: name, Payload: data, MaxAttempts: k.maxAttempts})
+	if err != nil {
+		return 0, err
+	}
+
+	q.notify()
+
+	return id, nil
+}
+
+// Get returns a job by id.
+func (q *Queue) Get(ctx context.Context, id int64) (types.Job, error) {
+	return q.store.GetJob(ctx, id)
+}
+
+// List returns the most recent jobs, optionally only those with the given
+// status or kind.
+func (q *Queue) List(ctx context.Context, status, kind string, limit int) ([]types.Job, error) {
+	return q.store.GetJobList(ctx, status, kind, limit)
+}
+
+// Counts returns the number of jobs in each status.
+func (q *Queue) Counts(ctx context.Context) (map[string]int, error) {
+	return q.store.CountJobs(ctx)
+}
+
+// Progress records how far a running job has got.
+func (q *Queue) Progress(ctx context.Context, id int64, done, total int) error {
+	return q.store.UpdateJobProgress(ctx, id, types.JobProgress{Done: done, Total: total})
+}
+
+// SetResult stores a job's result, encoded as JSON, and an optional output
+// for download.
+func (q *Queue) SetResult(ctx context.Context, id int64, result any, output []byte) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("encode job result: %w", err)
+	}
+
+	return q.store.SetJobResult(ctx, id, data, output)
+}
+
+// Output returns the output stored by a job, nil if there is none.
+func (q *Queue) Output(ctx context.Context, id int64) ([]byte, error) {
+	return q.store.GetJobOutput(ctx, id)
+}
+
+// Retry requeues a dead job with a fresh set of attempts.
+func (q *Queue) Retry(ctx context.Context, id int64) error {
+	if err := q.store.RequeueJob(ctx, id); err != nil {
+		return err
+	}
+
+	q.notify()
+
+	return nil
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start begins running jobs in the background.
+func (q *Queue) Start() {
+	ctx, stop := context.WithCancel(context.Background())
+	work, abort := context.WithCancel(context.Background())
+
+	q.stop, q.abort = stop, abort
+	q.stopped = make(chan struct{})
+
+	go q.run(ctx, work)
+}
+
+// Stop stops taking new jobs and waits for the running ones. Jobs still
+// running when ctx is done are cancelled and retried later.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.stop()
+	<-q.stopped
+
+	done := make(chan struct{})
+	go func() {
+		q.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.abort()
+		return nil
+	case <-ctx.Done():
+		q.abort()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) run(ctx, work context.Context) {
+	defer close(q.stopped)
+
+	slots := make(chan struct{}, q.workers)
+
+	for {
+		// wait for an idle worker before claiming
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		// the workers are shared by every tenant
+		job, ok, err := q.store.ClaimJob(tenant.All(ctx), time.Now(), q.lease)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("failed to claim job", slog.String("error", err.Error()))
+		}
+
+		if err != nil || !ok {
+			<-slots
+
+			select {
+			case <-q.wake:
+			case <-time.After(q.poll):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		q.inFlight.Add(1)
+		go func() {
+			defer q.inFlight.Done()
+			defer func() { <-slots }()
+
+			q.process(work, job)
+		}()
+	}
+}
+
+func (q *Queue) process(ctx context.Context, job types.Job) {
+	// the job runs, and its outcome is recorded, for its tenant
+	ctx = tenant.With(ctx, job.TenantId)
+
+	q.mu.RLock()
+	k, ok := q.kinds[job.Kind]
+	q.mu.RUnlock()
+
+	log := slog.With(slog.Int64("job_id", job.Id), slog.String("kind", job.Kind), slog.String("tenant", job.TenantId), slog.Int("attempt", job.Attempts))
+
+	// outcomes are recorded even when the job itself was cancelled
+	store := context.WithoutCancel(ctx)
+
+	if !ok {
+		log.Error("job of unknown kind dead-lettered")
+		q.deadLetter(store, log, job, fmt.Sprintf("unknown job kind %q", job.Kind))
+		return
+	}
+
+	// the previous attempt's lease ran out, most likely in a crashed process
+	if job.Attempts > job.MaxAttempts {
+		log.Error("job dead-lettered after its last attempt was interrupted")
+		q.deadLetter(store, log, job, "last attempt did not finish")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, q.lease)
+	defer cancel()
+
+	err := call(ctx, k.handler, job)
+	if err == nil {
+		if err := q.store.CompleteJob(store, job.Id); err != nil {
+			log.Error("failed to complete job", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	var p postponed
+	if errors.As(err, &p) {
+		if err := q.store.PostponeJob(store, job.Id, time.Now().Add(p.delay)); err != nil {
+			log.Error("failed to postpone job", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	if job.Attempts >= job.MaxAttempts {
+		log.Error("job failed, dead-lettered", slog.String("error", err.Error()))
+		q.deadLetter(store, log, job, err.Error())
+		return
+	}
+
+	delay := retryDelay(k.backoff, job.Attempts)
+	log.Warn("job failed, retrying", slog.String("error", err.Error()), slog.Duration("retry_in", delay))
+
+	if err := q.store.RescheduleJob(store, job.Id, time.Now().Add(delay), err.Error()); err != nil {
+		log.Error("failed to reschedule job", slog.String("error", err.Error()))
+	}
+}
+
+// retryDelay returns the delay before retrying a job after its attempts
+// failed: backoff, doubled after every failed attempt but the first, up to
+// maxBackoff.
+func retryDelay(backoff time.Duration, attempts int) time.Duration {
+	limit := max(backoff, maxBackoff)
+
+	delay := backoff
+	for i := 1; i < attempts && delay > 0 && delay < limit; i++ {
+		delay *= 2
+	}
+
+	return min(delay, limit)
+}
+
+func (q *Queue) deadLetter(ctx context.Context, log *slog.Logger, job types.Job, reason string) {
+	if err := q.store.DeadLetterJob(ctx, job.Id, reason); err != nil {
+		log.Error("failed to dead-letter job", slog.String("error", err.Error()))
+	}
+}
+
+// call runs h, turning a panic into an error so one bad job can't take the
+// process down.
+func call(ctx context.Context, h Handler, job types.Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return h(ctx, job)
+}
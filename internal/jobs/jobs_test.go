@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		backoff  time.Duration
+		attempts int
+		want     time.Duration
+	}{
+		{time.Second, 1, time.Second},
+		{time.Second, 2, 2 * time.Second},
+		{time.Second, 5, 16 * time.Second},
+		{time.Second, 12, 2048 * time.Second},
+		{time.Second, 13, time.Hour},
+		// far past where the shift overflowed into negative delays
+		{time.Second, 64, time.Hour},
+		{time.Second, 1000, time.Hour},
+		{time.Second, 1 << 30, time.Hour},
+		{0, 1000, 0},
+		{2 * time.Hour, 10, 2 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(tt.backoff, tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%s, %d) = %s, want %s", tt.backoff, tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER classes and the constructed bit of identifier octets
+const (
+	classApplication = 0x40
+	classContext     = 0x80
+	constructed      = 0x20
+)
+
+// universal tags
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+)
+
+// maxPacket bounds the size of a message read from the server
+const maxPacket = 16 << 20
+
+// packet is a BER element: its identifier octet and either its value or,
+// if constructed, its children.
+type packet struct {
+	tag      byte
+	value    []byte
+	children []*packet
+}
+
+// encode returns the BER encoding of an element with tag and content.
+func encode(tag byte, content ...[]byte) []byte {
+	var n int
+	for _, c := range content {
+		n += len(c)
+	}
+
+	b := append([]byte{tag}, encodeLength(n)...)
+	for _, c := range content {
+		b = append(b, c...)
+	}
+
+	return b
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func encodeInt(tag byte, n int64) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(n)}, b...)
+		// done once the rest is only the sign of what was written
+		if (n >= -0x80 && n < 0x80) || len(b) == 8 {
+			break
+		}
+		n >>= 8
+	}
+
+	return encode(tag, b)
+}
+
+func encodeString(tag byte, s string) []byte {
+	return encode(tag, []byte(s))
+}
+
+func encodeBool(tag byte, v bool) []byte {
+	if v {
+		return encode(tag, []byte{0xff})
+	}
+
+	return encode(tag, []byte{0})
+}
+
+// readPacket reads one element from r.
+func readPacket(r *bufio.Reader) (*packet, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+
+	value := make([]byte, n)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+
+	return parse(tag, value)
+}
+
+func readLength(r *bufio.Reader) (int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	if b < 0x80 {
+		return int(b), nil
+	}
+
+	size := int(b &^ 0x80)
+	if size == 0 || size > 4 {
+		return 0, fmt.Errorf("unsupported BER length of %d bytes", size)
+	}
+
+	var n int
+	for range size {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n = n<<8 | int(b)
+	}
+
+	if n > maxPacket {
+		return 0, fmt.Errorf("message of %d bytes is too large", n)
+	}
+
+	return n, nil
+}
+
+// parse builds the element with tag and value, parsing the children of
+// constructed ones.
+func parse(tag byte, value []byte) (*packet, error) {
+	p := &packet{tag: tag, value: value}
+	if tag&constructed == 0 {
+		return p, nil
+	}
+
+	for len(value) > 0 {
+		if len(value) < 2 {
+			return nil, errors.New("truncated BER element")
+		}
+
+		childTag, rest := value[0], value[1:]
+
+		n := int(rest[0])
+		rest = rest[1:]
+		if n >= 0x80 {
+			size := n &^ 0x80
+			if size == 0 || size > 4 || size > len(rest) {
+				return nil, errors.New("invalid BER length")
+			}
+			n = 0
+			for _, b := range rest[:size] {
+				n = n<<8 | int(b)
+			}
+			rest = rest[size:]
+		}
+
+		if n > len(rest) {
+			return nil, errors.New("truncated BER element")
+		}
+
+		child, err := parse(childTag, rest[:n])
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+
+		value = rest[n:]
+	}
+
+	return p, nil
+}
+
+// int returns the value of an INTEGER or ENUMERATED element.
+func (p *packet) int() int64 {
+	var n int64
+	for i, b := range p.value {
+		if i == 0 && b&0x80 != 0 {
+			n = -1
+		}
+		n = n<<8 | int64(b)
+	}
+
+	return n
+}
+
+// child returns the i-th child, or an empty element if there isn't one,
+// so malformed responses read as empty values rather than panic.
+func (p *packet) child(i int) *packet {
+	if i < len(p.children) {
+		return p.children[i]
+	}
+
+	return &packet{}
+}
@@ -0,0 +1,96 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncodeLength(t *testing.T) {
+	tests := map[int][]byte{
+		0:       {0x00},
+		0x7f:    {0x7f},
+		0x80:    {0x81, 0x80},
+		0xff:    {0x81, 0xff},
+		0x100:   {0x82, 0x01, 0x00},
+		0x12345: {0x83, 0x01, 0x23, 0x45},
+	}
+
+	for n, want := range tests {
+		if got := encodeLength(n); !bytes.Equal(got, want) {
+			t.Errorf("encodeLength(%d) = %x, want %x", n, got, want)
+		}
+	}
+}
+
+func TestInt(t *testing.T) {
+	tests := map[int64][]byte{
+		0:       {0x00},
+		127:     {0x7f},
+		128:     {0x00, 0x80},
+		256:     {0x01, 0x00},
+		-1:      {0xff},
+		-128:    {0x80},
+		-129:    {0xff, 0x7f},
+		1 << 40: {0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+	}
+
+	for n, want := range tests {
+		b := encodeInt(tagInteger, n)
+		if !bytes.Equal(b[2:], want) {
+			t.Errorf("encodeInt(%d) = %x, want %x", n, b[2:], want)
+		}
+
+		p, err := readPacket(bufio.NewReader(bytes.NewReader(b)))
+		if err != nil {
+			t.Fatalf("readPacket(%x): %v", b, err)
+		}
+		if p.tag != tagInteger || p.int() != n {
+			t.Errorf("encodeInt(%d) reads back as %d", n, p.int())
+		}
+	}
+}
+
+func TestReadPacket(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	b := encode(tagSequence, encodeInt(tagInteger, 7), encode(classApplication|constructed|3, encodeString(tagOctetString, long), encodeBool(tagBoolean, true)))
+
+	p, err := readPacket(bufio.NewReader(bytes.NewReader(b)))
+	if err != nil {
+		t.Fatalf("readPacket: %v", err)
+	}
+
+	if p.tag != tagSequence || len(p.children) != 2 || p.child(0).int() != 7 {
+		t.Fatalf("packet = %+v", p)
+	}
+	op := p.child(1)
+	if op.tag != classApplication|constructed|3 || string(op.child(0).value) != long || !bytes.Equal(op.child(1).value, []byte{0xff}) {
+		t.Errorf("operation = %+v", op)
+	}
+
+	// missing children read as empty rather than panic
+	if c := op.child(5).child(0); c.tag != 0 || c.value != nil {
+		t.Errorf("missing child = %+v", c)
+	}
+}
+
+func TestReadPacketErrors(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":             {},
+		"no length":         {tagSequence},
+		"short value":       {tagOctetString, 0x05, 'a'},
+		"indefinite":        {tagSequence, 0x80},
+		"length too long":   {tagOctetString, 0x85, 1, 0, 0, 0, 0},
+		"too large":         {tagOctetString, 0x84, 0x7f, 0xff, 0xff, 0xff},
+		"truncated child":   {tagSequence, 0x03, tagOctetString, 0x05, 'a'},
+		"child length":      {tagSequence, 0x02, tagOctetString, 0x82},
+		"one byte of child": {tagSequence, 0x01, tagOctetString},
+	}
+
+	for name, b := range tests {
+		if p, err := readPacket(bufio.NewReader(bytes.NewReader(b))); err == nil {
+			t.Errorf("%s: readPacket(%x) = %+v, want an error", name, b, p)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// filter choices of RFC 4511
+const (
+	filterAnd            = classContext | constructed | 0
+	filterOr             = classContext | constructed | 1
+	filterNot            = classContext | constructed | 2
+	filterEquality       = classContext | constructed | 3
+	filterSubstrings     = classContext | constructed | 4
+	filterGreaterOrEqual = classContext | constructed | 5
+	filterLessOrEqual    = classContext | constructed | 6
+	filterPresent        = classContext | 7
+	filterApprox         = classContext | constructed | 8
+	filterExtensible     = classContext | constructed | 9
+)
+
+// EscapeFilter escapes the characters of s that are special in a filter,
+// for values taken from users such as a username.
+func EscapeFilter(s string) string {
+	var b strings.Builder
+	for i := range len(s) {
+		switch c := s[i]; c {
+		case '\\', '*', '(', ')', 0:
+			fmt.Fprintf(&b, `\%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// CompileFilter checks the string form of a filter (RFC 4515), such as
+// "(&(objectClass=user)(sAMAccountName=jdoe))", and returns its encoding.
+func CompileFilter(filter string) ([]byte, error) {
+	b, rest, err := compileFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", filter, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("invalid filter %q: unexpected %q", filter, rest)
+	}
+
+	return b, nil
+}
+
+// compileFilter compiles the filter at the start of s and returns what
+// follows it.
+func compileFilter(s string) ([]byte, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("expected ( at %q", s)
+	}
+	s = s[1:]
+
+	var tag byte
+	switch {
+	case strings.HasPrefix(s, "&"):
+		tag = filterAnd
+	case strings.HasPrefix(s, "|"):
+		tag = filterOr
+	case strings.HasPrefix(s, "!"):
+		tag = filterNot
+	default:
+		end := strings.IndexByte(s, ')')
+		if end < 0 {
+			return nil, "", fmt.Errorf("missing ) after %q", s)
+		}
+		b, err := compileItem(s[:end])
+		return b, s[end+1:], err
+	}
+
+	s = s[1:]
+
+	var filters [][]byte
+	for strings.HasPrefix(s, "(") {
+		b, rest, err := compileFilter(s)
+		if err != nil {
+			return nil, "", err
+		}
+		filters, s = append(filters, b), rest
+	}
+
+	if !strings.HasPrefix(s, ")") {
+		return nil, "", fmt.Errorf("expected ) at %q", s)
+	}
+	if len(filters) == 0 || (tag == filterNot && len(filters) != 1) {
+		return nil, "", fmt.Errorf("wrong number of filters in a %c", "&|!"[tag&0x0f])
+	}
+
+	return encode(tag, filters...), s[1:], nil
+}
+
+// compileItem compiles a simple filter such as "uid=jdoe", without its
+// parentheses.
+func compileItem(s string) ([]byte, error) {
+	eq := strings.IndexByte(s, '=')
+	if eq < 1 {
+		return nil, fmt.Errorf("%q is not attribute=value", s)
+	}
+
+	attr, value := s[:eq], s[eq+1:]
+
+	tag := byte(filterEquality)
+	switch attr[len(attr)-1] {
+	case '>':
+		tag, attr = filterGreaterOrEqual, attr[:len(attr)-1]
+	case '<':
+		tag, attr = filterLessOrEqual, attr[:len(attr)-1]
+	case '~':
+		tag, attr = filterApprox, attr[:len(attr)-1]
+	case ':':
+		return compileExtensible(attr[:len(attr)-1], value)
+	}
+
+	if attr == "" {
+		return nil, fmt.Errorf("%q has no attribute", s)
+	}
+
+	if tag == filterEquality && value == "*" {
+		return encodeString(filterPresent, attr), nil
+	}
+
+	if tag == filterEquality && strings.Contains(value, "*") {
+		return compileSubstrings(attr, value)
+	}
+
+	v, err := unescape(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(tag, encodeString(tagOctetString, attr), encodeString(tagOctetString, v)), nil
+}
+
+// compileSubstrings compiles attr=value where value has wildcards, such as
+// "cn=Jo*Smith".
+func compileSubstrings(attr, value string) ([]byte, error) {
+	parts := strings.Split(value, "*")
+
+	var subs [][]byte
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+
+		v, err := unescape(part)
+		if err != nil {
+			return nil, err
+		}
+
+		tag := byte(classContext | 1) // any
+		switch i {
+		case 0:
+			tag = classContext | 0 // initial
+		case len(parts) - 1:
+			tag = classContext | 2 // final
+		}
+		subs = append(subs, encodeString(tag, v))
+	}
+
+	return encode(filterSubstrings, encodeString(tagOctetString, attr), encode(tagSequence, subs...)), nil
+}
+
+// compileExtensible compiles attr[:dn][:rule]:=value, such as Active
+// Directory's "memberOf:1.2.840.113556.1.4.1941:=<group>" that matches
+// nested group memberships.
+func compileExtensible(attr, value string) ([]byte, error) {
+	parts := strings.Split(attr, ":")
+
+	var rule string
+	var dnAttributes bool
+	for _, p := range parts[1:] {
+		switch {
+		case strings.EqualFold(p, "dn"):
+			dnAttributes = true
+		case rule == "" && p != "":
+			rule = p
+		default:
+			return nil, fmt.Errorf("invalid extensible match %q", attr)
+		}
+	}
+
+	if parts[0] == "" && rule == "" {
+		return nil, fmt.Errorf("extensible match %q needs an attribute or a rule", attr)
+	}
+
+	v, err := unescape(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var content [][]byte
+	if rule != "" {
+		content = append(content, encodeString(classContext|1, rule))
+	}
+	if parts[0] != "" {
+		content = append(content, encodeString(classContext|2, parts[0]))
+	}
+	content = append(content, encodeString(classContext|3, v))
+	if dnAttributes {
+		content = append(content, encodeBool(classContext|4, true))
+	}
+
+	return encode(filterExtensible, content...), nil
+}
+
+// unescape decodes the \XX escapes of a filter value.
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		if i+3 > len(s) {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		c, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		b.Write(c)
+		i += 2
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,216 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// decompile returns the string form of the encoded filter b, with values
+// escaped by EscapeFilter.
+func decompile(t *testing.T, b []byte) string {
+	t.Helper()
+
+	p, err := readPacket(bufio.NewReader(bytes.NewReader(b)))
+	if err != nil {
+		t.Fatalf("read filter %x: %v", b, err)
+	}
+
+	return describe(t, p)
+}
+
+func describe(t *testing.T, p *packet) string {
+	t.Helper()
+
+	pair := func(op string) string {
+		return "(" + string(p.child(0).value) + op + EscapeFilter(string(p.child(1).value)) + ")"
+	}
+
+	switch p.tag {
+	case filterAnd, filterOr, filterNot:
+		var b strings.Builder
+		b.WriteString("(" + string("&|!"[p.tag&0x0f]))
+		for _, c := range p.children {
+			b.WriteString(describe(t, c))
+		}
+		return b.String() + ")"
+	case filterEquality:
+		return pair("=")
+	case filterGreaterOrEqual:
+		return pair(">=")
+	case filterLessOrEqual:
+		return pair("<=")
+	case filterApprox:
+		return pair("~=")
+	case filterPresent:
+		return "(" + string(p.value) + "=*)"
+	case filterSubstrings:
+		var b strings.Builder
+		b.WriteString("(" + string(p.child(0).value) + "=")
+		subs := p.child(1).children
+		if len(subs) == 0 || subs[0].tag != classContext|0 {
+			b.WriteString("*")
+		}
+		for _, s := range subs {
+			b.WriteString(EscapeFilter(string(s.value)))
+			if s.tag != classContext|2 {
+				b.WriteString("*")
+			}
+		}
+		return b.String() + ")"
+	case filterExtensible:
+		var attr, rule, value string
+		var dn bool
+		for _, c := range p.children {
+			switch c.tag {
+			case classContext | 1:
+				rule = ":" + string(c.value)
+			case classContext | 2:
+				attr = string(c.value)
+			case classContext | 3:
+				value = EscapeFilter(string(c.value))
+			case classContext | 4:
+				dn = len(c.value) == 1 && c.value[0] != 0
+			}
+		}
+		if dn {
+			attr += ":dn"
+		}
+		return "(" + attr + rule + ":=" + value + ")"
+	}
+
+	t.Fatalf("unexpected filter tag %#x", p.tag)
+	return ""
+}
+
+func TestCompileFilter(t *testing.T) {
+	// filters in the form decompile returns them in
+	filters := []string{
+		"(uid=jdoe)",
+		"(&(objectClass=user)(sAMAccountName=jdoe))",
+		"(|(uid=ann)(uid=bob)(mail=bob@example.com))",
+		"(!(uid=jdoe))",
+		"(&(objectClass=person)(|(uid=a)(!(cn=b))))",
+		"(mail=*)",
+		"(cn=Jo*)",
+		"(cn=*Smith)",
+		"(cn=Jo*Smith)",
+		"(cn=*o*i*)",
+		"(cn=J*o*h*n)",
+		"(age>=21)",
+		"(age<=30)",
+		"(cn~=jon)",
+		"(memberOf:1.2.840.113556.1.4.1941:=cn=Teachers,ou=Groups,dc=example,dc=com)",
+		"(cn:dn:2.5.13.5:=Fred)",
+		"(:dn:2.4.6.8.10:=Dino)",
+		"(o:dn:=Ace)",
+		// escaped specials are values, not wildcards or parentheses
+		`(cn=a\2ab)`,
+		`(cn=\2a)`,
+		`(cn=\28x\29)`,
+		`(cn=\5c)`,
+		`(cn=a\00b)`,
+		`(cn=J\2a*\28*)`,
+	}
+
+	for _, f := range filters {
+		b, err := CompileFilter(f)
+		if err != nil {
+			t.Errorf("CompileFilter(%s): %v", f, err)
+			continue
+		}
+		if got := decompile(t, b); got != f {
+			t.Errorf("CompileFilter(%s) decompiles to %s", f, got)
+		}
+	}
+}
+
+func TestCompileFilterEscapes(t *testing.T) {
+	tests := map[string]string{
+		`(cn=\2A\5C)`:         `(cn=\2a\5c)`,
+		`(cn=caf\c3\a9)`:      "(cn=café)",
+		"(cn:DN:2.5.13.5:=x)": "(cn:dn:2.5.13.5:=x)",
+		// the order of dn and the rule is free
+		"(cn:2.5.13.5:dn:=x)": "(cn:dn:2.5.13.5:=x)",
+	}
+
+	for f, want := range tests {
+		b, err := CompileFilter(f)
+		if err != nil {
+			t.Errorf("CompileFilter(%s): %v", f, err)
+			continue
+		}
+		if got := decompile(t, b); got != want {
+			t.Errorf("CompileFilter(%s) decompiles to %s, want %s", f, got, want)
+		}
+	}
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	filters := []string{
+		"",
+		"uid=jdoe",
+		"(uid=jdoe",
+		"(uid=jdoe))",
+		"(uid=jdoe)(uid=ann)",
+		"(&)",
+		"(|)",
+		"(!(a=b)(c=d))",
+		"(&(uid=jdoe)",
+		"(&uid=jdoe)",
+		"(=x)",
+		"(>=x)",
+		"(uid)",
+		`(uid=\zz)`,
+		`(uid=\2)`,
+		`(uid=a\)`,
+		`(cn=J*\2)`,
+		"(:=x)",
+		"(:dn:=x)",
+		"(cn:a:b:=x)",
+		`(cn:1.2:=\x)`,
+	}
+
+	for _, f := range filters {
+		if b, err := CompileFilter(f); err == nil {
+			t.Errorf("CompileFilter(%q) = %x, want an error", f, b)
+		}
+	}
+}
+
+func TestEscapeFilter(t *testing.T) {
+	tests := map[string]string{
+		"jdoe":         "jdoe",
+		"*":            `\2a`,
+		"a*b":          `a\2ab`,
+		"(admin)":      `\28admin\29`,
+		`dom\user`:     `dom\5cuser`,
+		"nul\x00":      `nul\00`,
+		"café":         "café",
+		"*)(uid=*))(|": `\2a\29\28uid=\2a\29\29\28|`,
+	}
+
+	for s, want := range tests {
+		if got := EscapeFilter(s); got != want {
+			t.Errorf("EscapeFilter(%q) = %s, want %s", s, got, want)
+		}
+	}
+
+	// whatever a user types, an escaped value is matched for equality
+	for s := range tests {
+		b, err := CompileFilter("(uid=" + EscapeFilter(s) + ")")
+		if err != nil {
+			t.Errorf("CompileFilter of escaped %q: %v", s, err)
+			continue
+		}
+
+		p, err := readPacket(bufio.NewReader(bytes.NewReader(b)))
+		if err != nil {
+			t.Fatalf("read filter: %v", err)
+		}
+		if p.tag != filterEquality || string(p.child(0).value) != "uid" || string(p.child(1).value) != s {
+			t.Errorf("escaped %q compiles to %s", s, describe(t, p))
+		}
+	}
+}
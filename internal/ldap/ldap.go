@@ -0,0 +1,304 @@
+// Package ldap is a minimal LDAPv3 client (RFC 4511): simple binds and
+// searches over ldap://, ldaps:// or StartTLS, enough to check directory
+// credentials such as those of Active Directory.
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// protocol operations
+const (
+	opBindRequest      = classApplication | constructed | 0
+	opBindResponse     = classApplication | constructed | 1
+	opUnbindRequest    = classApplication | 2
+	opSearchRequest    = classApplication | constructed | 3
+	opSearchEntry      = classApplication | constructed | 4
+	opSearchDone       = classApplication | constructed | 5
+	opSearchReference  = classApplication | constructed | 19
+	opExtendedRequest  = classApplication | constructed | 23
+	opExtendedResponse = classApplication | constructed | 24
+)
+
+// result codes the callers tell apart
+const (
+	ResultSuccess            = 0
+	ResultSizeLimitExceeded  = 4
+	ResultInvalidCredentials = 49
+)
+
+// startTLS is the OID of the StartTLS extended operation
+const startTLS = "1.3.6.1.4.1.1466.20037"
+
+// Error is a result other than success answered by the server.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap result %d", e.Code)
+	}
+
+	return fmt.Sprintf("ldap result %d: %s", e.Code, e.Message)
+}
+
+// IsInvalidCredentials reports whether err is a bind refused for a wrong
+// DN or password.
+func IsInvalidCredentials(err error) bool {
+	var ldapErr *Error
+	return errors.As(err, &ldapErr) && ldapErr.Code == ResultInvalidCredentials
+}
+
+// Entry is an entry found by a search.
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Values returns the values of attr, whose name is matched regardless of
+// case like the server does.
+func (e Entry) Values(attr string) []string {
+	for name, values := range e.Attributes {
+		if strings.EqualFold(name, attr) {
+			return values
+		}
+	}
+
+	return nil
+}
+
+// Conn is a connection to a directory server. Operations are sent one at
+// a time; a Conn must not be used concurrently.
+type Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	id   int64
+}
+
+// Dial connects to the server at rawurl, ldap://host[:389] or
+// ldaps://host[:636], with config for TLS (nil for the defaults). With
+// startTLS, an ldap:// connection is upgraded to TLS before it is used.
+// ctx bounds the dial and every operation of the connection.
+func Dial(ctx context.Context, rawurl string, config *tls.Config, startTLS bool) (*Conn, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	host, port := u.Hostname(), u.Port()
+	switch {
+	case u.Scheme == "ldap" && port == "":
+		port = "389"
+	case u.Scheme == "ldaps" && port == "":
+		port = "636"
+	case u.Scheme != "ldap" && u.Scheme != "ldaps":
+		return nil, fmt.Errorf("unsupported scheme %q, use ldap or ldaps", u.Scheme)
+	}
+
+	if config == nil {
+		config = &tls.Config{}
+	}
+	if config.ServerName == "" {
+		config = config.Clone()
+		config.ServerName = host
+	}
+
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		nc.SetDeadline(deadline)
+	}
+
+	if u.Scheme == "ldaps" {
+		nc = tls.Client(nc, config)
+	}
+
+	c := &Conn{conn: nc, r: bufio.NewReader(nc)}
+
+	if startTLS && u.Scheme == "ldap" {
+		if err := c.startTLS(config); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Close unbinds and closes the connection.
+func (c *Conn) Close() error {
+	c.send(encode(opUnbindRequest))
+	return c.conn.Close()
+}
+
+// Bind authenticates as dn with password. An empty password is refused
+// here: servers accept it as an anonymous bind, whatever the DN.
+func (c *Conn) Bind(dn, password string) error {
+	if password == "" {
+		return &Error{Code: ResultInvalidCredentials, Message: "empty password"}
+	}
+
+	id, err := c.send(encode(opBindRequest,
+		encodeInt(tagInteger, 3),
+		encodeString(tagOctetString, dn),
+		encodeString(classContext|0, password),
+	))
+	if err != nil {
+		return err
+	}
+
+	res, err := c.read(id, opBindResponse)
+	if err != nil {
+		return err
+	}
+
+	return result(res)
+}
+
+// Search returns the entries under base, at any depth, that match filter
+// (see CompileFilter), with the attributes attrs. limit is the most
+// entries the server may return, 0 for its own limit; if more match, the
+// first limit are returned along with an *Error of ResultSizeLimitExceeded.
+func (c *Conn) Search(base, filter string, attrs []string, limit int) ([]Entry, error) {
+	f, err := CompileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([][]byte, len(attrs))
+	for i, a := range attrs {
+		names[i] = encodeString(tagOctetString, a)
+	}
+
+	id, err := c.send(encode(opSearchRequest,
+		encodeString(tagOctetString, base),
+		encodeInt(tagEnumerated, 2), // whole subtree
+		encodeInt(tagEnumerated, 0), // never dereference aliases
+		encodeInt(tagInteger, int64(limit)),
+		encodeInt(tagInteger, 0),
+		encodeBool(tagBoolean, false),
+		f,
+		encode(tagSequence, names...),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for {
+		res, err := c.read(id, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		switch res.tag {
+		case opSearchEntry:
+			entries = append(entries, newEntry(res))
+		case opSearchReference:
+			// referrals to other servers aren't followed
+		case opSearchDone:
+			return entries, result(res)
+		default:
+			return nil, fmt.Errorf("unexpected ldap operation 0x%02x", res.tag)
+		}
+	}
+}
+
+func (c *Conn) startTLS(config *tls.Config) error {
+	id, err := c.send(encode(opExtendedRequest, encodeString(classContext|0, startTLS)))
+	if err != nil {
+		return err
+	}
+
+	res, err := c.read(id, opExtendedResponse)
+	if err != nil {
+		return err
+	}
+	if err := result(res); err != nil {
+		return fmt.Errorf("starttls refused: %w", err)
+	}
+
+	tc := tls.Client(c.conn, config)
+	if err := tc.Handshake(); err != nil {
+		return err
+	}
+
+	c.conn, c.r = tc, bufio.NewReader(tc)
+
+	return nil
+}
+
+// send sends the operation op in a message of its own and returns the
+// message id.
+func (c *Conn) send(op []byte) (int64, error) {
+	c.id++
+	_, err := c.conn.Write(encode(tagSequence, encodeInt(tagInteger, c.id), op))
+
+	return c.id, err
+}
+
+// read returns the next operation of the response to message id, which
+// must be op unless op is 0.
+func (c *Conn) read(id int64, op byte) (*packet, error) {
+	for {
+		msg, err := readPacket(c.r)
+		if err != nil {
+			return nil, err
+		}
+
+		if msg.tag != tagSequence || len(msg.children) < 2 {
+			return nil, errors.New("malformed ldap message")
+		}
+
+		res := msg.child(1)
+		switch msgId := msg.child(0).int(); {
+		case msgId == 0 && res.tag == opExtendedResponse:
+			// an unsolicited notice, such as of disconnection
+			return nil, fmt.Errorf("server closed the connection: %w", result(res))
+		case msgId != id:
+			continue
+		}
+
+		if op != 0 && res.tag != op {
+			return nil, fmt.Errorf("unexpected ldap operation 0x%02x", res.tag)
+		}
+
+		return res, nil
+	}
+}
+
+// result returns the error of an LDAPResult other than success.
+func result(res *packet) error {
+	code := int(res.child(0).int())
+	if code == ResultSuccess {
+		return nil
+	}
+
+	return &Error{Code: code, Message: string(res.child(2).value)}
+}
+
+func newEntry(res *packet) Entry {
+	e := Entry{DN: string(res.child(0).value), Attributes: map[string][]string{}}
+	for _, attr := range res.child(1).children {
+		var values []string
+		for _, v := range attr.child(1).children {
+			values = append(values, string(v.value))
+		}
+		e.Attributes[string(attr.child(0).value)] = values
+	}
+
+	return e
+}
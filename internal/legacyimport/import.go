@@ -0,0 +1,175 @@
+package legacyimport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/types"
+	"github.com/cmanish049/students-api/internal/validation"
+)
+
+// Creator is the part of the storage an import needs.
+type Creator interface {
+	CreateStudent(ctx context.Context, name, email string, age int) (int64, error)
+}
+
+// RecordError reports why one record was not imported.
+type RecordError struct {
+	Record int // 1-based position in the export
+	Line   int
+	Err    error
+}
+
+func (e RecordError) Error() string {
+	return fmt.Sprintf("record %d (line %d): %v", e.Record, e.Line, e.Err)
+}
+
+type Result struct {
+	Read     int
+	Imported int
+	Errors   []RecordError
+}
+
+// Import reads the export in r according to m and creates a student for
+// every record that passes validate. Bad records are collected in the
+// result and don't stop the import; only unreadable input does. With dryRun
+// nothing is written.
+func Import(ctx context.Context, r io.Reader, m *Mapping, store Creator, validate *validation.Validator, dryRun bool) (Result, error) {
+	var res Result
+
+	now := time.Now()
+	seen := map[string]int{}
+
+	each := func(rec record) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		res.Read++
+		fail := func(err error) {
+			res.Errors = append(res.Errors, RecordError{Record: res.Read, Line: rec.line, Err: err})
+		}
+
+		student, err := m.student(rec, now)
+		if err != nil {
+			fail(err)
+			return nil
+		}
+
+		if err := validate.Struct(student); err != nil {
+			fail(err)
+			return nil
+		}
+
+		key := strings.ToLower(student.Email)
+		if first, ok := seen[key]; ok {
+			fail(fmt.Errorf("email %s already used by record %d", student.Email, first))
+			return nil
+		}
+		seen[key] = res.Read
+
+		if !dryRun {
+			if _, err := store.CreateStudent(ctx, student.Name, student.Email, student.Age); err != nil {
+				fail(err)
+				return nil
+			}
+		}
+
+		res.Imported++
+		return nil
+	}
+
+	var err error
+	if m.Format == FormatXML {
+		err = readXML(r, m, each)
+	} else {
+		err = readFixedWidth(r, m, each)
+	}
+
+	return res, err
+}
+
+func (m *Mapping) student(rec record, now time.Time) (types.Student, error) {
+	name, err := m.value(rec, "name", now)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	email, err := m.value(rec, "email", now)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	ageText, err := m.value(rec, "age", now)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	age, err := strconv.Atoi(ageText)
+	if err != nil && ageText != "" {
+		return types.Student{}, fmt.Errorf("age: %q is not a number", ageText)
+	}
+	if age < 0 {
+		return types.Student{}, fmt.Errorf("age: %d is negative", age)
+	}
+
+	return types.Student{Name: name, Email: email, Age: age}, nil
+}
+
+func (m *Mapping) value(rec record, field string, now time.Time) (string, error) {
+	src := m.Fields[field]
+
+	v, err := extract(rec, src, now)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", field, err)
+	}
+
+	return v, nil
+}
+
+func extract(rec record, src Source, now time.Time) (string, error) {
+	var v string
+	if len(src.Parts) > 0 {
+		var parts []string
+		for _, part := range src.Parts {
+			p, err := extract(rec, part, now)
+			if err != nil {
+				return "", err
+			}
+			if p != "" {
+				parts = append(parts, p)
+			}
+		}
+		v = strings.Join(parts, " ")
+	} else if src.Path != "" || src.Length > 0 {
+		v = strings.TrimSpace(rec.get(src))
+	}
+
+	if v == "" {
+		v = src.Default
+	}
+
+	if src.BirthDate != "" && v != "" {
+		born, err := time.Parse(src.BirthDate, v)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a date like %s", v, src.BirthDate)
+		}
+		return strconv.Itoa(ageOn(born, now)), nil
+	}
+
+	return v, nil
+}
+
+// ageOn is the age in completed years of someone born on born.
+func ageOn(born, now time.Time) int {
+	age := now.Year() - born.Year()
+	if now.Month() < born.Month() || (now.Month() == born.Month() && now.Day() < born.Day()) {
+		age--
+	}
+
+	return age
+}
@@ -0,0 +1,125 @@
+package legacyimport
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatFixedWidth = "fixed-width"
+	FormatXML        = "xml"
+)
+
+// fields every mapping has to provide
+var required = []string{"name", "email", "age"}
+
+// Mapping describes where the student fields are found in a legacy export.
+type Mapping struct {
+	Format string `yaml:"format"`
+
+	// fixed-width: header lines to skip before the first record
+	SkipLines int `yaml:"skip_lines"`
+
+	// xml: name of the element that holds one record
+	Record string `yaml:"record"`
+
+	Fields map[string]Source `yaml:"fields"`
+}
+
+// Source locates one field.
+type Source struct {
+	// fixed-width: 1-based first column and width
+	Start  int `yaml:"start"`
+	Length int `yaml:"length"`
+
+	// xml: child element path below the record ("Contact/Email") or an
+	// attribute ("@id", "Contact/@type")
+	Path string `yaml:"path"`
+
+	// Parts are joined with a space, e.g. first and last name columns.
+	Parts []Source `yaml:"parts"`
+
+	// Default is used when the value is empty.
+	Default string `yaml:"default"`
+
+	// BirthDate is a Go time layout ("2006-01-02", "01/02/2006"); the value
+	// is a date of birth and the field becomes the age on the import day.
+	BirthDate string `yaml:"birth_date"`
+}
+
+// LoadMapping reads and checks a mapping file.
+func LoadMapping(path string) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Mapping
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse mapping: %w", err)
+	}
+
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid mapping %s: %w", path, err)
+	}
+
+	return &m, nil
+}
+
+func (m *Mapping) validate() error {
+	var errs []error
+
+	switch m.Format {
+	case FormatFixedWidth:
+	case FormatXML:
+		if m.Record == "" {
+			errs = append(errs, errors.New("record: is required for xml"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("format: %q is not fixed-width or xml", m.Format))
+	}
+
+	for _, name := range required {
+		if _, ok := m.Fields[name]; !ok {
+			errs = append(errs, fmt.Errorf("fields.%s: is required", name))
+		}
+	}
+
+	for name, src := range m.Fields {
+		if err := m.validateSource(src); err != nil {
+			errs = append(errs, fmt.Errorf("fields.%s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Mapping) validateSource(src Source) error {
+	if src.BirthDate != "" {
+		if _, err := time.Parse(src.BirthDate, time.Now().Format(src.BirthDate)); err != nil {
+			return fmt.Errorf("birth_date: invalid layout %q", src.BirthDate)
+		}
+	}
+
+	if len(src.Parts) > 0 {
+		for _, part := range src.Parts {
+			if err := m.validateSource(part); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if m.Format == FormatFixedWidth && (src.Start < 1 || src.Length < 1) && src.Default == "" {
+		return errors.New("start and length must be at least 1")
+	}
+	if m.Format == FormatXML && src.Path == "" && src.Default == "" {
+		return errors.New("path is required")
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package legacyimport
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// record is one entry of an export; get returns the raw text of a source.
+type record struct {
+	line int
+	get  func(Source) string
+}
+
+// readFixedWidth calls fn for every non-blank line after the skipped header.
+// Columns count characters, not bytes.
+func readFixedWidth(r io.Reader, m *Mapping, fn func(record) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := 0
+	for sc.Scan() {
+		line++
+		if line <= m.SkipLines {
+			continue
+		}
+
+		text := []rune(strings.TrimRight(sc.Text(), "\r"))
+		if strings.TrimSpace(string(text)) == "" {
+			continue
+		}
+
+		err := fn(record{line: line, get: func(src Source) string {
+			start := src.Start - 1
+			if start >= len(text) {
+				return ""
+			}
+			end := min(start+src.Length, len(text))
+			return string(text[start:end])
+		}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return sc.Err()
+}
+
+// node is a generic XML element.
+type node struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Children []node     `xml:",any"`
+}
+
+// find resolves a path like "Contact/Email" or "Contact/@type".
+func (n *node) find(path string) string {
+	cur := n
+	parts := strings.Split(path, "/")
+
+	for i, part := range parts {
+		if attr, ok := strings.CutPrefix(part, "@"); ok && i == len(parts)-1 {
+			for _, a := range cur.Attrs {
+				if a.Name.Local == attr {
+					return a.Value
+				}
+			}
+			return ""
+		}
+
+		var next *node
+		for j := range cur.Children {
+			if cur.Children[j].XMLName.Local == part {
+				next = &cur.Children[j]
+				break
+			}
+		}
+		if next == nil {
+			return ""
+		}
+		cur = next
+	}
+
+	return cur.Text
+}
+
+// readXML calls fn for every element named m.Record, wherever it is nested.
+func readXML(r io.Reader, m *Mapping, fn func(record) error) error {
+	dec := xml.NewDecoder(r)
+	// legacy exports are often declared as latin-1 or windows-1252; only
+	// ASCII-compatible content is read correctly without a real decoder
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read xml: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != m.Record {
+			continue
+		}
+
+		line, _ := dec.InputPos()
+
+		var n node
+		if err := dec.DecodeElement(&n, &start); err != nil {
+			return fmt.Errorf("read xml record at line %d: %w", line, err)
+		}
+
+		if err := fn(record{line: line, get: func(src Source) string { return n.find(src.Path) }}); err != nil {
+			return err
+		}
+	}
+}
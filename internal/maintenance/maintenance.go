@@ -0,0 +1,46 @@
+package maintenance
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Mode is the runtime read-only switch. While enabled, mutating requests
+// are rejected and reads keep working.
+type Mode struct {
+	enabled    atomic.Bool
+	retryAfter atomic.Int64
+}
+
+func New(retryAfter time.Duration) *Mode {
+	m := &Mode{}
+	m.SetRetryAfter(retryAfter)
+	return m
+}
+
+func (m *Mode) Enabled() bool {
+	return m.enabled.Load()
+}
+
+func (m *Mode) Set(enabled bool) {
+	m.enabled.Store(enabled)
+}
+
+// Toggle flips the mode and returns the new state.
+func (m *Mode) Toggle() bool {
+	for {
+		old := m.enabled.Load()
+		if m.enabled.CompareAndSwap(old, !old) {
+			return !old
+		}
+	}
+}
+
+// RetryAfter is the hint sent to clients in the Retry-After header.
+func (m *Mode) RetryAfter() time.Duration {
+	return time.Duration(m.retryAfter.Load())
+}
+
+func (m *Mode) SetRetryAfter(d time.Duration) {
+	m.retryAfter.Store(int64(d))
+}
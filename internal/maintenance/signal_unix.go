@@ -0,0 +1,11 @@
+//go:build !windows
+
+package maintenance
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signal toggles maintenance mode on a running process.
+var Signal os.Signal = syscall.SIGUSR1
@@ -0,0 +1,8 @@
+//go:build windows
+
+package maintenance
+
+import "os"
+
+// Signal is nil on windows; use the admin endpoint instead.
+var Signal os.Signal
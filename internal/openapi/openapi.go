@@ -0,0 +1,376 @@
+// Package openapi checks requests against the served OpenAPI document, so
+// the paths, parameters and bodies it describes are what the API accepts.
+// It understands the part of OpenAPI 3 the document uses: local $refs,
+// path, query and header parameters, JSON request bodies, and schemas with
+// type, format, nullable, required, properties, items, enum, minimum and
+// maximum.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+)
+
+type parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Ref      string `json:"$ref"`
+	Required bool   `json:"required"`
+	Content  map[string]struct {
+		Schema *schema `json:"schema"`
+	} `json:"content"`
+}
+
+type operation struct {
+	Parameters  []parameter  `json:"parameters"`
+	RequestBody *requestBody `json:"requestBody"`
+}
+
+type document struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas       map[string]*schema     `json:"schemas"`
+		Parameters    map[string]parameter   `json:"parameters"`
+		RequestBodies map[string]requestBody `json:"requestBodies"`
+	} `json:"components"`
+}
+
+// route is a path of the document; segments in braces are parameters.
+type route struct {
+	segments   []string
+	literals   int
+	operations map[string]*operation
+}
+
+var methods = map[string]string{
+	"get": http.MethodGet, "put": http.MethodPut, "post": http.MethodPost, "delete": http.MethodDelete,
+	"patch": http.MethodPatch, "head": http.MethodHead, "options": http.MethodOptions,
+}
+
+// Validator checks requests against an OpenAPI document.
+type Validator struct {
+	routes []route
+}
+
+// New returns a validator for the JSON OpenAPI document spec.
+func New(spec []byte) (*Validator, error) {
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("decode openapi document: %w", err)
+	}
+
+	v := &Validator{}
+	r := resolver{doc: &doc, seen: map[*schema]bool{}}
+
+	for path, item := range doc.Paths {
+		var shared []parameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		rt := route{segments: strings.Split(path, "/"), operations: map[string]*operation{}}
+		for _, seg := range rt.segments {
+			if !isParam(seg) {
+				rt.literals++
+			}
+		}
+
+		for name, raw := range item {
+			method, ok := methods[name]
+			if !ok {
+				continue
+			}
+
+			op := &operation{}
+			if err := json.Unmarshal(raw, op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", name, path, err)
+			}
+			if err := r.operation(op, shared); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", name, path, err)
+			}
+			rt.operations[method] = op
+		}
+
+		v.routes = append(v.routes, rt)
+	}
+
+	return v, nil
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// match returns the operation for a request and its path parameters. Like
+// ServeMux, a literal segment wins over a parameter.
+func (v *Validator) match(method, path string) (*operation, map[string]string) {
+	segments := strings.Split(path, "/")
+
+	var best *route
+	for i := range v.routes {
+		rt := &v.routes[i]
+		if len(rt.segments) != len(segments) || rt.operations[method] == nil {
+			continue
+		}
+		if best != nil && best.literals >= rt.literals {
+			continue
+		}
+
+		matched := true
+		for j, seg := range rt.segments {
+			if isParam(seg) && segments[j] == "" || !isParam(seg) && seg != segments[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			best = rt
+		}
+	}
+
+	if best == nil {
+		return nil, nil
+	}
+
+	values := map[string]string{}
+	for j, seg := range best.segments {
+		if isParam(seg) {
+			values[strings.Trim(seg, "{}")] = segments[j]
+		}
+	}
+
+	return best.operations[method], values
+}
+
+// Handler rejects requests to operations of the document whose parameters
+// or JSON body don't match it with a validation problem. Requests to paths
+// or methods it doesn't describe are passed on unchecked.
+func (v *Validator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.check(r); err != nil {
+			handlers.WriteError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (v *Validator) check(r *http.Request) error {
+	op, pathValues := v.match(r.Method, r.URL.Path)
+	if op == nil {
+		return nil
+	}
+
+	errs := &handlers.InvalidFields{}
+
+	for _, p := range op.Parameters {
+		var value string
+		var present bool
+
+		switch p.In {
+		case "path":
+			value, present = pathValues[p.Name]
+		case "query":
+			present = r.URL.Query().Has(p.Name)
+			value = r.URL.Query().Get(p.Name)
+		case "header":
+			value = r.Header.Get(p.Name)
+			present = value != ""
+		default:
+			continue
+		}
+
+		field := p.In + "." + p.Name
+		if !present {
+			if p.Required {
+				errs.Add(field, "required", "field %s is required field", field)
+			}
+			continue
+		}
+
+		p.Schema.check(errs, field, p.Schema.parse(value))
+	}
+
+	if op.RequestBody != nil {
+		if err := checkBody(r, op.RequestBody, errs); err != nil {
+			return err
+		}
+	}
+
+	if errs.Len() > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// checkBody checks a JSON body and leaves it for the handler to read.
+func checkBody(r *http.Request, body *requestBody, errs *handlers.InvalidFields) error {
+	content, ok := body.Content["application/json"]
+	if !ok || content.Schema == nil {
+		return nil
+	}
+
+	if r.Header.Get("Content-Type") != "" {
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+			return nil
+		}
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return handlers.JSONError(err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if body.Required {
+			return handlers.Errorf(http.StatusBadRequest, "empty body")
+		}
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return handlers.JSONError(err)
+	}
+
+	content.Schema.check(errs, "", value)
+
+	return nil
+}
+
+// resolver replaces the $refs of a document by what they point to.
+type resolver struct {
+	doc  *document
+	seen map[*schema]bool
+}
+
+func (r resolver) operation(op *operation, shared []parameter) error {
+	var params []parameter
+	for _, p := range shared {
+		resolved, err := r.parameter(p)
+		if err != nil {
+			return err
+		}
+		params = append(params, resolved)
+	}
+
+	for _, p := range op.Parameters {
+		resolved, err := r.parameter(p)
+		if err != nil {
+			return err
+		}
+
+		// an operation's parameter overrides the path's one
+		replaced := false
+		for i, q := range params {
+			if q.In == resolved.In && q.Name == resolved.Name {
+				params[i], replaced = resolved, true
+			}
+		}
+		if !replaced {
+			params = append(params, resolved)
+		}
+	}
+	op.Parameters = params
+
+	if op.RequestBody != nil {
+		body := *op.RequestBody
+		if body.Ref != "" {
+			name, ok := strings.CutPrefix(body.Ref, "#/components/requestBodies/")
+			found, exists := r.doc.Components.RequestBodies[name]
+			if !ok || !exists {
+				return fmt.Errorf("unknown request body %s", body.Ref)
+			}
+			body = found
+		}
+
+		for mediaType, content := range body.Content {
+			s, err := r.schema(content.Schema)
+			if err != nil {
+				return err
+			}
+			content.Schema = s
+			body.Content[mediaType] = content
+		}
+		op.RequestBody = &body
+	}
+
+	return nil
+}
+
+func (r resolver) parameter(p parameter) (parameter, error) {
+	if p.Ref != "" {
+		name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+		found, exists := r.doc.Components.Parameters[name]
+		if !ok || !exists {
+			return parameter{}, fmt.Errorf("unknown parameter %s", p.Ref)
+		}
+		p = found
+	}
+
+	s, err := r.schema(p.Schema)
+	if err != nil {
+		return parameter{}, err
+	}
+	p.Schema = s
+
+	return p, nil
+}
+
+func (r resolver) schema(s *schema) (*schema, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		found, exists := r.doc.Components.Schemas[name]
+		if !ok || !exists {
+			return nil, fmt.Errorf("unknown schema %s", s.Ref)
+		}
+		s = found
+	}
+
+	// schemas can refer to themselves
+	if r.seen[s] {
+		return s, nil
+	}
+	r.seen[s] = true
+
+	for name, prop := range s.Properties {
+		resolved, err := r.schema(prop)
+		if err != nil {
+			return nil, err
+		}
+		s.Properties[name] = resolved
+	}
+
+	items, err := r.schema(s.Items)
+	if err != nil {
+		return nil, err
+	}
+	s.Items = items
+
+	return s, nil
+}
@@ -0,0 +1,168 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/http/handlers"
+)
+
+type schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Nullable   bool               `json:"nullable"`
+	Required   []string           `json:"required"`
+	Properties map[string]*schema `json:"properties"`
+	Items      *schema            `json:"items"`
+	Enum       []any              `json:"enum"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+}
+
+// parse turns the text of a parameter into the JSON value it stands for,
+// leaving it a string if it isn't one of the schema's type.
+func (s *schema) parse(text string) any {
+	if s == nil {
+		return text
+	}
+
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(text, 64); err == nil {
+			return json.Number(text)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(text); err == nil {
+			return b
+		}
+	}
+
+	return text
+}
+
+// check adds the ways value, decoded with json.Decoder.UseNumber, doesn't
+// match s to errs.
+func (s *schema) check(errs *handlers.InvalidFields, field string, value any) {
+	if s == nil {
+		return
+	}
+
+	if value == nil {
+		if !s.Nullable && s.Type != "" {
+			addTypeError(errs, field, s.Type)
+		}
+		return
+	}
+
+	if !s.checkType(errs, field, value) {
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		allowed := make([]string, len(s.Enum))
+		found := false
+		for i, e := range s.Enum {
+			allowed[i] = fmt.Sprint(e)
+			found = found || allowed[i] == fmt.Sprint(value)
+		}
+		if !found {
+			errs.Add(field, "enum", "field %s must be one of %s", field, strings.Join(allowed, ", "))
+		}
+	}
+
+	if n, ok := value.(json.Number); ok {
+		f, _ := n.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			errs.Add(field, "minimum", "field %s must be at least %v", field, *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			errs.Add(field, "maximum", "field %s must be at most %v", field, *s.Maximum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				errs.Add(join(field, name), "required", "field %s is required field", join(field, name))
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+			if pv, ok := v[name]; ok {
+				s.Properties[name].check(errs, join(field, name), pv)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			s.Items.check(errs, fmt.Sprintf("%s[%d]", field, i), item)
+		}
+	}
+}
+
+// checkType reports whether value is of the schema's type, adding an error
+// if it isn't.
+func (s *schema) checkType(errs *handlers.InvalidFields, field string, value any) bool {
+	ok := true
+
+	switch s.Type {
+	case "object":
+		_, ok = value.(map[string]any)
+	case "array":
+		_, ok = value.([]any)
+	case "string":
+		_, ok = value.(string)
+	case "boolean":
+		_, ok = value.(bool)
+	case "number":
+		_, ok = value.(json.Number)
+	case "integer":
+		var n json.Number
+		if n, ok = value.(json.Number); ok {
+			ok = isInteger(n, s.Format)
+		}
+	}
+
+	if !ok {
+		addTypeError(errs, field, s.Type)
+	}
+
+	return ok
+}
+
+// addTypeError adds that field isn't of type typ; an empty field is the
+// whole body.
+func addTypeError(errs *handlers.InvalidFields, field, typ string) {
+	if field == "" {
+		errs.Add(field, "type", "body must be of type %s", typ)
+		return
+	}
+
+	errs.Add(field, "type", "field %s must be of type %s", field, typ)
+}
+
+func isInteger(n json.Number, format string) bool {
+	i, err := n.Int64()
+	if err != nil {
+		return false
+	}
+
+	if format == "int32" {
+		return i >= math.MinInt32 && i <= math.MaxInt32
+	}
+
+	return true
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+
+	return field + "." + name
+}
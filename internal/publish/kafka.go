@@ -0,0 +1,40 @@
+package publish
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/events"
+	"github.com/segmentio/kafka-go"
+)
+
+type kafkaSink struct {
+	w *kafka.Writer
+}
+
+func newKafka(brokers []string, topic string) *kafkaSink {
+	for i := range brokers {
+		brokers[i] = strings.TrimSpace(brokers[i])
+	}
+
+	return &kafkaSink{w: &kafka.Writer{
+		Addr:  kafka.TCP(brokers...),
+		Topic: topic,
+		// the same student always lands on the same partition, keeping its events in order
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}}
+}
+
+func (k *kafkaSink) Send(ctx context.Context, _ events.Type, key string, headers map[string]string, value []byte) error {
+	msg := kafka.Message{Key: []byte(key), Value: value}
+	for name, v := range headers {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: name, Value: []byte(v)})
+	}
+
+	return k.w.WriteMessages(ctx, msg)
+}
+
+func (k *kafkaSink) Close() error {
+	return k.w.Close()
+}
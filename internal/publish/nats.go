@@ -0,0 +1,43 @@
+package publish
+
+import (
+	"context"
+
+	"github.com/cmanish049/students-api/internal/events"
+	"github.com/nats-io/nats.go"
+)
+
+type natsSink struct {
+	conn    *nats.Conn
+	subject string
+}
+
+func newNats(url, subject string) (*natsSink, error) {
+	// keep starting up while the server is unreachable; messages are
+	// buffered by the client until it reconnects
+	conn, err := nats.Connect(url,
+		nats.Name("students-api"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &natsSink{conn: conn, subject: subject}, nil
+}
+
+func (n *natsSink) Send(_ context.Context, t events.Type, _ string, headers map[string]string, value []byte) error {
+	msg := nats.NewMsg(n.subject + "." + string(t))
+	msg.Data = value
+	for name, v := range headers {
+		msg.Header.Set(name, v)
+	}
+
+	return n.conn.PublishMsg(msg)
+}
+
+func (n *natsSink) Close() error {
+	// Drain flushes pending messages before closing
+	return n.conn.Drain()
+}
@@ -0,0 +1,169 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/events"
+)
+
+// SchemaVersion is bumped whenever Message changes incompatibly. Consumers
+// should check it (or the schema header) before decoding data.
+const (
+	SchemaVersion = 1
+	Schema        = "students-api.event.v1"
+)
+
+// sendTimeout bounds a single broker write
+const sendTimeout = 10 * time.Second
+
+// Message is the payload published for every event.
+type Message struct {
+	Schema        string      `json:"schema"`
+	SchemaVersion int         `json:"schema_version"`
+	Id            int64       `json:"id"`
+	Type          events.Type `json:"type"`
+	Time          time.Time   `json:"time"`
+	Tenant        string      `json:"tenant"`
+	Data          any         `json:"data"`
+}
+
+// sink is a message broker connection.
+type sink interface {
+	// Send publishes value. key groups messages that must stay in order
+	// (the student id).
+	Send(ctx context.Context, t events.Type, key string, headers map[string]string, value []byte) error
+	Close() error
+}
+
+// Publisher forwards events to Kafka or NATS. Events are queued and sent in
+// order by a single worker so a slow broker never holds up a request; when
+// the queue is full new events are dropped and logged.
+type Publisher struct {
+	sink  sink
+	queue chan events.Event
+	done  chan struct{}
+
+	// mu guards closed and the queue being closed, as jobs still running
+	// at shutdown can publish after Close
+	mu     sync.Mutex
+	closed bool
+}
+
+// New connects to the broker. backend is "kafka" (addr is a comma separated
+// broker list, topic the Kafka topic) or "nats" (addr is the server URL(s),
+// events go to the subject "<topic>.<event type>").
+func New(backend, addr, topic string, bufferSize int) (*Publisher, error) {
+	var s sink
+	var err error
+
+	switch backend {
+	case "kafka":
+		s = newKafka(strings.Split(addr, ","), topic)
+	case "nats":
+		s, err = newNats(addr, topic)
+	default:
+		err = fmt.Errorf("unknown publisher backend %q", backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Publisher{
+		sink:  s,
+		queue: make(chan events.Event, bufferSize),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+
+	return p, nil
+}
+
+// Handle is an events.Bus subscriber. Events after Close are dropped and
+// logged.
+func (p *Publisher) Handle(e events.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		slog.Warn("publisher closed, dropping event", slog.Int64("id", e.Id), slog.String("type", string(e.Type)))
+		return
+	}
+
+	select {
+	case p.queue <- e:
+	default:
+		slog.Error("publisher queue full, dropping event", slog.Int64("id", e.Id), slog.String("type", string(e.Type)))
+	}
+}
+
+// Close sends what is still queued, waiting until ctx is done, and closes
+// the broker connection.
+func (p *Publisher) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.queue)
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		slog.Warn("publisher closed with events still queued", slog.Int("queued", len(p.queue)))
+	}
+
+	return p.sink.Close()
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+
+	for e := range p.queue {
+		p.send(e)
+	}
+}
+
+func (p *Publisher) send(e events.Event) {
+	value, err := json.Marshal(Message{
+		Schema:        Schema,
+		SchemaVersion: SchemaVersion,
+		Id:            e.Id,
+		Type:          e.Type,
+		Time:          e.Time,
+		Tenant:        e.Tenant,
+		Data:          e.Data,
+	})
+	if err != nil {
+		slog.Error("failed to encode event", slog.String("error", err.Error()))
+		return
+	}
+
+	var key string
+	if id, ok := e.StudentId(); ok {
+		key = strconv.FormatInt(id, 10)
+	}
+
+	headers := map[string]string{
+		"content-type": "application/json",
+		"schema":       Schema,
+		"event-type":   string(e.Type),
+		"event-id":     strconv.FormatInt(e.Id, 10),
+		"tenant":       e.Tenant,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	if err := p.sink.Send(ctx, e.Type, key, headers, value); err != nil {
+		slog.Error("failed to publish event", slog.Int64("id", e.Id), slog.String("type", string(e.Type)), slog.String("error", err.Error()))
+	}
+}
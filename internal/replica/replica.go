@@ -0,0 +1,398 @@
+// Package replica copies the databases to a blob store as they change, so
+// a single node can be recovered after losing its disk, and restores them
+// as they were at a point in time.
+//
+// Each pass takes a consistent snapshot of every database and uploads it,
+// compressed, unless it is the same as the last one uploaded. Snapshots
+// are named after the time they were taken, so recovering to a time picks
+// the newest snapshot taken at or before it: at most the changes made
+// during one interval are lost.
+package replica
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/blob"
+	"github.com/cmanish049/students-api/internal/storage/sqlite"
+	"github.com/cmanish049/students-api/internal/storage/tenantdb"
+	"github.com/cmanish049/students-api/internal/tenant"
+)
+
+// timeFormat names the snapshots; it sorts in the order they were taken
+const timeFormat = "20060102T150405.000Z"
+
+// suffix ends the keys of snapshots, which are gzipped databases
+const suffix = ".db.gz"
+
+// Status is the replication state of one database, shown on the admin
+// listener.
+type Status struct {
+	// Tenant is empty for the main database
+	Tenant     string    `json:"tenant,omitempty"`
+	Key        string    `json:"key,omitempty"`
+	Size       int64     `json:"size,omitempty"`
+	SnapshotAt time.Time `json:"snapshot_at,omitzero"`
+	CheckedAt  time.Time `json:"checked_at,omitzero"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Replicator uploads snapshots of the databases of a Manager to a store.
+type Replicator struct {
+	databases *tenantdb.Manager
+	store     blob.Store
+	prefix    string
+	retention time.Duration
+
+	// pass keeps a pass from overlapping the next, or the one at shutdown
+	pass sync.Mutex
+
+	mu     sync.Mutex
+	status map[string]*Status
+	hashes map[string][sha256.Size]byte
+}
+
+// New returns a Replicator uploading under prefix. Snapshots older than
+// retention are deleted, except the newest of them, which is what the
+// database looked like when the retention period began; 0 keeps them all.
+func New(databases *tenantdb.Manager, store blob.Store, prefix string, retention time.Duration) *Replicator {
+	return &Replicator{
+		databases: databases,
+		store:     store,
+		prefix:    strings.Trim(prefix, "/"),
+		retention: retention,
+		status:    map[string]*Status{},
+		hashes:    map[string][sha256.Size]byte{},
+	}
+}
+
+// Run replicates now and every interval until ctx is done.
+func (r *Replicator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Replicate(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("database replication failed", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Replicate uploads a snapshot of every database that changed since the
+// last one. A database that fails doesn't hold up the others.
+func (r *Replicator) Replicate(ctx context.Context) error {
+	r.pass.Lock()
+	defer r.pass.Unlock()
+
+	err := r.replicate(ctx, "", r.databases.Main())
+
+	for _, id := range r.databases.Tenants() {
+		db, dbErr := r.databases.For(tenant.With(ctx, id))
+		if dbErr == nil {
+			dbErr = r.replicate(ctx, id, db)
+		} else {
+			r.setStatus(id, func(s *Status) { s.CheckedAt, s.Error = time.Now(), dbErr.Error() })
+		}
+		err = errors.Join(err, dbErr)
+	}
+
+	return err
+}
+
+// Status returns the state of every database, the main one first.
+func (r *Replicator) Status() []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]Status, 0, len(r.status))
+	if s, ok := r.status[""]; ok {
+		list = append(list, *s)
+	}
+	for _, id := range r.databases.Tenants() {
+		if s, ok := r.status[id]; ok {
+			list = append(list, *s)
+		}
+	}
+
+	return list
+}
+
+func (r *Replicator) setStatus(id string, update func(*Status)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.status[id]
+	if !ok {
+		s = &Status{Tenant: id}
+		r.status[id] = s
+	}
+	update(s)
+}
+
+// replicate uploads a snapshot of db, the database of tenant id or the
+// main one if id is empty, unless it didn't change.
+func (r *Replicator) replicate(ctx context.Context, id string, db *sqlite.Sqlite) error {
+	now := time.Now().UTC()
+
+	snapshot, err := r.upload(ctx, id, db, now)
+	if err == nil && snapshot.Key != "" {
+		err = r.prune(ctx, id, now)
+	}
+
+	r.setStatus(id, func(s *Status) {
+		s.CheckedAt, s.Error = now, ""
+		if err != nil {
+			s.Error = err.Error()
+		}
+		if snapshot.Key != "" {
+			s.Key, s.Size, s.SnapshotAt = snapshot.Key, snapshot.Size, snapshot.Time
+		}
+	})
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", name(id), err)
+	}
+
+	return nil
+}
+
+// upload writes a snapshot of db to the store and returns it, or a zero
+// Snapshot if it is the same as the last one uploaded.
+func (r *Replicator) upload(ctx context.Context, id string, db *sqlite.Sqlite, now time.Time) (Snapshot, error) {
+	dir, err := os.MkdirTemp("", "students-api-replica-")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	raw := filepath.Join(dir, "snapshot.db")
+	if err := db.Snapshot(ctx, raw); err != nil {
+		return Snapshot{}, fmt.Errorf("snapshot: %w", err)
+	}
+
+	hash, size, err := compress(raw, raw+".gz")
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	r.mu.Lock()
+	unchanged := r.hashes[id] == hash
+	r.mu.Unlock()
+	if unchanged {
+		return Snapshot{}, nil
+	}
+
+	f, err := os.Open(raw + ".gz")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer f.Close()
+
+	s := Snapshot{Key: Dir(r.prefix, id) + "/" + now.Format(timeFormat) + suffix, Time: now, Size: size}
+	if err := r.store.Put(ctx, s.Key, "application/gzip", size, f); err != nil {
+		return Snapshot{}, fmt.Errorf("upload: %w", err)
+	}
+
+	r.mu.Lock()
+	r.hashes[id] = hash
+	r.mu.Unlock()
+
+	slog.Info("database replicated", slog.String("database", name(id)), slog.String("key", s.Key), slog.Int64("size", size))
+
+	return s, nil
+}
+
+// compress gzips the file at src to dst, returning the hash of src and the
+// size of dst.
+func compress(src, dst string) ([sha256.Size]byte, int64, error) {
+	var hash [sha256.Size]byte
+
+	in, err := os.Open(src)
+	if err != nil {
+		return hash, 0, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return hash, 0, err
+	}
+	defer out.Close()
+
+	h := sha256.New()
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, io.TeeReader(in, h)); err != nil {
+		return hash, 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return hash, 0, err
+	}
+
+	info, err := out.Stat()
+	if err != nil {
+		return hash, 0, err
+	}
+	h.Sum(hash[:0])
+
+	return hash, info.Size(), nil
+}
+
+// prune deletes the snapshots of tenant id that are past the retention.
+func (r *Replicator) prune(ctx context.Context, id string, now time.Time) error {
+	if r.retention == 0 {
+		return nil
+	}
+
+	snapshots, err := Snapshots(ctx, r.store, r.prefix, id)
+	if err != nil {
+		return err
+	}
+
+	// the newest snapshot before the cutoff is the state at the cutoff
+	cutoff := now.Add(-r.retention)
+	for i := 0; i+1 < len(snapshots) && !snapshots[i+1].Time.After(cutoff); i++ {
+		if err := r.store.Delete(ctx, snapshots[i].Key); err != nil {
+			return err
+		}
+		slog.Info("old database snapshot removed", slog.String("database", name(id)), slog.String("key", snapshots[i].Key))
+	}
+
+	return nil
+}
+
+// Snapshot is a snapshot of a database in the store.
+type Snapshot struct {
+	Key  string
+	Time time.Time
+	Size int64
+}
+
+// Dir is where the snapshots of the database of tenant id go under prefix:
+// prefix/main for the main database and prefix/tenants/<id> for the
+// others.
+func Dir(prefix, id string) string {
+	if id == "" {
+		return path.Join(prefix, "main")
+	}
+
+	return path.Join(prefix, "tenants", id)
+}
+
+// Snapshots returns the snapshots of the database of tenant id, or of the
+// main one if id is empty, oldest first.
+func Snapshots(ctx context.Context, store blob.Store, prefix, id string) ([]Snapshot, error) {
+	dir := Dir(strings.Trim(prefix, "/"), id) + "/"
+
+	objects, err := store.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	for _, o := range objects {
+		name, ok := strings.CutSuffix(strings.TrimPrefix(o.Key, dir), suffix)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(timeFormat, name)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{Key: o.Key, Time: t, Size: o.Size})
+	}
+
+	return snapshots, nil
+}
+
+// At returns the newest of snapshots taken at or before t.
+func At(snapshots []Snapshot, t time.Time) (Snapshot, bool) {
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if !snapshots[i].Time.After(t) {
+			return snapshots[i], true
+		}
+	}
+
+	return Snapshot{}, false
+}
+
+// Restore downloads s to the database at path, replacing any file there.
+// The download is checked before it takes the place of the database, so
+// a failed restore leaves it as it was. The database must not be in use.
+func Restore(ctx context.Context, store blob.Store, s Snapshot, path string) error {
+	body, err := store.Get(ctx, s.Key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".recover-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zr, err := gzip.NewReader(body)
+	if err != nil {
+		return fmt.Errorf("%s is not a snapshot: %w", s.Key, err)
+	}
+	if _, err := io.Copy(tmp, zr); err != nil {
+		return fmt.Errorf("download %s: %w", s.Key, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := check(ctx, tmp.Name()); err != nil {
+		return fmt.Errorf("%s: %w", s.Key, err)
+	}
+
+	// a journal left by the lost database would be rolled back into this one
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// check opens the database at path and runs its integrity check.
+func check(ctx context.Context, path string) error {
+	db, err := sqlite.Open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+
+	return db.Verify(ctx)
+}
+
+// name is how logs call the database of tenant id.
+func name(id string) string {
+	if id == "" {
+		return "main"
+	}
+
+	return "tenant " + id
+}
@@ -0,0 +1,63 @@
+// Package requestid carries the id of a request, so its responses and logs
+// can be matched to it. Clients may send their own id; otherwise one is
+// generated.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Header carries the id, on requests and their responses.
+const Header = "X-Request-Id"
+
+// maxLen limits the ids taken from clients
+const maxLen = 128
+
+type key struct{}
+
+// With returns a context carrying id.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// From returns the id carried by ctx, empty if none.
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
+
+// FromRequest returns the id sent with r if it is usable, a new one if not.
+func FromRequest(r *http.Request) string {
+	if id := r.Header.Get(Header); valid(id) {
+		return id
+	}
+
+	return New()
+}
+
+// New returns a random id.
+func New() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// valid accepts printable ASCII ids of reasonable length, which are safe
+// to echo in a header and to log.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,68 @@
+package retention
+
+import (
+	"context"
+	"expvar"
+	"log/slog"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// purged counts what retention removed since the process started; it is
+// served with the other expvars on the admin listener.
+var purged = expvar.NewMap("retention_purged")
+
+// Store is the part of the storage retention cleans up.
+type Store interface {
+	// PurgeJobs removes jobs in status that finished before before.
+	PurgeJobs(ctx context.Context, status string, before time.Time) (int64, error)
+	PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Policy says how long finished data is kept. Zero keeps it forever.
+type Policy struct {
+	Jobs              time.Duration
+	DeadJobs          time.Duration
+	WebhookDeliveries time.Duration
+}
+
+// Result is the number of rows purged, by kind of data.
+type Result map[string]int64
+
+// Purge removes everything older than the policy allows.
+func Purge(ctx context.Context, store Store, policy Policy, now time.Time) (Result, error) {
+	res := Result{}
+
+	steps := []struct {
+		name  string
+		keep  time.Duration
+		purge func(time.Time) (int64, error)
+	}{
+		{"jobs", policy.Jobs, func(before time.Time) (int64, error) { return store.PurgeJobs(ctx, types.JobDone, before) }},
+		{"dead_jobs", policy.DeadJobs, func(before time.Time) (int64, error) { return store.PurgeJobs(ctx, types.JobDead, before) }},
+		{"webhook_deliveries", policy.WebhookDeliveries, func(before time.Time) (int64, error) { return store.PurgeWebhookDeliveries(ctx, before) }},
+	}
+
+	var attrs []any
+	for _, step := range steps {
+		if step.keep <= 0 {
+			continue
+		}
+
+		n, err := step.purge(now.Add(-step.keep))
+		if err != nil {
+			return res, err
+		}
+
+		res[step.name] = n
+		purged.Add(step.name, n)
+		attrs = append(attrs, slog.Int64(step.name, n))
+	}
+
+	purged.Add("runs", 1)
+
+	slog.Info("retention purge finished", attrs...)
+
+	return res, nil
+}
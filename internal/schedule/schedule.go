@@ -0,0 +1,236 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Task is one run of a scheduled piece of work. It should return when ctx
+// is cancelled.
+type Task func(ctx context.Context) error
+
+// Status is what is known about a schedule and its most recent run.
+type Status struct {
+	Name      string    `json:"name"`
+	Task      string    `json:"task"`
+	Schedule  string    `json:"schedule"`
+	Running   bool      `json:"running"`
+	NextRun   time.Time `json:"next_run"`
+	LastStart time.Time `json:"last_start,omitzero"`
+	LastEnd   time.Time `json:"last_end,omitzero"`
+	LastError string    `json:"last_error,omitempty"`
+	Runs      int       `json:"runs"`
+	Failures  int       `json:"failures"`
+	// Skipped counts runs that were due while the previous one was still going
+	Skipped int `json:"skipped"`
+}
+
+type entry struct {
+	schedule cron.Schedule
+	task     Task
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Scheduler runs tasks on cron schedules. A run that is due while the
+// previous run of the same schedule hasn't finished is skipped, so slow
+// tasks never pile up.
+type Scheduler struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	order   []string
+
+	ctx     context.Context
+	stop    context.CancelFunc
+	running sync.WaitGroup
+}
+
+func New() *Scheduler {
+	ctx, stop := context.WithCancel(context.Background())
+
+	return &Scheduler{
+		entries: map[string]*entry{},
+		ctx:     ctx,
+		stop:    stop,
+	}
+}
+
+// Parse parses a standard five-field cron expression, or a descriptor such
+// as "@daily" or "@every 1h30m". Times are in the server's local time zone
+// unless the spec starts with "CRON_TZ=<zone> ".
+func Parse(spec string) (cron.Schedule, error) {
+	return cron.ParseStandard(spec)
+}
+
+// Add registers a schedule; name identifies it in the status and logs.
+func (s *Scheduler) Add(name, taskName, spec string, task Task) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("schedule %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[name]; ok {
+		return fmt.Errorf("schedule %s is defined twice", name)
+	}
+
+	s.entries[name] = &entry{
+		schedule: schedule,
+		task:     task,
+		status:   Status{Name: name, Task: taskName, Schedule: spec},
+	}
+	s.order = append(s.order, name)
+
+	return nil
+}
+
+// Start begins running the schedules in the background.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, name := range s.order {
+		go s.loop(s.entries[name])
+	}
+}
+
+func (s *Scheduler) loop(e *entry) {
+	for {
+		next := e.schedule.Next(time.Now())
+
+		e.mu.Lock()
+		e.status.NextRun = next
+		e.mu.Unlock()
+
+		select {
+		case <-time.After(time.Until(next)):
+			s.run(e)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// ErrRunning is returned by Run when the task is already running.
+var ErrRunning = errors.New("already running")
+
+// Run starts a schedule's task now, outside its schedule.
+func (s *Scheduler) Run(name string) error {
+	s.mu.Lock()
+	e, ok := s.entries[name]
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no schedule named %q", name)
+	}
+
+	e.mu.Lock()
+	running := e.status.Running
+	e.mu.Unlock()
+
+	if running {
+		return ErrRunning
+	}
+
+	go s.run(e)
+
+	return nil
+}
+
+func (s *Scheduler) run(e *entry) {
+	e.mu.Lock()
+	if e.status.Running || s.ctx.Err() != nil {
+		if e.status.Running {
+			e.status.Skipped++
+			slog.Warn("scheduled task skipped, previous run still going", slog.String("schedule", e.status.Name))
+		}
+		e.mu.Unlock()
+		return
+	}
+	e.status.Running = true
+	e.status.LastStart = time.Now().UTC()
+	s.running.Add(1)
+	e.mu.Unlock()
+
+	defer s.running.Done()
+
+	log := slog.With(slog.String("schedule", e.status.Name), slog.String("task", e.status.Task))
+	log.Info("scheduled task started")
+
+	err := call(s.ctx, e.task)
+
+	e.mu.Lock()
+	e.status.Running = false
+	e.status.LastEnd = time.Now().UTC()
+	e.status.Runs++
+	e.status.LastError = ""
+	if err != nil {
+		e.status.Failures++
+		e.status.LastError = err.Error()
+	}
+	took := e.status.LastEnd.Sub(e.status.LastStart)
+	e.mu.Unlock()
+
+	if err != nil {
+		log.Error("scheduled task failed", slog.String("error", err.Error()), slog.Duration("took", took))
+		return
+	}
+
+	log.Info("scheduled task finished", slog.Duration("took", took))
+}
+
+// Status returns the status of every schedule, in the order they were added.
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := []Status{}
+	for _, name := range s.order {
+		e := s.entries[name]
+
+		e.mu.Lock()
+		statuses = append(statuses, e.status)
+		e.mu.Unlock()
+	}
+
+	return statuses
+}
+
+// Stop stops scheduling runs and cancels the running ones, waiting for them
+// to return until ctx is done.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.stop()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// call runs task, turning a panic into an error.
+func call(ctx context.Context, task Task) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return task(ctx)
+}
@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// References look like "<scheme>:<location>#<key>", for example
+//
+//	vault:secret/data/students-api#jwt_key
+//	aws-sm:prod/students-api#smtp_password
+const (
+	schemeVault = "vault:"
+	schemeAWS   = "aws-sm:"
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// IsReference reports whether value points at a secret store.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, schemeVault) || strings.HasPrefix(value, schemeAWS)
+}
+
+// Resolve fetches the secret a reference points at.
+func Resolve(ctx context.Context, ref string) (string, error) {
+	if rest, ok := strings.CutPrefix(ref, schemeVault); ok {
+		path, key := splitKey(rest)
+		return vaultSecret(ctx, path, key)
+	}
+
+	if rest, ok := strings.CutPrefix(ref, schemeAWS); ok {
+		id, key := splitKey(rest)
+		return awsSecret(ctx, id, key)
+	}
+
+	return "", fmt.Errorf("not a secret reference: %q", ref)
+}
+
+// ResolveAll replaces every string field of the struct v points to that holds
+// a secret reference with the secret's value, descending into nested structs,
+// slices and maps.
+func ResolveAll(ctx context.Context, v any) error {
+	return resolveValue(ctx, reflect.ValueOf(v).Elem(), "")
+}
+
+func resolveValue(ctx context.Context, v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := range v.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			// report problems by the name used in the config file
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				name = field.Name
+			}
+
+			if err := resolveValue(ctx, v.Field(i), path+"."+name); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			if err := resolveValue(ctx, v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// map values can't be set in place, so each is resolved in a copy
+		for _, key := range v.MapKeys() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(key))
+			if err := resolveValue(ctx, value, fmt.Sprintf("%s.%v", path, key)); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, value)
+		}
+	case reflect.String:
+		if !IsReference(v.String()) {
+			return nil
+		}
+
+		secret, err := Resolve(ctx, v.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", strings.TrimPrefix(path, "."), err)
+		}
+		v.SetString(secret)
+	}
+
+	return nil
+}
+
+func splitKey(s string) (location, key string) {
+	location, key, _ = strings.Cut(s, "#")
+	return location, key
+}
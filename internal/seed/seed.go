@@ -0,0 +1,54 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+var firstNames = []string{
+	"Aarav", "Aisha", "Alejandro", "Amara", "Ananya", "Ben", "Carlos", "Chen", "Chloe", "Daniel",
+	"Diya", "Elena", "Emma", "Ethan", "Fatima", "Gabriel", "Hana", "Isabella", "Ivan", "Jack",
+	"Jin", "Kwame", "Laila", "Liam", "Lucas", "Maya", "Mei", "Mohammed", "Nadia", "Noah",
+	"Olivia", "Omar", "Priya", "Rahul", "Rosa", "Sakura", "Samuel", "Sofia", "Tariq", "Yuki",
+}
+
+var lastNames = []string{
+	"Adeyemi", "Ahmed", "Andersson", "Bianchi", "Brown", "Chen", "Cohen", "Dubois", "Fernandez", "Garcia",
+	"Gupta", "Hansen", "Ivanova", "Johnson", "Kim", "Kowalski", "Lee", "Mensah", "Müller", "Nakamura",
+	"Nguyen", "O'Brien", "Okafor", "Patel", "Rossi", "Santos", "Schmidt", "Sharma", "Silva", "Smith",
+	"Suzuki", "Tanaka", "Thapa", "Wang", "Williams", "Wilson", "Yilmaz", "Zhang",
+}
+
+var domains = []string{"example.edu", "students.example.org", "mail.example.com"}
+
+// Students generates n students with plausible names, emails and ages.
+// Emails are unique within a call and carry a random batch tag, so
+// repeated runs against the same database don't collide.
+func Students(r *rand.Rand, n int) []types.Student {
+	batch := fmt.Sprintf("%06x", r.Uint32()&0xffffff)
+
+	students := make([]types.Student, 0, n)
+	for i := range n {
+		first := firstNames[r.IntN(len(firstNames))]
+		last := lastNames[r.IntN(len(lastNames))]
+
+		students = append(students, types.Student{
+			Name:  first + " " + last,
+			Email: fmt.Sprintf("%s.%s.%s%d@%s", emailPart(first), emailPart(last), batch, i, domains[r.IntN(len(domains))]),
+			// mostly undergraduate ages with a tail of mature students
+			Age: min(17+int(r.ExpFloat64()*4), 65),
+		})
+	}
+
+	return students
+}
+
+func emailPart(name string) string {
+	name = strings.ToLower(name)
+	name = strings.NewReplacer("'", "", "ü", "u").Replace(name)
+
+	return name
+}
@@ -0,0 +1,250 @@
+// Package file keeps the files uploaded for students, such as photos and
+// documents: their contents in a blob store and what they are in the
+// storage.
+package file
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cmanish049/students-api/internal/blob"
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/jobs"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// CleanupKind is the job kind deleting the contents of the files of a
+// deleted student, queued by the storage as it deletes them
+const CleanupKind = storage.FileCleanupKind
+
+// maxNameLength is the most characters kept of a file name
+const maxNameLength = 255
+
+var (
+	// ErrUnsupportedType is an upload of a content type that isn't allowed
+	ErrUnsupportedType = errors.New("unsupported file type")
+	// ErrNoName is an upload without a file name
+	ErrNoName = errors.New("file name is required")
+)
+
+// cleanup is the job payload; jobs queued before the storage deleted the
+// files with the student have the student id instead of the keys.
+type cleanup struct {
+	storage.FileCleanup
+	StudentId int64 `json:"student_id,omitempty"`
+}
+
+// Service uploads, lists and deletes the files of students. The files of
+// a deleted student are deleted with it, and their contents by a job.
+type Service struct {
+	store    storage.FileStorage
+	students storage.Storage
+	blobs    blob.Store
+	queue    *jobs.Queue
+	allowed  []string
+	urlTtl   time.Duration
+}
+
+// New returns a service keeping the contents of files in blobs. Only files
+// of the allowed content types are accepted, and their download URLs are
+// valid for urlTtl. It registers the cleanup job kind on queue.
+func New(store storage.FileStorage, students storage.Storage, blobs blob.Store, queue *jobs.Queue, allowed []string, urlTtl time.Duration) *Service {
+	s := &Service{store: store, students: students, blobs: blobs, queue: queue, allowed: allowed, urlTtl: urlTtl}
+
+	queue.Register(CleanupKind, storage.FileCleanupAttempts, time.Minute, s.cleanup)
+
+	return s
+}
+
+// AllowedTypes returns the content types files may have.
+func (s *Service) AllowedTypes() []string {
+	return s.allowed
+}
+
+// Upload stores the size bytes of body as a file of student studentId.
+// Only the base of name is kept. Dry runs check the upload but store
+// nothing.
+func (s *Service) Upload(ctx context.Context, studentId int64, name, contentType string, size int64, body io.Reader) (types.File, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !slices.Contains(s.allowed, mediaType) {
+		return types.File{}, ErrUnsupportedType
+	}
+
+	name = cleanName(name)
+	if name == "" {
+		return types.File{}, ErrNoName
+	}
+
+	if _, err := s.students.GetStudentById(ctx, studentId); err != nil {
+		return types.File{}, err
+	}
+
+	f := types.File{
+		StudentId:   studentId,
+		Name:        name,
+		ContentType: mediaType,
+		Size:        size,
+		Key:         newKey(ctx, studentId),
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	if !dryrun.Enabled(ctx) {
+		if err := s.blobs.Put(ctx, f.Key, f.ContentType, f.Size, body); err != nil {
+			return types.File{}, fmt.Errorf("cannot store the file: %w", err)
+		}
+	}
+
+	f.Id, err = s.store.CreateFile(ctx, f)
+	if err != nil {
+		s.deleteBlob(ctx, f)
+		return types.File{}, err
+	}
+
+	return f, nil
+}
+
+// List returns the files of student studentId, oldest first.
+func (s *Service) List(ctx context.Context, studentId int64) ([]types.File, error) {
+	if _, err := s.students.GetStudentById(ctx, studentId); err != nil {
+		return nil, err
+	}
+
+	return s.store.GetFiles(ctx, studentId)
+}
+
+// Get returns file id of student studentId.
+func (s *Service) Get(ctx context.Context, studentId, id int64) (types.File, error) {
+	if _, err := s.students.GetStudentById(ctx, studentId); err != nil {
+		return types.File{}, err
+	}
+
+	return s.store.GetFile(ctx, studentId, id)
+}
+
+// URL returns a URL that downloads f for the configured time, without a
+// token, and when it expires.
+func (s *Service) URL(ctx context.Context, f types.File) (string, time.Time, error) {
+	expires := time.Now().Add(s.urlTtl).UTC().Truncate(time.Second)
+
+	url, err := s.blobs.URL(ctx, f.Key, f.Name, f.ContentType, s.urlTtl)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return url, expires, nil
+}
+
+// Delete deletes file id of student studentId and its contents.
+func (s *Service) Delete(ctx context.Context, studentId, id int64) error {
+	f, err := s.Get(ctx, studentId, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.DeleteFile(ctx, studentId, id); err != nil {
+		return err
+	}
+
+	s.deleteBlob(ctx, f)
+
+	return nil
+}
+
+// deleteBlob deletes the contents of f, unless ctx is a dry run. A blob
+// that can't be deleted is only logged: f is gone either way.
+func (s *Service) deleteBlob(ctx context.Context, f types.File) {
+	if dryrun.Enabled(ctx) {
+		return
+	}
+
+	if err := s.blobs.Delete(ctx, f.Key); err != nil {
+		slog.Error("failed to delete file contents", slog.String("key", f.Key), slog.String("error", err.Error()))
+	}
+}
+
+func (s *Service) cleanup(ctx context.Context, job types.Job) error {
+	var c cleanup
+	if err := json.Unmarshal(job.Payload, &c); err != nil {
+		return err
+	}
+
+	// a retry deletes the contents already gone again, which is a no-op
+	for _, key := range c.Keys {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	if len(c.Keys) > 0 {
+		slog.Info("files of deleted student removed", slog.Int("files", len(c.Keys)))
+	}
+
+	if c.StudentId == 0 {
+		return nil
+	}
+
+	files, err := s.store.GetFiles(ctx, c.StudentId)
+	if err != nil {
+		return err
+	}
+
+	// each file is deleted after its contents, so a retry finds those left
+	for _, f := range files {
+		if err := s.blobs.Delete(ctx, f.Key); err != nil {
+			return err
+		}
+		if err := s.store.DeleteFile(ctx, f.StudentId, f.Id); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+
+	if len(files) > 0 {
+		slog.Info("files of deleted student removed", slog.Int64("student_id", c.StudentId), slog.Int("files", len(files)))
+	}
+
+	return nil
+}
+
+// newKey returns a new blob key for a file of student studentId, grouped
+// by tenant and student.
+func newKey(ctx context.Context, studentId int64) string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+
+	return tenant.From(ctx) + "/students/" + strconv.FormatInt(studentId, 10) + "/" + hex.EncodeToString(buf)
+}
+
+// cleanName keeps the last element of a path, as browsers may send one,
+// without control characters and at most maxNameLength characters long.
+func cleanName(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	if runes := []rune(name); len(runes) > maxNameLength {
+		name = string(runes[:maxNameLength])
+	}
+
+	return name
+}
@@ -0,0 +1,47 @@
+package student
+
+import (
+	"context"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/events"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// Archive moves the students not updated since before to the archive and
+// returns them, publishing a StudentArchived event for each. With a
+// tenant.All context it archives the students of every tenant.
+func (s *Service) Archive(ctx context.Context, before time.Time) ([]types.Student, error) {
+	archived, err := s.store.ArchiveStudents(ctx, before)
+
+	// the students archived before a failure are gone from the list all the same
+	for _, student := range archived {
+		s.publish(tenant.With(ctx, student.TenantId), events.StudentArchived, student)
+	}
+
+	return archived, err
+}
+
+// GetArchived returns archived student id.
+func (s *Service) GetArchived(ctx context.Context, id int64) (types.Student, error) {
+	return s.store.GetArchivedStudent(ctx, id)
+}
+
+// Unarchive moves archived student id back and returns it. It fails with
+// ErrEmailTaken if another student took the email in the meantime.
+func (s *Service) Unarchive(ctx context.Context, id int64) (types.Student, error) {
+	archived, err := s.store.GetArchivedStudent(ctx, id)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	student, err := s.store.UnarchiveStudent(ctx, id)
+	if err != nil {
+		return types.Student{}, taken(err, archived.Email)
+	}
+
+	s.publishStudent(ctx, events.StudentUnarchived, student)
+
+	return student, nil
+}
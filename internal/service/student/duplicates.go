@@ -0,0 +1,151 @@
+package student
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/cmanish049/students-api/internal/events"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// DefaultSimilarity is the name similarity above which two students are
+// considered duplicates when none is given.
+const DefaultSimilarity = 0.85
+
+// Duplicate is a pair of students that look like the same person: their
+// normalized emails are equal, or their names are at least as similar as
+// asked for.
+type Duplicate struct {
+	Student types.Student
+	Other   types.Student
+	// EmailMatch is set if the normalized emails are equal
+	EmailMatch bool
+	// NameSimilarity is 1 for names that are equal once normalized, and 0
+	// for names with nothing in common
+	NameSimilarity float64
+}
+
+// Duplicates finds the pairs of students that look like duplicates, the
+// student with the lower id first. Every pair of students is compared, so
+// it takes time quadratic in the number of students.
+func (s *Service) Duplicates(ctx context.Context, minSimilarity float64) ([]Duplicate, error) {
+	students, err := s.store.GetStudentList(ctx, storage.StudentFilter{})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(students, func(a, b types.Student) int { return a.Id - b.Id })
+
+	names := make([][]rune, len(students))
+	emails := make([]string, len(students))
+	for i, student := range students {
+		names[i] = []rune(normalizeName(student.Name))
+		emails[i] = normalizeEmail(student.Email)
+	}
+
+	var dups []Duplicate
+	for i := range students {
+		for j := i + 1; j < len(students); j++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			emailMatch := emails[i] == emails[j]
+			nameSimilarity := similarity(names[i], names[j])
+			if emailMatch || nameSimilarity >= minSimilarity {
+				dups = append(dups, Duplicate{Student: students[i], Other: students[j], EmailMatch: emailMatch, NameSimilarity: nameSimilarity})
+			}
+		}
+	}
+
+	return dups, nil
+}
+
+// Merge folds student otherId into id and returns the merged student: id
+// keeps its fields, but for a verified email of otherId replacing an
+// unverified one, and the earlier of the two creation times; otherId is
+// deleted. See storage.Storage.MergeStudents.
+func (s *Service) Merge(ctx context.Context, id, otherId int64) (types.Student, error) {
+	if id == otherId {
+		return types.Student{}, fmt.Errorf("cannot merge student %d with itself", id)
+	}
+
+	merged, err := s.store.MergeStudents(ctx, id, otherId)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	s.publish(ctx, events.StudentUpdated, merged)
+	s.publish(ctx, events.StudentDeleted, events.Deleted{Id: otherId})
+
+	return merged, nil
+}
+
+// normalizeEmail lowercases an email and drops the +tag of its local part,
+// so "John.Doe+import@School.edu" and "john.doe@school.edu" are the same.
+func normalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return email
+	}
+	local, _, _ = strings.Cut(local, "+")
+
+	return local + "@" + domain
+}
+
+// normalizeName lowercases a name, drops punctuation and sorts its words,
+// so "Doe, John" and "john  doe" are the same.
+func normalizeName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '-'
+	})
+	slices.Sort(words)
+
+	return strings.Join(words, " ")
+}
+
+// similarity is 1 minus the edit distance of a and b relative to the
+// longer one.
+func similarity(a, b []rune) float64 {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(distance(a, b))/float64(longest)
+}
+
+// distance is the edit distance of a and b, counting the swap of two
+// adjacent letters, a common typo, as one edit like in the optimal string
+// alignment distance.
+func distance(a, b []rune) int {
+	// the rows for the prefixes of a before the current one
+	before := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], before[j-2]+1)
+			}
+		}
+		before, prev, cur = prev, cur, before
+	}
+
+	return prev[len(b)]
+}
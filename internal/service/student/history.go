@@ -0,0 +1,112 @@
+package student
+
+import (
+	"context"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// Change is a field that differs between two versions of a student.
+type Change struct {
+	Field string
+	From  any
+	To    any
+}
+
+// Version is a version of a student and its changes from an earlier one.
+type Version struct {
+	types.StudentVersion
+	Changes []Change
+}
+
+// History returns the versions of student id, oldest first, each with its
+// changes from the one before. The first has none.
+func (s *Service) History(ctx context.Context, id int64) ([]Version, error) {
+	versions, err := s.store.GetStudentVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Version, len(versions))
+	for i, v := range versions {
+		res[i] = Version{StudentVersion: v}
+		if i > 0 {
+			res[i].Changes = Diff(versions[i-1], v)
+		}
+	}
+
+	return res, nil
+}
+
+// Version returns version of student id with its changes from version
+// compare, or from the version before it if compare is 0.
+func (s *Service) Version(ctx context.Context, id int64, version, compare int) (Version, error) {
+	v, err := s.store.GetStudentVersion(ctx, id, version)
+	if err != nil {
+		return Version{}, err
+	}
+
+	if compare == 0 {
+		compare = version - 1
+	}
+	if compare < 1 || compare == version {
+		return Version{StudentVersion: v}, nil
+	}
+
+	base, err := s.store.GetStudentVersion(ctx, id, compare)
+	if err != nil {
+		return Version{}, err
+	}
+
+	return Version{StudentVersion: v, Changes: Diff(base, v)}, nil
+}
+
+// Restore updates student id back to the fields of version, with the same
+// rules as Update, and returns the student. The restore is a change like
+// any other and so becomes the latest version.
+func (s *Service) Restore(ctx context.Context, id int64, version int) (types.Student, error) {
+	v, err := s.store.GetStudentVersion(ctx, id, version)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	before, err := s.store.GetStudentById(ctx, id)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	if err := s.Update(ctx, id, types.Student{Name: v.Name, Email: v.Email, Age: v.Age}); err != nil {
+		return types.Student{}, err
+	}
+
+	if !dryrun.Enabled(ctx) {
+		return s.store.GetStudentById(ctx, id)
+	}
+
+	// what the rolled back update would have stored
+	restored := before
+	restored.Name, restored.Email, restored.Age, restored.UpdatedAt = v.Name, v.Email, v.Age, time.Now().UTC()
+	if v.Email != before.Email {
+		restored.EmailVerified, restored.EmailVerifiedAt = false, time.Time{}
+	}
+
+	return restored, nil
+}
+
+// Diff returns the fields that differ from one version to another.
+func Diff(from, to types.StudentVersion) []Change {
+	var changes []Change
+	if from.Name != to.Name {
+		changes = append(changes, Change{Field: "name", From: from.Name, To: to.Name})
+	}
+	if from.Email != to.Email {
+		changes = append(changes, Change{Field: "email", From: from.Email, To: to.Email})
+	}
+	if from.Age != to.Age {
+		changes = append(changes, Change{Field: "age", From: from.Age, To: to.Age})
+	}
+
+	return changes
+}
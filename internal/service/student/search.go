@@ -0,0 +1,81 @@
+package student
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// DefaultMinScore is the score below which students don't match a search
+// when none is given; it lets "Jon Smiht" find "John Smith".
+const DefaultMinScore = 0.6
+
+// Match is a student found by a search and how well its name matches.
+type Match struct {
+	Student types.Student
+	// Score is 1 for a name that is the query once normalized, or that has
+	// every word of it, and 0 for one with nothing in common
+	Score float64
+}
+
+// Search finds the students whose names match query at least minScore,
+// tolerating typos, best match first, the archived ones too if
+// includeArchived is set. Every student is scored, so it takes time linear
+// in the number of students.
+func (s *Service) Search(ctx context.Context, query string, minScore float64, includeArchived bool) ([]Match, error) {
+	students, err := s.store.GetStudentList(ctx, storage.StudentFilter{IncludeArchived: includeArchived})
+	if err != nil {
+		return nil, err
+	}
+
+	q := normalizeName(query)
+	words := strings.Fields(q)
+
+	var matches []Match
+	for _, student := range students {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if score := nameScore(q, words, normalizeName(student.Name)); score >= minScore {
+			matches = append(matches, Match{Student: student, Score: score})
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b Match) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), a.Student.Id-b.Student.Id)
+	})
+
+	return matches, nil
+}
+
+// nameScore scores how well the normalized name matches the normalized
+// query q and its words: the better of the similarity of the whole names
+// and the average similarity of each word of the query to the closest word
+// of the name, which lets a query of only the last name match.
+func nameScore(q string, words []string, name string) float64 {
+	score := similarity([]rune(q), []rune(name))
+	if len(words) == 0 {
+		return score
+	}
+
+	nameWords := strings.Fields(name)
+	if len(nameWords) == 0 {
+		return score
+	}
+
+	var total float64
+	for _, w := range words {
+		var best float64
+		for _, nw := range nameWords {
+			best = max(best, similarity([]rune(w), []rune(nw)))
+		}
+		total += best
+	}
+
+	return max(score, total/float64(len(words)))
+}
@@ -0,0 +1,202 @@
+// Package student holds the rules for creating and changing students, so
+// every entry point (REST, gRPC, the CLI, bulk imports) enforces the same
+// ones and publishes the same events.
+package student
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/events"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/types"
+	"github.com/cmanish049/students-api/internal/utils/response"
+	"github.com/cmanish049/students-api/internal/validation"
+)
+
+var (
+	// ErrNotFound is matched by the errors for students that don't exist
+	ErrNotFound = storage.ErrNotFound
+	// ErrEmailTaken is matched when another student already has the email;
+	// the errors matching it also match storage.ErrConflict
+	ErrEmailTaken = errors.New("email is already used by another student")
+)
+
+// emailTakenError is returned when another student has email.
+type emailTakenError struct {
+	email string
+}
+
+func (e emailTakenError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrEmailTaken, e.email)
+}
+
+func (e emailTakenError) Is(target error) bool {
+	return target == ErrEmailTaken || target == storage.ErrConflict
+}
+
+// Message lets the message be translated, see i18n.Message.
+func (e emailTakenError) Message() (string, []any) {
+	return "email is already used by another student: %s", []any{e.email}
+}
+
+func (e emailTakenError) Code() response.Code {
+	return response.CodeEmailTaken
+}
+
+// Service creates, changes and reads students.
+type Service struct {
+	store    storage.Storage
+	bus      *events.Bus
+	validate *validation.Validator
+
+	verifier        Verifier
+	verificationTtl time.Duration
+}
+
+// New returns a service on store that checks students with validate.
+// Successful mutations are published on bus unless it is nil; dry runs
+// publish nothing.
+func New(store storage.Storage, bus *events.Bus, validate *validation.Validator) *Service {
+	return &Service{store: store, bus: bus, validate: validate}
+}
+
+// Validate checks a student's fields, returning a *validation.Error if any
+// is invalid.
+func (s *Service) Validate(student types.Student) error {
+	return s.validate.Struct(student)
+}
+
+// Create validates and stores a new student and returns its id.
+func (s *Service) Create(ctx context.Context, student types.Student) (int64, error) {
+	if err := s.Validate(student); err != nil {
+		return 0, err
+	}
+
+	if err := s.checkEmail(ctx, student.Email, 0); err != nil {
+		return 0, err
+	}
+
+	id, err := s.store.CreateStudent(ctx, student.Name, student.Email, student.Age)
+	if err != nil {
+		return 0, taken(err, student.Email)
+	}
+
+	student.Id = int(id)
+	s.publishStudent(ctx, events.StudentCreated, student)
+	s.verifyNew(ctx, student)
+
+	return id, nil
+}
+
+func (s *Service) Get(ctx context.Context, id int64) (types.Student, error) {
+	return s.store.GetStudentById(ctx, id)
+}
+
+func (s *Service) List(ctx context.Context, filter storage.StudentFilter) ([]types.Student, error) {
+	return s.store.GetStudentList(ctx, filter)
+}
+
+// Report returns the aggregates of the groups of students query asks for.
+func (s *Service) Report(ctx context.Context, query storage.ReportQuery) ([]types.ReportGroup, error) {
+	return s.store.ReportStudents(ctx, query)
+}
+
+// Update validates and replaces the fields of student id. A changed email
+// is unverified again.
+func (s *Service) Update(ctx context.Context, id int64, student types.Student) error {
+	if err := s.Validate(student); err != nil {
+		return err
+	}
+
+	before, err := s.store.GetStudentById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkEmail(ctx, student.Email, id); err != nil {
+		return err
+	}
+
+	if err := s.store.UpdateStudent(ctx, id, student.Name, student.Email, student.Age); err != nil {
+		return taken(err, student.Email)
+	}
+
+	student.Id = int(id)
+	s.publishStudent(ctx, events.StudentUpdated, student)
+	if student.Email != before.Email {
+		s.verifyNew(ctx, student)
+	}
+
+	return nil
+}
+
+func (s *Service) Delete(ctx context.Context, id int64) error {
+	if err := s.store.DeleteStudent(ctx, id); err != nil {
+		return err
+	}
+
+	s.publish(ctx, events.StudentDeleted, events.Deleted{Id: id})
+
+	return nil
+}
+
+// checkEmail fails with ErrEmailTaken if a student other than except has
+// email. The storage's unique constraint still catches concurrent writes.
+func (s *Service) checkEmail(ctx context.Context, email string, except int64) error {
+	other, err := s.store.GetStudentByEmail(ctx, email)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if int64(other.Id) != except {
+		return emailTakenError{email: email}
+	}
+
+	return nil
+}
+
+// taken turns the storage's unique constraint error into ErrEmailTaken.
+func taken(err error, email string) error {
+	if errors.Is(err, storage.ErrConflict) {
+		return emailTakenError{email: email}
+	}
+
+	return err
+}
+
+// publishStudent publishes student as stored, with the timestamps the
+// storage gave it.
+func (s *Service) publishStudent(ctx context.Context, typ events.Type, student types.Student) {
+	if dryrun.Enabled(ctx) {
+		return
+	}
+
+	if s.bus == nil {
+		return
+	}
+
+	if stored, err := s.store.GetStudentById(ctx, int64(student.Id)); err == nil {
+		student = stored
+	}
+
+	s.bus.Publish(ctx, typ, student)
+}
+
+func (s *Service) publish(ctx context.Context, typ events.Type, data any) {
+	if dryrun.Enabled(ctx) {
+		return
+	}
+
+	if s.bus == nil {
+		return
+	}
+
+	s.bus.Publish(ctx, typ, data)
+}
@@ -0,0 +1,121 @@
+package student
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/events"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// ErrVerificationDisabled is returned when asked to send a verification
+// while no Verifier is set.
+var ErrVerificationDisabled = errors.New("email verification is not enabled")
+
+// Verifier sends a student the token that verifies their email.
+type Verifier interface {
+	SendVerification(ctx context.Context, student types.Student, token string) error
+}
+
+// alreadyVerifiedError is returned when asked to verify a verified email.
+type alreadyVerifiedError struct {
+	id int64
+}
+
+func (e alreadyVerifiedError) Error() string {
+	return fmt.Sprintf("the email of student %d is already verified", e.id)
+}
+
+func (e alreadyVerifiedError) Is(target error) bool {
+	return target == storage.ErrConflict
+}
+
+// Message lets the message be translated, see i18n.Message.
+func (e alreadyVerifiedError) Message() (string, []any) {
+	return "the email of student %d is already verified", []any{e.id}
+}
+
+// SetVerifier makes new students, and students whose email changes, get a
+// token through v that verifies their email for ttl. Without one their
+// email stays unverified until a verification is sent.
+func (s *Service) SetVerifier(v Verifier, ttl time.Duration) {
+	s.verifier, s.verificationTtl = v, ttl
+}
+
+// SendVerification sends student id a new token for their email, replacing
+// any earlier one.
+func (s *Service) SendVerification(ctx context.Context, id int64) error {
+	if s.verifier == nil {
+		return ErrVerificationDisabled
+	}
+
+	student, err := s.store.GetStudentById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if student.EmailVerified {
+		return alreadyVerifiedError{id: id}
+	}
+
+	return s.sendVerification(ctx, student)
+}
+
+// Verify marks the email of the student token was sent to as verified.
+func (s *Service) Verify(ctx context.Context, token string) (types.Student, error) {
+	student, err := s.store.VerifyEmail(ctx, hashToken(token), time.Now())
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	s.publish(ctx, events.StudentEmailVerified, student)
+
+	return student, nil
+}
+
+// verifyNew sends a verification to student, just created or given a new
+// email, if a Verifier is set. Failing to is logged, not returned: the
+// change is made and the verification can be sent again.
+func (s *Service) verifyNew(ctx context.Context, student types.Student) {
+	if s.verifier == nil || dryrun.Enabled(ctx) {
+		return
+	}
+
+	if err := s.sendVerification(ctx, student); err != nil {
+		slog.Error("failed to send email verification", slog.Int("student_id", student.Id), slog.String("error", err.Error()))
+	}
+}
+
+func (s *Service) sendVerification(ctx context.Context, student types.Student) error {
+	token := newToken()
+	if err := s.store.SetVerificationToken(ctx, int64(student.Id), hashToken(token), time.Now().Add(s.verificationTtl)); err != nil {
+		return err
+	}
+
+	if dryrun.Enabled(ctx) {
+		return nil
+	}
+
+	return s.verifier.SendVerification(ctx, student, token)
+}
+
+// newToken returns a random token; only its hash is stored.
+func newToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,106 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTwilioUrl is the Twilio REST API
+const DefaultTwilioUrl = "https://api.twilio.com"
+
+// Twilio sends through the Twilio Messages API, or any gateway with the same
+// API. accountSid and authToken are the API credentials.
+type Twilio struct {
+	baseUrl    string
+	accountSid string
+	authToken  string
+	from       string
+	client     *http.Client
+}
+
+func NewTwilio(baseUrl, accountSid, authToken, from string, timeout time.Duration) *Twilio {
+	if baseUrl == "" {
+		baseUrl = DefaultTwilioUrl
+	}
+
+	return &Twilio{
+		baseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		accountSid: accountSid,
+		authToken:  authToken,
+		from:       from,
+		client:     &http.Client{Timeout: timeout},
+	}
+}
+
+func (t *Twilio) Send(ctx context.Context, to, body string) error {
+	form := url.Values{"To": {to}, "From": {t.from}, "Body": {body}}
+	endpoint := t.baseUrl + "/2010-04-01/Accounts/" + url.PathEscape(t.accountSid) + "/Messages.json"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+
+	req.SetBasicAuth(t.accountSid, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return do(t.client, req)
+}
+
+// HTTP sends to a gateway that takes {"from", "to", "body"} as JSON,
+// authenticated with a bearer token if one is set.
+type HTTP struct {
+	url    string
+	token  string
+	from   string
+	client *http.Client
+}
+
+func NewHTTP(url, token, from string, timeout time.Duration) *HTTP {
+	return &HTTP{url: url, token: token, from: from, client: &http.Client{Timeout: timeout}}
+}
+
+func (h *HTTP) Send(ctx context.Context, to, body string) error {
+	payload, err := json.Marshal(map[string]string{"from": h.from, "to": to, "body": body})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+
+	return do(h.client, req)
+}
+
+// do sends req and turns a non-2xx answer into an error carrying the start
+// of the gateway's explanation.
+func do(client *http.Client, req *http.Request) error {
+	req.Header.Set("User-Agent", "students-api-sms")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/jobs"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// Kind is the job kind of one text message
+const Kind = "sms"
+
+// phone numbers are accepted in E.164 form only, e.g. +15551234567
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// Provider sends a text message through an SMS gateway.
+type Provider interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+type message struct {
+	To   string `json:"to"`
+	Body string `json:"body"`
+}
+
+// Notifier queues text messages and sends them through a provider, at most
+// perMinute a minute so a burst of events can't run up the bill or hit the
+// provider's own limits.
+type Notifier struct {
+	provider Provider
+	queue    *jobs.Queue
+	limiter  *limiter
+}
+
+// New registers the sms job kind on queue.
+func New(provider Provider, queue *jobs.Queue, perMinute, maxAttempts int, backoff time.Duration) *Notifier {
+	n := &Notifier{provider: provider, queue: queue, limiter: newLimiter(perMinute, time.Minute)}
+
+	queue.Register(Kind, maxAttempts, backoff, n.send)
+
+	return n
+}
+
+// Notify queues a text message to an E.164 phone number and returns the
+// job id.
+func (n *Notifier) Notify(ctx context.Context, to, body string) (int64, error) {
+	if !e164.MatchString(to) {
+		return 0, fmt.Errorf("%q is not a phone number in E.164 form, e.g. +15551234567", to)
+	}
+
+	if body == "" {
+		return 0, fmt.Errorf("empty message")
+	}
+
+	return n.queue.Enqueue(ctx, Kind, message{To: to, Body: body})
+}
+
+func (n *Notifier) send(ctx context.Context, job types.Job) error {
+	var msg message
+	if err := json.Unmarshal(job.Payload, &msg); err != nil {
+		return err
+	}
+
+	// over the limit, try again later without using up an attempt
+	if wait := n.limiter.take(); wait > 0 {
+		return jobs.Postpone(wait)
+	}
+
+	if err := n.provider.Send(ctx, msg.To, msg.Body); err != nil {
+		return err
+	}
+
+	slog.Info("sms sent", slog.Int64("job_id", job.Id))
+
+	return nil
+}
+
+// limiter allows n events per window, spread evenly.
+type limiter struct {
+	mu    sync.Mutex
+	every time.Duration
+	next  time.Time
+}
+
+func newLimiter(n int, window time.Duration) *limiter {
+	return &limiter{every: window / time.Duration(n)}
+}
+
+// take allows an event if one is due, else returns how long until one is.
+func (l *limiter) take() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(l.next) {
+		return l.next.Sub(now)
+	}
+
+	l.next = now.Add(l.every)
+
+	return 0
+}
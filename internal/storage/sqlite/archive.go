@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// archivedStudentsTable keeps the students ArchiveStudents moved out of
+// the students table, so that one stays small; added in schema 9. Emails
+// aren't unique, as new students may have taken those of archived ones.
+const archivedStudentsTable = `CREATE TABLE IF NOT EXISTS archived_students (
+		id INTEGER PRIMARY KEY,
+		tenant_id TEXT NOT NULL DEFAULT 'default',
+		name TEXT NOT NULL,
+		email TEXT NOT NULL,
+		age INTEGER NOT NULL,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL,
+		email_verified BOOLEAN NOT NULL DEFAULT 0,
+		email_verified_at DATETIME,
+		archived_at DATETIME NOT NULL
+	);`
+
+// archivedColumns are the columns scanArchived reads.
+const archivedColumns = studentColumns + ", archived_at"
+
+// allStudents is the students together with the archived ones, for the
+// reports that include them.
+const allStudents = "(SELECT " + archivedColumns + " FROM archived_students UNION ALL SELECT " + studentColumns + ", NULL FROM students)"
+
+func scanArchived(row interface{ Scan(...any) error }) (types.Student, error) {
+	var student types.Student
+	var verifiedAt, archivedAt sql.NullTime
+	err := row.Scan(&student.Id, &student.TenantId, &student.Name, &student.Email, &student.Age, &student.CreatedAt, &student.UpdatedAt, &student.EmailVerified, &verifiedAt, &archivedAt)
+	student.EmailVerifiedAt, student.ArchivedAt = verifiedAt.Time, archivedAt.Time
+
+	return student, err
+}
+
+func (s *Sqlite) ArchiveStudents(ctx context.Context, before time.Time) ([]types.Student, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	inactive := "updated_at < ? AND " + inTenant
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+studentColumns+" FROM students WHERE "+inactive+" ORDER BY id", scoped(ctx, before.UTC())...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var students []types.Student
+	for rows.Next() {
+		student, err := scanStudent(rows)
+		if err != nil {
+			return nil, err
+		}
+		student.ArchivedAt = now
+		students = append(students, student)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if len(students) == 0 {
+		return nil, nil
+	}
+
+	// pending verification tokens aren't kept, the versions are
+	if _, err := tx.ExecContext(ctx, "INSERT INTO archived_students ("+archivedColumns+") SELECT "+studentColumns+", ? FROM students WHERE "+inactive, scoped(ctx, now, before.UTC())...); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM students WHERE "+inactive, scoped(ctx, before.UTC())...); err != nil {
+		return nil, err
+	}
+
+	return students, commit(ctx, tx)
+}
+
+func (s *Sqlite) GetArchivedStudent(ctx context.Context, id int64) (types.Student, error) {
+	student, err := scanArchived(s.Db.QueryRowContext(ctx, "SELECT "+archivedColumns+" FROM archived_students WHERE id = ? AND "+inTenant, scoped(ctx, id)...))
+	if err == sql.ErrNoRows {
+		return types.Student{}, storage.NotFound("no archived student found with id %d", id)
+	}
+	if err != nil {
+		return types.Student{}, fmt.Errorf("query error: %w", err)
+	}
+
+	return student, nil
+}
+
+func (s *Sqlite) UnarchiveStudent(ctx context.Context, id int64) (types.Student, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return types.Student{}, err
+	}
+	defer tx.Rollback()
+
+	archived, err := scanArchived(tx.QueryRowContext(ctx, "SELECT "+archivedColumns+" FROM archived_students WHERE id = ? AND "+inTenant, scoped(ctx, id)...))
+	if err == sql.ErrNoRows {
+		return types.Student{}, storage.NotFound("no archived student found with id %d", id)
+	}
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO students ("+studentColumns+`)
+		SELECT id, tenant_id, name, email, age, created_at, ?, email_verified, email_verified_at FROM archived_students WHERE id = ?`, time.Now().UTC(), id)
+	if err != nil {
+		return types.Student{}, emailConflict(err, archived.Email)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM archived_students WHERE id = ?", id); err != nil {
+		return types.Student{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, newVersion, id); err != nil {
+		return types.Student{}, err
+	}
+
+	student, err := scanStudent(tx.QueryRowContext(ctx, "SELECT "+studentColumns+" FROM students WHERE id = ?", id))
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	return student, commit(ctx, tx)
+}
+
+// reserveArchivedIds keeps new students from being given the ids of the
+// archived ones restored in tx, which AUTOINCREMENT doesn't know about.
+func reserveArchivedIds(ctx context.Context, tx *sql.Tx) error {
+	var seq int64
+	err := tx.QueryRowContext(ctx, "SELECT seq FROM sqlite_sequence WHERE name = 'students'").Scan(&seq)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM sqlite_sequence WHERE name = 'students';
+	INSERT INTO sqlite_sequence (name, seq) SELECT 'students', MAX(?, COALESCE(MAX(id), 0)) FROM (SELECT id FROM students UNION ALL SELECT id FROM archived_students);`, seq)
+	return err
+}
@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// ErrNotEmpty is returned by Restore when the database already has data.
+var ErrNotEmpty = errors.New("database is not empty")
+
+// Restore loads students, the archived ones into the archive, and
+// webhooks with their original ids in one transaction. Unless replace is
+// set the database must be empty; with replace everything in it
+// (including the delivery log) is removed first.
+func (s *Sqlite) Restore(ctx context.Context, students []types.Student, webhooks []types.Webhook, replace bool) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := prepareRestore(ctx, tx, replace); err != nil {
+		return err
+	}
+
+	studentStmt, err := tx.PrepareContext(ctx, "INSERT INTO students (id, tenant_id, name, email, age, created_at, updated_at, email_verified, email_verified_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer studentStmt.Close()
+
+	archivedStmt, err := tx.PrepareContext(ctx, "INSERT INTO archived_students (id, tenant_id, name, email, age, created_at, updated_at, email_verified, email_verified_at, archived_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer archivedStmt.Close()
+
+	// backups from before timestamps and tenants were kept have none
+	now := time.Now().UTC()
+	for _, student := range students {
+		created, updated := orDefault(student.CreatedAt, now), orDefault(student.UpdatedAt, now)
+		verifiedAt := sql.NullTime{Time: student.EmailVerifiedAt.UTC(), Valid: !student.EmailVerifiedAt.IsZero()}
+		args := []any{student.Id, tenantOrDefault(student.TenantId), student.Name, student.Email, student.Age, created, updated, student.EmailVerified, verifiedAt}
+		if !student.ArchivedAt.IsZero() {
+			_, err = archivedStmt.ExecContext(ctx, append(args, student.ArchivedAt.UTC())...)
+		} else {
+			_, err = studentStmt.ExecContext(ctx, args...)
+		}
+		if err != nil {
+			return fmt.Errorf("restore student %d: %w", student.Id, err)
+		}
+	}
+
+	if err := reserveArchivedIds(ctx, tx); err != nil {
+		return err
+	}
+
+	// backups don't keep history; it starts again from what they hold
+	if _, err := tx.ExecContext(ctx, firstVersions); err != nil {
+		return err
+	}
+
+	webhookStmt, err := tx.PrepareContext(ctx, "INSERT INTO webhooks (id, tenant_id, url, secret, events, created_at) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer webhookStmt.Close()
+
+	for _, webhook := range webhooks {
+		if _, err := webhookStmt.ExecContext(ctx, webhook.Id, tenantOrDefault(webhook.TenantId), webhook.Url, webhook.Secret, strings.Join(webhook.Events, ","), webhook.CreatedAt.UTC()); err != nil {
+			return fmt.Errorf("restore webhook %d: %w", webhook.Id, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func orDefault(t, def time.Time) time.Time {
+	if t.IsZero() {
+		return def
+	}
+
+	return t.UTC()
+}
+
+func tenantOrDefault(id string) string {
+	if id == "" {
+		return tenant.Default
+	}
+
+	return id
+}
+
+// RestoreSQL runs a SQL dump written by backup.WriteSQL, with the same
+// rules as Restore. The dump's own BEGIN/COMMIT are replaced by the
+// transaction used here, so a failed restore leaves the database untouched.
+func (s *Sqlite) RestoreSQL(ctx context.Context, script string, replace bool) error {
+	body := strings.TrimSuffix(strings.TrimSpace(script), "COMMIT;")
+	body = strings.Replace(body, "\nBEGIN;\n", "\n", 1)
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := prepareRestore(ctx, tx, replace); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return err
+	}
+
+	if err := reserveArchivedIds(ctx, tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, firstVersions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// prepareRestore empties the database for a restore, or checks that it is empty.
+func prepareRestore(ctx context.Context, tx *sql.Tx, replace bool) error {
+	if replace {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM students; DELETE FROM student_versions; DELETE FROM webhooks; DELETE FROM webhook_deliveries; DELETE FROM student_files; DELETE FROM archived_students;"); err != nil {
+			return err
+		}
+	} else {
+		var n int
+		err := tx.QueryRowContext(ctx, "SELECT (SELECT COUNT(*) FROM students) + (SELECT COUNT(*) FROM archived_students) + (SELECT COUNT(*) FROM webhooks)").Scan(&n)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrNotEmpty
+		}
+	}
+
+	return nil
+}
+
+// Snapshot writes a consistent copy of the whole database to path while
+// it stays in use. path must not exist yet.
+func (s *Sqlite) Snapshot(ctx context.Context, path string) error {
+	_, err := s.Db.ExecContext(ctx, "VACUUM INTO ?", path)
+	return err
+}
+
+// Verify runs SQLite's integrity check, for databases such as snapshots
+// that are about to be restored.
+func (s *Sqlite) Verify(ctx context.Context) error {
+	var result string
+	if err := s.Db.QueryRowContext(ctx, "PRAGMA integrity_check(1)").Scan(&result); err != nil {
+		return err
+	}
+
+	if result != "ok" {
+		return fmt.Errorf("integrity check failed: %s", result)
+	}
+
+	return nil
+}
@@ -0,0 +1,345 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/tenant"
+)
+
+// Finding is the outcome of one integrity check.
+type Finding struct {
+	Check   string
+	Problem string // empty when the check passed
+	Fixed   bool
+}
+
+// ages outside this range are considered data errors
+const (
+	minAge = 1
+	maxAge = 120
+)
+
+// what Migrate creates; the unique index on students (tenant_id, email)
+// comes from the table constraint
+var (
+	tables  = []string{"students", "student_versions", "student_files", "archived_students", "webhooks", "webhook_deliveries", "jobs"}
+	indexes = []string{"idx_students_created_at", "idx_student_files_student_id", "idx_webhook_deliveries_webhook_id", "idx_jobs_status_run_at"}
+)
+
+// Doctor checks the database for problems left by manual edits or bugs.
+// Open the database with Open rather than New, or schema problems will
+// already have been papered over by Migrate.
+// With repair, problems that can be fixed without losing student data are
+// fixed; the rest (duplicates, bad values) are only reported.
+func (s *Sqlite) Doctor(ctx context.Context, repair bool) ([]Finding, error) {
+	findings, err := run(ctx, repair, s.checkIntegrity, s.checkSchema, s.checkSchemaVersion)
+	if err != nil {
+		return findings, err
+	}
+
+	missing, err := s.missing(ctx, tables)
+	if err != nil {
+		return findings, err
+	}
+	if len(missing) > 0 {
+		return append(findings, Finding{Check: "data", Problem: "not checked while tables are missing"}), nil
+	}
+
+	more, err := run(ctx, repair, s.checkOrphanedDeliveries, s.checkOrphanedFiles, s.checkDuplicateEmails, s.checkAges, s.checkEmptyFields)
+
+	return append(findings, more...), err
+}
+
+func run(ctx context.Context, repair bool, checks ...func(context.Context, bool) (Finding, error)) ([]Finding, error) {
+	var findings []Finding
+	for _, check := range checks {
+		f, err := check(ctx, repair)
+		if err != nil {
+			return findings, fmt.Errorf("%s: %w", f.Check, err)
+		}
+		findings = append(findings, f)
+	}
+
+	return findings, nil
+}
+
+// missing returns the tables or indexes that don't exist.
+func (s *Sqlite) missing(ctx context.Context, names []string) ([]string, error) {
+	var missing []string
+	for _, name := range names {
+		var n int
+		err := s.Db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE name = ?", name).Scan(&n)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing, nil
+}
+
+func (s *Sqlite) checkIntegrity(ctx context.Context, _ bool) (Finding, error) {
+	f := Finding{Check: "integrity"}
+
+	rows, err := s.Db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return f, err
+	}
+	defer rows.Close()
+
+	var msgs []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return f, err
+		}
+		if msg != "ok" {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	if len(msgs) > 0 {
+		f.Problem = strings.Join(msgs, "; ") + " (restore from a backup)"
+	}
+
+	return f, rows.Err()
+}
+
+func (s *Sqlite) checkSchemaVersion(ctx context.Context, repair bool) (Finding, error) {
+	f := Finding{Check: "schema version"}
+
+	var version int
+	if err := s.Db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return f, err
+	}
+
+	switch {
+	case version > SchemaVersion:
+		f.Problem = fmt.Sprintf("database has schema %d, newer than this build's %d", version, SchemaVersion)
+	case version < SchemaVersion:
+		f.Problem = fmt.Sprintf("database has schema %d, expected %d", version, SchemaVersion)
+		if repair {
+			err := s.Migrate()
+			f.Fixed = err == nil
+			return f, err
+		}
+	}
+
+	return f, nil
+}
+
+func (s *Sqlite) checkSchema(ctx context.Context, repair bool) (Finding, error) {
+	f := Finding{Check: "tables and indexes"}
+
+	missing, err := s.missing(ctx, append(slices.Clone(tables), indexes...))
+	if err != nil {
+		return f, err
+	}
+
+	var unique int
+	err = s.Db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pragma_index_list('students') WHERE \"unique\" = 1").Scan(&unique)
+	if err != nil {
+		return f, err
+	}
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(missing, ", "))
+	}
+	if unique == 0 && !slices.Contains(missing, "students") {
+		// needs a table rebuild, which is left to the operator
+		problems = append(problems, "students (tenant_id, email) has no unique index")
+	}
+
+	if len(problems) == 0 {
+		return f, nil
+	}
+
+	f.Problem = strings.Join(problems, "; ")
+	if repair && len(missing) > 0 {
+		if err := s.Migrate(); err != nil {
+			return f, err
+		}
+		// also rebuilds indexes that integrity_check found inconsistent
+		if _, err := s.Db.ExecContext(ctx, "REINDEX"); err != nil {
+			return f, err
+		}
+		f.Fixed = unique > 0 || slices.Contains(missing, "students")
+	}
+
+	return f, nil
+}
+
+func (s *Sqlite) checkOrphanedDeliveries(ctx context.Context, repair bool) (Finding, error) {
+	f := Finding{Check: "orphaned webhook deliveries"}
+
+	const orphaned = "FROM webhook_deliveries WHERE webhook_id NOT IN (SELECT id FROM webhooks)"
+
+	var n int
+	if err := s.Db.QueryRowContext(ctx, "SELECT COUNT(*) "+orphaned).Scan(&n); err != nil {
+		return f, err
+	}
+	if n == 0 {
+		return f, nil
+	}
+
+	f.Problem = fmt.Sprintf("%d deliveries belong to deleted webhooks", n)
+	if repair {
+		_, err := s.Db.ExecContext(ctx, "DELETE "+orphaned)
+		f.Fixed = err == nil
+		return f, err
+	}
+
+	return f, nil
+}
+
+func (s *Sqlite) checkOrphanedFiles(ctx context.Context, repair bool) (Finding, error) {
+	f := Finding{Check: "orphaned student files"}
+
+	const orphaned = "FROM student_files WHERE student_id NOT IN (SELECT id FROM students UNION ALL SELECT id FROM archived_students)"
+
+	var n int
+	if err := s.Db.QueryRowContext(ctx, "SELECT COUNT(*) "+orphaned).Scan(&n); err != nil {
+		return f, err
+	}
+	if n == 0 {
+		return f, nil
+	}
+
+	f.Problem = fmt.Sprintf("%d files belong to deleted students", n)
+	if !repair {
+		return f, nil
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return f, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT DISTINCT tenant_id, student_id "+orphaned)
+	if err != nil {
+		return f, err
+	}
+	defer rows.Close()
+
+	type owner struct {
+		tenantId  string
+		studentId int64
+	}
+	var owners []owner
+	for rows.Next() {
+		var o owner
+		if err := rows.Scan(&o.tenantId, &o.studentId); err != nil {
+			return f, err
+		}
+		owners = append(owners, o)
+	}
+	if err := rows.Err(); err != nil {
+		return f, err
+	}
+	rows.Close()
+
+	// deleted as with their students, contents by the file cleanup job
+	for _, o := range owners {
+		if err := deleteFiles(tenant.With(ctx, o.tenantId), tx, o.studentId); err != nil {
+			return f, err
+		}
+	}
+
+	err = commit(ctx, tx)
+	f.Fixed = err == nil
+	return f, err
+}
+
+func (s *Sqlite) checkDuplicateEmails(ctx context.Context, _ bool) (Finding, error) {
+	f := Finding{Check: "duplicate emails"}
+
+	// the unique index is case sensitive, people's inboxes are not
+	rows, err := s.Db.QueryContext(ctx, `SELECT LOWER(email), GROUP_CONCAT(id) FROM students
+		GROUP BY tenant_id, LOWER(email) HAVING COUNT(*) > 1 ORDER BY LOWER(email)`)
+	if err != nil {
+		return f, err
+	}
+	defer rows.Close()
+
+	var dups []string
+	for rows.Next() {
+		var email, ids string
+		if err := rows.Scan(&email, &ids); err != nil {
+			return f, err
+		}
+		dups = append(dups, fmt.Sprintf("%s (ids %s)", email, ids))
+	}
+
+	if len(dups) > 0 {
+		f.Problem = fmt.Sprintf("%d emails used more than once: %s", len(dups), limit(dups))
+	}
+
+	return f, rows.Err()
+}
+
+func (s *Sqlite) checkAges(ctx context.Context, _ bool) (Finding, error) {
+	f := Finding{Check: "ages"}
+
+	ids, err := s.ids(ctx, "SELECT id FROM students WHERE age < ? OR age > ? OR typeof(age) != 'integer' ORDER BY id", minAge, maxAge)
+	if err != nil {
+		return f, err
+	}
+
+	if len(ids) > 0 {
+		f.Problem = fmt.Sprintf("%d students with an age outside %d-%d: ids %s", len(ids), minAge, maxAge, limit(ids))
+	}
+
+	return f, nil
+}
+
+func (s *Sqlite) checkEmptyFields(ctx context.Context, _ bool) (Finding, error) {
+	f := Finding{Check: "required fields"}
+
+	ids, err := s.ids(ctx, "SELECT id FROM students WHERE TRIM(name) = '' OR TRIM(email) = '' ORDER BY id")
+	if err != nil {
+		return f, err
+	}
+
+	if len(ids) > 0 {
+		f.Problem = fmt.Sprintf("%d students with an empty name or email: ids %s", len(ids), limit(ids))
+	}
+
+	return f, nil
+}
+
+func (s *Sqlite) ids(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := s.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// limit keeps long lists readable
+func limit(items []string) string {
+	const max = 10
+
+	if len(items) <= max {
+		return strings.Join(items, ", ")
+	}
+
+	return strings.Join(items[:max], ", ") + fmt.Sprintf(" and %d more", len(items)-max)
+}
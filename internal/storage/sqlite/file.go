@@ -0,0 +1,139 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// studentFilesTable keeps what the files uploaded for students are; added
+// in schema 8.
+const studentFilesTable = `CREATE TABLE IF NOT EXISTS student_files (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tenant_id TEXT NOT NULL DEFAULT 'default',
+		student_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		blob_key TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_student_files_student_id ON student_files (student_id);`
+
+// fileColumns are the columns scanFile reads.
+const fileColumns = "id, student_id, name, content_type, size, blob_key, created_at"
+
+func scanFile(row interface{ Scan(...any) error }) (types.File, error) {
+	var f types.File
+	err := row.Scan(&f.Id, &f.StudentId, &f.Name, &f.ContentType, &f.Size, &f.Key, &f.CreatedAt)
+
+	return f, err
+}
+
+func (s *Sqlite) CreateFile(ctx context.Context, f types.File) (int64, error) {
+	result, err := s.execute(ctx, "INSERT INTO student_files (tenant_id, student_id, name, content_type, size, blob_key, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		tenant.From(ctx), f.StudentId, f.Name, f.ContentType, f.Size, f.Key, f.CreatedAt.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (s *Sqlite) GetFiles(ctx context.Context, studentId int64) ([]types.File, error) {
+	rows, err := s.Db.QueryContext(ctx, "SELECT "+fileColumns+" FROM student_files WHERE student_id = ? AND "+inTenant+" ORDER BY id", scoped(ctx, studentId)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []types.File
+	for rows.Next() {
+		f, err := scanFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	return files, rows.Err()
+}
+
+func (s *Sqlite) GetFile(ctx context.Context, studentId, id int64) (types.File, error) {
+	f, err := scanFile(s.Db.QueryRowContext(ctx, "SELECT "+fileColumns+" FROM student_files WHERE id = ? AND student_id = ? AND "+inTenant, scoped(ctx, id, studentId)...))
+	if err == sql.ErrNoRows {
+		return types.File{}, storage.NotFound("student %d has no file %d", studentId, id)
+	}
+	if err != nil {
+		return types.File{}, fmt.Errorf("query error: %w", err)
+	}
+
+	return f, nil
+}
+
+func (s *Sqlite) DeleteFile(ctx context.Context, studentId, id int64) error {
+	result, err := s.execute(ctx, "DELETE FROM student_files WHERE id = ? AND student_id = ? AND "+inTenant, scoped(ctx, id, studentId)...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return storage.NotFound("student %d has no file %d", studentId, id)
+	}
+
+	return nil
+}
+
+// deleteFiles deletes the files of student studentId in tx and queues a
+// job deleting their contents, as the student is deleted.
+func deleteFiles(ctx context.Context, tx *sql.Tx, studentId int64) error {
+	rows, err := tx.QueryContext(ctx, "SELECT blob_key FROM student_files WHERE student_id = ? AND "+inTenant+" ORDER BY id", scoped(ctx, studentId)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var cleanup storage.FileCleanup
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return err
+		}
+		cleanup.Keys = append(cleanup.Keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	if len(cleanup.Keys) == 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM student_files WHERE student_id = ? AND "+inTenant, scoped(ctx, studentId)...); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(cleanup)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	_, err = tx.ExecContext(ctx, `INSERT INTO jobs
+		(tenant_id, kind, payload, status, attempts, max_attempts, last_error, run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 0, ?, '', ?, ?, ?)`, tenant.From(ctx), storage.FileCleanupKind, string(payload), types.JobQueued, storage.FileCleanupAttempts, now, now, now)
+	return err
+}
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// studentVersionsTable keeps the versions of every student; added in
+// schema 7.
+const studentVersionsTable = `CREATE TABLE IF NOT EXISTS student_versions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tenant_id TEXT NOT NULL DEFAULT 'default',
+		student_id INTEGER NOT NULL,
+		version INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		email TEXT NOT NULL,
+		age INTEGER NOT NULL,
+		created_at DATETIME NOT NULL,
+		UNIQUE (student_id, version)
+	);`
+
+// newVersion stores the fields of student ? as its next version, unless
+// they are those of its latest one.
+const newVersion = `INSERT INTO student_versions (tenant_id, student_id, version, name, email, age, created_at)
+	SELECT tenant_id, id, COALESCE((SELECT MAX(version) FROM student_versions WHERE student_id = students.id), 0) + 1, name, email, age, updated_at
+	FROM students WHERE id = ? AND NOT EXISTS (
+		SELECT 1 FROM student_versions v WHERE v.student_id = students.id
+		AND v.version = (SELECT MAX(version) FROM student_versions WHERE student_id = students.id)
+		AND v.name = students.name AND v.email = students.email AND v.age = students.age
+	)`
+
+// currentVersion stores the fields of student ? as its next version even
+// if they are those of its latest one, for changes to the student beyond
+// them such as a merge.
+const currentVersion = `INSERT INTO student_versions (tenant_id, student_id, version, name, email, age, created_at)
+	SELECT tenant_id, id, COALESCE((SELECT MAX(version) FROM student_versions WHERE student_id = students.id), 0) + 1, name, email, age, updated_at
+	FROM students WHERE id = ?`
+
+// renumberVersions numbers the versions of student ? from 1 in the order
+// they were made, as after moving those of another student to it. Their
+// numbers must be distinct negative ones before, so none is taken while
+// they are renumbered.
+const renumberVersions = `UPDATE student_versions SET version = (
+		SELECT COUNT(*) FROM student_versions v WHERE v.student_id = student_versions.student_id
+		AND (v.created_at < student_versions.created_at OR (v.created_at = student_versions.created_at AND v.id <= student_versions.id))
+	) WHERE student_id = ?`
+
+// firstVersions stores the fields of the students without a version, such
+// as those from before schema 7 or a restore, as their first.
+const firstVersions = `INSERT INTO student_versions (tenant_id, student_id, version, name, email, age, created_at)
+	SELECT tenant_id, id, 1, name, email, age, updated_at FROM students
+	WHERE id NOT IN (SELECT student_id FROM student_versions)`
+
+// versionColumns are the columns scanVersion reads.
+const versionColumns = "student_id, version, name, email, age, created_at"
+
+func scanVersion(row interface{ Scan(...any) error }) (types.StudentVersion, error) {
+	var v types.StudentVersion
+	err := row.Scan(&v.StudentId, &v.Version, &v.Name, &v.Email, &v.Age, &v.CreatedAt)
+
+	return v, err
+}
+
+func (s *Sqlite) GetStudentVersions(ctx context.Context, id int64) ([]types.StudentVersion, error) {
+	if _, err := s.GetStudentById(ctx, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Db.QueryContext(ctx, "SELECT "+versionColumns+" FROM student_versions WHERE student_id = ? AND "+inTenant+" ORDER BY version", scoped(ctx, id)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []types.StudentVersion
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+func (s *Sqlite) GetStudentVersion(ctx context.Context, id int64, version int) (types.StudentVersion, error) {
+	v, err := scanVersion(s.Db.QueryRowContext(ctx, "SELECT "+versionColumns+" FROM student_versions WHERE student_id = ? AND version = ? AND "+inTenant, scoped(ctx, id, version)...))
+	if err == sql.ErrNoRows {
+		if _, err := s.GetStudentById(ctx, id); err != nil {
+			return types.StudentVersion{}, err
+		}
+
+		return types.StudentVersion{}, storage.NotFound("student %d has no version %d", id, version)
+	}
+	if err != nil {
+		return types.StudentVersion{}, fmt.Errorf("query error: %w", err)
+	}
+
+	return v, nil
+}
+
+// commit commits tx, unless ctx is a dry run: its changes are then rolled
+// back, having been checked against the constraints.
+func commit(ctx context.Context, tx *sql.Tx) error {
+	if dryrun.Enabled(ctx) {
+		return nil
+	}
+
+	return tx.Commit()
+}
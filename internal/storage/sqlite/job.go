@@ -0,0 +1,277 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+const jobColumns = "id, tenant_id, kind, payload, status, attempts, max_attempts, last_error, progress_done, progress_total, result, run_at, created_at, updated_at"
+
+func (s *Sqlite) CreateJob(ctx context.Context, job types.Job) (int64, error) {
+	stmt, err := s.Db.PrepareContext(ctx, `INSERT INTO jobs
+		(tenant_id, kind, payload, status, attempts, max_attempts, last_error, run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 0, ?, '', ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+
+	result, err := stmt.ExecContext(ctx, tenant.From(ctx), job.Kind, string(job.Payload), types.JobQueued, job.MaxAttempts, job.RunAt.UTC(), now, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (s *Sqlite) ClaimJob(ctx context.Context, now time.Time, lease time.Duration) (types.Job, bool, error) {
+	now = now.UTC()
+
+	row := s.Db.QueryRowContext(ctx, `UPDATE jobs SET status = ?, attempts = attempts + 1, locked_until = ?, updated_at = ?
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE ((status = ? AND run_at <= ?) OR (status = ? AND locked_until <= ?)) AND `+inTenant+`
+			ORDER BY run_at, id LIMIT 1
+		)
+		RETURNING `+jobColumns,
+		scoped(ctx, types.JobRunning, now.Add(lease), now,
+			types.JobQueued, now, types.JobRunning, now)...)
+
+	job, err := scanJob(row)
+	if err == sql.ErrNoRows {
+		return types.Job{}, false, nil
+	}
+	if err != nil {
+		return types.Job{}, false, err
+	}
+
+	return job, true, nil
+}
+
+func (s *Sqlite) CompleteJob(ctx context.Context, id int64) error {
+	return s.finishJob(ctx, id, types.JobDone, time.Time{}, "")
+}
+
+func (s *Sqlite) RescheduleJob(ctx context.Context, id int64, runAt time.Time, reason string) error {
+	return s.finishJob(ctx, id, types.JobQueued, runAt, reason)
+}
+
+func (s *Sqlite) DeadLetterJob(ctx context.Context, id int64, reason string) error {
+	return s.finishJob(ctx, id, types.JobDead, time.Time{}, reason)
+}
+
+func (s *Sqlite) PostponeJob(ctx context.Context, id int64, runAt time.Time) error {
+	_, err := s.Db.ExecContext(ctx, `UPDATE jobs SET status = ?, attempts = attempts - 1, run_at = ?, locked_until = NULL, updated_at = ?
+		WHERE id = ? AND status = ? AND `+inTenant,
+		scoped(ctx, types.JobQueued, runAt.UTC(), time.Now().UTC(), id, types.JobRunning)...)
+
+	return err
+}
+
+// finishJob ends the running attempt of a job. A zero runAt keeps run_at,
+// and the last error is only replaced by a new one.
+func (s *Sqlite) finishJob(ctx context.Context, id int64, status string, runAt time.Time, reason string) error {
+	now := time.Now().UTC()
+
+	var at any
+	if !runAt.IsZero() {
+		at = runAt.UTC()
+	}
+
+	_, err := s.Db.ExecContext(ctx, `UPDATE jobs SET status = ?, run_at = COALESCE(?, run_at),
+		last_error = CASE WHEN ? = '' THEN last_error ELSE ? END, locked_until = NULL, updated_at = ?
+		WHERE id = ? AND status = ? AND `+inTenant,
+		scoped(ctx, status, at, reason, reason, now, id, types.JobRunning)...)
+
+	return err
+}
+
+// RequeueJob gives a dead job a fresh set of attempts, starting now.
+func (s *Sqlite) RequeueJob(ctx context.Context, id int64) error {
+	now := time.Now().UTC()
+
+	result, err := s.Db.ExecContext(ctx, `UPDATE jobs SET status = ?, attempts = 0, progress_done = 0, progress_total = 0, result = '', output = NULL, run_at = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND `+inTenant,
+		scoped(ctx, types.JobQueued, now, now, id, types.JobDead)...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return storage.NotFound("no dead job found with id %d", id)
+	}
+
+	return nil
+}
+
+func (s *Sqlite) UpdateJobProgress(ctx context.Context, id int64, progress types.JobProgress) error {
+	_, err := s.Db.ExecContext(ctx, "UPDATE jobs SET progress_done = ?, progress_total = ?, updated_at = ? WHERE id = ? AND "+inTenant,
+		scoped(ctx, progress.Done, progress.Total, time.Now().UTC(), id)...)
+
+	return err
+}
+
+func (s *Sqlite) SetJobResult(ctx context.Context, id int64, result []byte, output []byte) error {
+	_, err := s.Db.ExecContext(ctx, "UPDATE jobs SET result = ?, output = ?, updated_at = ? WHERE id = ? AND "+inTenant,
+		scoped(ctx, string(result), output, time.Now().UTC(), id)...)
+
+	return err
+}
+
+// GetJobOutput returns what the job stored for download; nil if nothing.
+func (s *Sqlite) GetJobOutput(ctx context.Context, id int64) ([]byte, error) {
+	var output []byte
+
+	err := s.Db.QueryRowContext(ctx, "SELECT output FROM jobs WHERE id = ? AND "+inTenant, scoped(ctx, id)...).Scan(&output)
+	if err == sql.ErrNoRows {
+		return nil, storage.NotFound("no job found with id %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+
+	return output, nil
+}
+
+func (s *Sqlite) GetJob(ctx context.Context, id int64) (types.Job, error) {
+	job, err := scanJob(s.Db.QueryRowContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE id = ? AND "+inTenant, scoped(ctx, id)...))
+	if err == sql.ErrNoRows {
+		return types.Job{}, storage.NotFound("no job found with id %d", id)
+	}
+	if err != nil {
+		return types.Job{}, fmt.Errorf("query error: %w", err)
+	}
+
+	return job, nil
+}
+
+// GetJobList returns the most recent jobs, newest first. Empty status or
+// kind match any.
+func (s *Sqlite) GetJobList(ctx context.Context, status, kind string, limit int) ([]types.Job, error) {
+	rows, err := s.Db.QueryContext(ctx, `SELECT `+jobColumns+` FROM jobs
+		WHERE (? = '' OR status = ?) AND (? = '' OR kind = ?) AND `+inTenant+`
+		ORDER BY id DESC LIMIT ?`, append(scoped(ctx, status, status, kind, kind), limit)...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	jobs := []types.Job{}
+
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// CountJobs returns the number of jobs in each status.
+func (s *Sqlite) CountJobs(ctx context.Context) (map[string]int, error) {
+	counts := map[string]int{types.JobQueued: 0, types.JobRunning: 0, types.JobDone: 0, types.JobDead: 0}
+
+	rows, err := s.Db.QueryContext(ctx, "SELECT status, COUNT(*) FROM jobs WHERE "+inTenant+" GROUP BY status", scoped(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+
+	return counts, rows.Err()
+}
+
+func scanJob(row interface{ Scan(...any) error }) (types.Job, error) {
+	var job types.Job
+	var payload, result string
+
+	err := row.Scan(&job.Id, &job.TenantId, &job.Kind, &payload, &job.Status, &job.Attempts, &job.MaxAttempts, &job.LastError,
+		&job.Progress.Done, &job.Progress.Total, &result, &job.RunAt, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return types.Job{}, err
+	}
+
+	job.Payload = []byte(payload)
+	if result != "" {
+		job.Result = []byte(result)
+	}
+
+	return job, nil
+}
+
+// PurgeJobs removes the jobs in status last updated before before.
+func (s *Sqlite) PurgeJobs(ctx context.Context, status string, before time.Time) (int64, error) {
+	result, err := s.Db.ExecContext(ctx, "DELETE FROM jobs WHERE status = ? AND updated_at < ? AND "+inTenant, scoped(ctx, status, before.UTC())...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// MoveJobs moves the queued jobs of kind, of every tenant, to the queue of
+// to, for databases whose own jobs aren't run. It returns how many moved.
+func (s *Sqlite) MoveJobs(ctx context.Context, to storage.JobStorage, kind string) (int, error) {
+	rows, err := s.Db.QueryContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE kind = ? AND status = ? ORDER BY id", kind, types.JobQueued)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var jobs []types.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return 0, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	// each job is deleted once queued in to, so none is lost on failure
+	for i, job := range jobs {
+		if _, err := to.CreateJob(tenant.With(ctx, job.TenantId), job); err != nil {
+			return i, err
+		}
+		if _, err := s.Db.ExecContext(ctx, "DELETE FROM jobs WHERE id = ?", job.Id); err != nil {
+			return i, err
+		}
+	}
+
+	return len(jobs), nil
+}
@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+func (s *Sqlite) ReportStudents(ctx context.Context, query storage.ReportQuery) ([]types.ReportGroup, error) {
+	// the same keys as storage.ReportQuery.Key; SQLite divides integers
+	// towards zero like Go
+	var key string
+	var args []any
+	switch query.GroupBy {
+	case storage.ReportByAgeBucket:
+		key, args = "age / ? * ?", []any{query.BucketSize, query.BucketSize}
+	case storage.ReportByStatus:
+		// the driver would scan the BOOLEAN column as a bool
+		key = "CAST(email_verified AS INTEGER)"
+	default:
+		return nil, fmt.Errorf("unknown report grouping %q", query.GroupBy)
+	}
+
+	from := "students"
+	if query.Filter.IncludeArchived {
+		from = allStudents
+	}
+
+	where, whereArgs := filterWhere(ctx, query.Filter)
+	rows, err := s.Db.QueryContext(ctx, "SELECT "+key+` AS report_key, COUNT(*), SUM(email_verified), ROUND(AVG(age), 2), MIN(age), MAX(age)
+		FROM `+from+` WHERE `+where+" GROUP BY report_key ORDER BY report_key", append(args, whereArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []types.ReportGroup
+	for rows.Next() {
+		var k int
+		var g types.ReportGroup
+		if err := rows.Scan(&k, &g.Count, &g.Verified, &g.AverageAge, &g.MinAge, &g.MaxAge); err != nil {
+			return nil, err
+		}
+		g.Group = query.Label(k)
+		groups = append(groups, g)
+	}
+
+	return groups, rows.Err()
+}
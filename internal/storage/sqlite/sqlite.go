@@ -1,32 +1,47 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/cmanish049/students-api/internal/config"
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/tenant"
 	"github.com/cmanish049/students-api/internal/types"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
+// SchemaVersion is stored in PRAGMA user_version; bump it together with
+// any change to the tables created by New.
+const SchemaVersion = 9
+
 type Sqlite struct {
 	Db *sql.DB
 }
 
 func New(cfg *config.Config) (*Sqlite, error) {
-	db, err := sql.Open("sqlite3", cfg.StoragePath)
-
+	s, err := Open(cfg.StoragePath)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS students (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		email TEXT NOT NULL UNIQUE,
-		age INTEGER NOT NULL
-	);`)
+	if err := s.Migrate(); err != nil {
+		s.Db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
 
+// Open opens the database without touching the schema.
+func Open(path string) (*Sqlite, error) {
+	db, err := sql.Open("sqlite3", path)
 	if err != nil {
 		return nil, err
 	}
@@ -36,43 +51,293 @@ func New(cfg *config.Config) (*Sqlite, error) {
 	}, nil
 }
 
-func (s *Sqlite) CreateStudent(name, email string, age int) (int64, error) {
-	stmt, err := s.Db.Prepare("INSERT INTO students (name, email, age) VALUES (?, ?, ?)")
+// Ping checks that the database answers queries on the students table.
+func (s *Sqlite) Ping(ctx context.Context) error {
+	_, err := s.Db.ExecContext(ctx, "SELECT 1 FROM students LIMIT 1")
+	return err
+}
+
+// Migrate creates the tables and indexes that are missing.
+func (s *Sqlite) Migrate() error {
+	db := s.Db
+
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS students " + studentsTable)
+
 	if err != nil {
-		return 0, err
+		return err
+	}
+
+	// added in schema 4; older students get the time of the migration
+	if err := s.addColumns("students", map[string]string{"created_at": "DATETIME", "updated_at": "DATETIME"}); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	_, err = db.Exec(`UPDATE students SET created_at = ? WHERE created_at IS NULL;
+	UPDATE students SET updated_at = created_at WHERE updated_at IS NULL;`, now)
+
+	if err != nil {
+		return err
+	}
+
+	// added in schema 5
+	if err := s.addStudentTenants(); err != nil {
+		return err
+	}
+
+	// added in schema 6; older students are unverified
+	if err := s.addColumns("students", map[string]string{
+		"email_verified":          "BOOLEAN NOT NULL DEFAULT 0",
+		"email_verified_at":       "DATETIME",
+		"verification_token":      "TEXT",
+		"verification_expires_at": "DATETIME",
+	}); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_students_created_at ON students (tenant_id, created_at);"); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(studentVersionsTable); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(firstVersions); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(studentFilesTable); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(archivedStudentsTable); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tenant_id TEXT NOT NULL DEFAULT 'default',
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tenant_id TEXT NOT NULL DEFAULT 'default',
+		webhook_id INTEGER NOT NULL,
+		event_id INTEGER NOT NULL,
+		event_type TEXT NOT NULL,
+		attempt INTEGER NOT NULL,
+		status_code INTEGER NOT NULL,
+		error TEXT NOT NULL,
+		success INTEGER NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);`)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tenant_id TEXT NOT NULL DEFAULT 'default',
+		kind TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL,
+		max_attempts INTEGER NOT NULL,
+		last_error TEXT NOT NULL,
+		progress_done INTEGER NOT NULL DEFAULT 0,
+		progress_total INTEGER NOT NULL DEFAULT 0,
+		result TEXT NOT NULL DEFAULT '',
+		output BLOB,
+		run_at DATETIME NOT NULL,
+		locked_until DATETIME,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_jobs_status_run_at ON jobs (status, run_at);`)
+
+	if err != nil {
+		return err
+	}
+
+	// added in schema 3
+	err = s.addColumns("jobs", map[string]string{
+		"progress_done":  "INTEGER NOT NULL DEFAULT 0",
+		"progress_total": "INTEGER NOT NULL DEFAULT 0",
+		"result":         "TEXT NOT NULL DEFAULT ''",
+		"output":         "BLOB",
+	})
+	if err != nil {
+		return err
+	}
+
+	// added in schema 5; what existed before belongs to the default tenant
+	for _, table := range []string{"webhooks", "webhook_deliveries", "jobs"} {
+		if err := s.addColumns(table, map[string]string{"tenant_id": "TEXT NOT NULL DEFAULT 'default'"}); err != nil {
+			return err
+		}
+	}
+
+	// record the schema the tables above correspond to, never downgrading it
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version < SchemaVersion {
+		if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addColumns adds the columns a table created by an older schema lacks.
+func (s *Sqlite) addColumns(table string, columns map[string]string) error {
+	for _, name := range slices.Sorted(maps.Keys(columns)) {
+		var n int
+		err := s.Db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, name).Scan(&n)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+
+		if _, err := s.Db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, name, columns[name])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// studentsTable is the definition of the students table. Emails are
+// unique within a tenant.
+const studentsTable = `(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tenant_id TEXT NOT NULL DEFAULT 'default',
+		name TEXT NOT NULL,
+		email TEXT NOT NULL,
+		age INTEGER NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		email_verified BOOLEAN NOT NULL DEFAULT 0,
+		email_verified_at DATETIME,
+		verification_token TEXT,
+		verification_expires_at DATETIME,
+		UNIQUE (tenant_id, email)
+	)`
+
+// addStudentTenants rebuilds a students table from before tenants, whose
+// emails are unique across the whole table, giving its students to the
+// default tenant. Ids, and the ids never to be reused, are kept.
+func (s *Sqlite) addStudentTenants() error {
+	var n int
+	err := s.Db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('students') WHERE name = 'tenant_id'").Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var seq int64
+	err = tx.QueryRow("SELECT seq FROM sqlite_sequence WHERE name = 'students'").Scan(&seq)
+	if err != nil && err != sql.ErrNoRows {
+		return err
 	}
-	defer stmt.Close()
 
-	result, err := stmt.Exec(name, email, age)
+	_, err = tx.Exec(`CREATE TABLE students_tenants ` + studentsTable + `;
+	INSERT INTO students_tenants (id, tenant_id, name, email, age, created_at, updated_at)
+		SELECT id, 'default', name, email, age, created_at, updated_at FROM students;
+	DROP TABLE students;
+	ALTER TABLE students_tenants RENAME TO students;
+	DELETE FROM sqlite_sequence WHERE name = 'students';`)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("INSERT INTO sqlite_sequence (name, seq) SELECT 'students', MAX(?, COALESCE(MAX(id), 0)) FROM students", seq); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// inTenant ends the WHERE clause of every query on a tenant's data, with
+// the arguments added by scoped.
+const inTenant = "(? OR tenant_id = ?)"
+
+// scoped appends the arguments of inTenant to args: queries with a
+// tenant.All context span every tenant, others see only the tenant of ctx.
+func scoped(ctx context.Context, args ...any) []any {
+	return append(args, tenant.IsAll(ctx), tenant.From(ctx))
+}
+
+// studentColumns are the columns scanStudent reads.
+const studentColumns = "id, tenant_id, name, email, age, created_at, updated_at, email_verified, email_verified_at"
+
+func scanStudent(row interface{ Scan(...any) error }) (types.Student, error) {
+	var student types.Student
+	var verifiedAt sql.NullTime
+	err := row.Scan(&student.Id, &student.TenantId, &student.Name, &student.Email, &student.Age, &student.CreatedAt, &student.UpdatedAt, &student.EmailVerified, &verifiedAt)
+	student.EmailVerifiedAt = verifiedAt.Time
+
+	return student, err
+}
+
+func (s *Sqlite) CreateStudent(ctx context.Context, name, email string, age int) (int64, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	result, err := tx.ExecContext(ctx, "INSERT INTO students (tenant_id, name, email, age, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)", tenant.From(ctx), name, email, age, now, now)
+	if err != nil {
+		return 0, emailConflict(err, email)
+	}
 
 	id, err := result.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
 
-	return id, nil
+	if _, err := tx.ExecContext(ctx, newVersion, id); err != nil {
+		return 0, err
+	}
+
+	return id, commit(ctx, tx)
 }
 
-func (s *Sqlite) GetStudentById(id int64) (types.Student, error) {
+func (s *Sqlite) GetStudentById(ctx context.Context, id int64) (types.Student, error) {
 
-	stmt, err := s.Db.Prepare("SELECT id, name, email, age FROM students WHERE id = ? limit 1")
+	stmt, err := s.Db.PrepareContext(ctx, "SELECT "+studentColumns+" FROM students WHERE id = ? AND "+inTenant+" limit 1")
 
 	if err != nil {
 		return types.Student{}, err
 	}
 	defer stmt.Close()
 
-	var student types.Student
-
-	row := stmt.QueryRow(id)
+	row := stmt.QueryRowContext(ctx, scoped(ctx, id)...)
 
-	err = row.Scan(&student.Id, &student.Name, &student.Email, &student.Age)
+	student, err := scanStudent(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return types.Student{}, fmt.Errorf("no student found with id %d", id)
+			return types.Student{}, storage.NotFound("no student found with id %d", id)
 		}
 
 		return types.Student{}, fmt.Errorf("query error: %w", err)
@@ -81,14 +346,49 @@ func (s *Sqlite) GetStudentById(id int64) (types.Student, error) {
 	return student, nil
 }
 
-func (s *Sqlite) GetStudentList() ([]types.Student, error) {
-	stmt, err := s.Db.Prepare("SELECT id, name, email, age FROM students")
+func (s *Sqlite) GetStudentByEmail(ctx context.Context, email string) (types.Student, error) {
+	row := s.Db.QueryRowContext(ctx, "SELECT "+studentColumns+" FROM students WHERE email = ? AND "+inTenant+" limit 1", scoped(ctx, email)...)
+
+	student, err := scanStudent(row)
+	if err == sql.ErrNoRows {
+		return types.Student{}, storage.NotFound("no student found with email %s", email)
+	}
+	if err != nil {
+		return types.Student{}, fmt.Errorf("query error: %w", err)
+	}
+
+	return student, nil
+}
+
+func (s *Sqlite) GetStudentList(ctx context.Context, filter storage.StudentFilter) ([]types.Student, error) {
+	where, args := filterWhere(ctx, filter)
+
+	students, err := s.queryStudents(ctx, "SELECT "+studentColumns+" FROM students WHERE "+where, args, scanStudent)
+	if err != nil || !filter.IncludeArchived {
+		return students, err
+	}
+
+	// a UNION would lose the column types the driver scans times by
+	archived, err := s.queryStudents(ctx, "SELECT "+archivedColumns+" FROM archived_students WHERE "+where, args, scanArchived)
+	if err != nil {
+		return nil, err
+	}
+
+	students = append(students, archived...)
+	slices.SortFunc(students, func(a, b types.Student) int { return a.Id - b.Id })
+
+	return students, nil
+}
+
+// queryStudents runs query and reads every row with scan.
+func (s *Sqlite) queryStudents(ctx context.Context, query string, args []any, scan func(interface{ Scan(...any) error }) (types.Student, error)) ([]types.Student, error) {
+	stmt, err := s.Db.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query()
+	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -98,8 +398,7 @@ func (s *Sqlite) GetStudentList() ([]types.Student, error) {
 	var students []types.Student
 
 	for rows.Next() {
-		var student types.Student
-		err := rows.Scan(&student.Id, &student.Name, &student.Email, &student.Age)
+		student, err := scan(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -113,14 +412,71 @@ func (s *Sqlite) GetStudentList() ([]types.Student, error) {
 	return students, nil
 }
 
-func (s *Sqlite) UpdateStudent(id int64, name, email string, age int) error {
-	stmt, err := s.Db.Prepare("UPDATE students SET name = ?, email = ?, age = ? WHERE id = ?")
+// filterWhere returns the WHERE clause that keeps the students of the
+// tenant of ctx that pass filter, and its arguments.
+func filterWhere(ctx context.Context, filter storage.StudentFilter) (string, []any) {
+	where := inTenant
+	args := scoped(ctx)
+
+	if !filter.CreatedAfter.IsZero() {
+		where += " AND created_at > ?"
+		args = append(args, filter.CreatedAfter.UTC())
+	}
+	if !filter.CreatedBefore.IsZero() {
+		where += " AND created_at < ?"
+		args = append(args, filter.CreatedBefore.UTC())
+	}
+	if filter.EmailVerified != nil {
+		where += " AND email_verified = ?"
+		args = append(args, *filter.EmailVerified)
+	}
+
+	return where, args
+}
+
+func (s *Sqlite) UpdateStudent(ctx context.Context, id int64, name, email string, age int) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
+
+	// a changed email is unverified; the right-hand sides see the old row
+	result, err := tx.ExecContext(ctx, `UPDATE students SET name = ?, age = ?, updated_at = ?,
+		email_verified = email_verified AND email = ?,
+		email_verified_at = CASE WHEN email = ? THEN email_verified_at END,
+		verification_token = CASE WHEN email = ? THEN verification_token END,
+		verification_expires_at = CASE WHEN email = ? THEN verification_expires_at END,
+		email = ?
+		WHERE id = ? AND `+inTenant, scoped(ctx, name, age, time.Now().UTC(), email, email, email, email, email, id)...)
+	if err != nil {
+		return emailConflict(err, email)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return storage.NotFound("no student found with id %d", id)
+	}
+
+	if _, err := tx.ExecContext(ctx, newVersion, id); err != nil {
+		return err
+	}
 
-	result, err := stmt.Exec(name, email, age, id)
+	return commit(ctx, tx)
+}
+
+func (s *Sqlite) DeleteStudent(ctx context.Context, id int64) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM students WHERE id = ? AND "+inTenant, scoped(ctx, id)...)
 	if err != nil {
 		return err
 	}
@@ -131,20 +487,91 @@ func (s *Sqlite) UpdateStudent(id int64, name, email string, age int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("no student found with id %d", id)
+		return storage.NotFound("no student found with id %d", id)
 	}
 
-	return nil
+	if _, err := tx.ExecContext(ctx, "DELETE FROM student_versions WHERE student_id = ?", id); err != nil {
+		return err
+	}
+
+	if err := deleteFiles(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return commit(ctx, tx)
 }
 
-func (s *Sqlite) DeleteStudent(id int64) error {
-	stmt, err := s.Db.Prepare("DELETE FROM students WHERE id = ?")
+func (s *Sqlite) MergeStudents(ctx context.Context, id, otherId int64) (types.Student, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return types.Student{}, err
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	result, err := stmt.Exec(id)
+	get := func(sid int64) (types.Student, error) {
+		student, err := scanStudent(tx.QueryRowContext(ctx, "SELECT "+studentColumns+" FROM students WHERE id = ? AND "+inTenant, scoped(ctx, sid)...))
+		if err == sql.ErrNoRows {
+			return types.Student{}, storage.NotFound("no student found with id %d", sid)
+		}
+		return student, err
+	}
+
+	student, err := get(id)
+	if err != nil {
+		return types.Student{}, err
+	}
+	other, err := get(otherId)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	// deleted first so id can take its email
+	if _, err := tx.ExecContext(ctx, "DELETE FROM students WHERE id = ? AND "+inTenant, scoped(ctx, otherId)...); err != nil {
+		return types.Student{}, err
+	}
+
+	// a verified email of otherId replaces an unverified one of id, as the
+	// address known to reach the student, with its verification
+	if other.EmailVerified && !student.EmailVerified {
+		_, err = tx.ExecContext(ctx, `UPDATE students SET email = ?, email_verified = 1, email_verified_at = ?,
+			verification_token = NULL, verification_expires_at = NULL WHERE id = ? AND `+inTenant, scoped(ctx, other.Email, other.EmailVerifiedAt.UTC(), id)...)
+		if err != nil {
+			return types.Student{}, emailConflict(err, other.Email)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE students SET created_at = MIN(created_at, ?), updated_at = ? WHERE id = ? AND "+inTenant, scoped(ctx, other.CreatedAt.UTC(), time.Now().UTC(), id)...)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	// the history of otherId joins that of id, in the order it was made,
+	// and the merge is the latest version
+	if _, err := tx.ExecContext(ctx, "UPDATE student_versions SET student_id = ?, version = -id WHERE student_id IN (?, ?)", id, id, otherId); err != nil {
+		return types.Student{}, err
+	}
+	if _, err := tx.ExecContext(ctx, renumberVersions, id); err != nil {
+		return types.Student{}, err
+	}
+	if _, err := tx.ExecContext(ctx, currentVersion, id); err != nil {
+		return types.Student{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE student_files SET student_id = ? WHERE student_id = ?", id, otherId); err != nil {
+		return types.Student{}, err
+	}
+
+	// dry runs see the result of the merge, which is rolled back
+	merged, err := get(id)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	return merged, commit(ctx, tx)
+}
+
+func (s *Sqlite) SetVerificationToken(ctx context.Context, id int64, tokenHash string, expiresAt time.Time) error {
+	result, err := s.execute(ctx, "UPDATE students SET verification_token = ?, verification_expires_at = ? WHERE id = ? AND "+inTenant, scoped(ctx, tokenHash, expiresAt.UTC(), id)...)
 	if err != nil {
 		return err
 	}
@@ -155,8 +582,130 @@ func (s *Sqlite) DeleteStudent(id int64) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("no student found with id %d", id)
+		return storage.NotFound("no student found with id %d", id)
+	}
+
+	return nil
+}
+
+func (s *Sqlite) VerifyEmail(ctx context.Context, tokenHash string, now time.Time) (types.Student, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return types.Student{}, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM students WHERE verification_token = ? AND verification_expires_at > ? AND "+inTenant, scoped(ctx, tokenHash, now.UTC())...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return types.Student{}, storage.NotFound("invalid or expired verification token")
+	}
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE students SET email_verified = 1, email_verified_at = ?, verification_token = NULL, verification_expires_at = NULL WHERE id = ?", now.UTC(), id)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	student, err := scanStudent(tx.QueryRowContext(ctx, "SELECT "+studentColumns+" FROM students WHERE id = ?", id))
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	if dryrun.Enabled(ctx) {
+		return student, nil
+	}
+
+	return student, tx.Commit()
+}
+
+// emailConflict turns a violation of the unique email constraint into an
+// error matching storage.ErrConflict.
+func emailConflict(err error, email string) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return storage.Conflict("email %s is already used by another student", email)
+	}
+
+	return err
+}
+
+// execute runs a mutating statement. For dry runs it runs in a transaction
+// that is rolled back, so constraints are still checked and the result
+// (ids, affected rows) is what a real run would see.
+func (s *Sqlite) execute(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if !dryrun.Enabled(ctx) {
+		stmt, err := s.Db.PrepareContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		defer stmt.Close()
+
+		return stmt.ExecContext(ctx, args...)
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return tx.ExecContext(ctx, query, args...)
+}
+
+// CreateStudents inserts students in batches of one transaction each, which
+// is much faster than CreateStudent for bulk loads.
+func (s *Sqlite) CreateStudents(ctx context.Context, students []types.Student) error {
+	const batchSize = 1000
+
+	for start := 0; start < len(students); start += batchSize {
+		end := min(start+batchSize, len(students))
+
+		if err := s.createBatch(ctx, students[start:end]); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+func (s *Sqlite) createBatch(ctx context.Context, students []types.Student) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO students (tenant_id, name, email, age, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	versionStmt, err := tx.PrepareContext(ctx, newVersion)
+	if err != nil {
+		return err
+	}
+	defer versionStmt.Close()
+
+	now := time.Now().UTC()
+	for _, student := range students {
+		result, err := stmt.ExecContext(ctx, tenant.From(ctx), student.Name, student.Email, student.Age, now, now)
+		if err != nil {
+			return fmt.Errorf("insert %s: %w", student.Email, err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		if _, err := versionStmt.ExecContext(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,24 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cmanish049/students-api/pkg/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.TestStorage(t, func(t *testing.T) storagetest.Store {
+		s, err := Open(filepath.Join(t.TempDir(), "students.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Db.Close() })
+
+		if err := s.Migrate(); err != nil {
+			t.Fatal(err)
+		}
+
+		return s
+	})
+}
@@ -0,0 +1,149 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+func (s *Sqlite) CreateWebhook(ctx context.Context, url, secret string, events []string) (int64, error) {
+	stmt, err := s.Db.PrepareContext(ctx, "INSERT INTO webhooks (tenant_id, url, secret, events, created_at) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, tenant.From(ctx), url, secret, strings.Join(events, ","), time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (s *Sqlite) GetWebhookList(ctx context.Context) ([]types.Webhook, error) {
+	stmt, err := s.Db.PrepareContext(ctx, "SELECT id, tenant_id, url, secret, events, created_at FROM webhooks WHERE "+inTenant)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, scoped(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var webhooks []types.Webhook
+
+	for rows.Next() {
+		var webhook types.Webhook
+		var events string
+		err := rows.Scan(&webhook.Id, &webhook.TenantId, &webhook.Url, &webhook.Secret, &events, &webhook.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		webhook.Events = []string{}
+		if events != "" {
+			webhook.Events = strings.Split(events, ",")
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (s *Sqlite) DeleteWebhook(ctx context.Context, id int64) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ? AND "+inTenant, scoped(ctx, id)...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return storage.NotFound("no webhook found with id %d", id)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE webhook_id = ? AND "+inTenant, scoped(ctx, id)...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Sqlite) CreateWebhookDelivery(ctx context.Context, d types.WebhookDelivery) error {
+	stmt, err := s.Db.PrepareContext(ctx, `INSERT INTO webhook_deliveries
+		(tenant_id, webhook_id, event_id, event_type, attempt, status_code, error, success, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, tenant.From(ctx), d.WebhookId, d.EventId, d.EventType, d.Attempt, d.StatusCode, d.Error, d.Success, d.CreatedAt)
+
+	return err
+}
+
+func (s *Sqlite) GetWebhookDeliveries(ctx context.Context, webhookId int64, limit int) ([]types.WebhookDelivery, error) {
+	stmt, err := s.Db.PrepareContext(ctx, `SELECT id, webhook_id, event_id, event_type, attempt, status_code, error, success, created_at
+		FROM webhook_deliveries WHERE webhook_id = ? AND `+inTenant+` ORDER BY id DESC LIMIT ?`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, append(scoped(ctx, webhookId), limit)...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var deliveries []types.WebhookDelivery
+
+	for rows.Next() {
+		var d types.WebhookDelivery
+		err := rows.Scan(&d.Id, &d.WebhookId, &d.EventId, &d.EventType, &d.Attempt, &d.StatusCode, &d.Error, &d.Success, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (s *Sqlite) PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.Db.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE created_at < ? AND "+inTenant, scoped(ctx, before.UTC())...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -1,15 +1,245 @@
 package storage
 
-import "github.com/cmanish049/students-api/internal/types"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// errors returned by storages are matched against these with errors.Is
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is a write that would break a unique constraint
+	ErrConflict = errors.New("conflict")
+)
+
+// kindError keeps the message of an error while making it match kind.
+type kindError struct {
+	kind   error
+	format string
+	args   []any
+}
+
+func (e *kindError) Error() string {
+	return fmt.Sprintf(e.format, e.args...)
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+// Message lets the message be translated, see i18n.Message.
+func (e *kindError) Message() (string, []any) {
+	return e.format, e.args
+}
+
+// NotFound returns an error matching ErrNotFound with the formatted message.
+func NotFound(format string, args ...any) error {
+	return &kindError{kind: ErrNotFound, format: format, args: args}
+}
+
+// Conflict returns an error matching ErrConflict with the formatted message.
+func Conflict(format string, args ...any) error {
+	return &kindError{kind: ErrConflict, format: format, args: args}
+}
+
+// StudentFilter narrows a student list; zero fields don't narrow it.
+type StudentFilter struct {
+	// CreatedAfter and CreatedBefore are exclusive bounds of created_at
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// EmailVerified, if set, keeps the students whose email is or isn't
+	// verified
+	EmailVerified *bool
+	// IncludeArchived adds the archived students, which are left out
+	// otherwise
+	IncludeArchived bool
+}
+
+// Match reports whether student passes the filter.
+func (f StudentFilter) Match(student types.Student) bool {
+	if !f.CreatedAfter.IsZero() && !student.CreatedAt.After(f.CreatedAfter) {
+		return false
+	}
+
+	if !f.CreatedBefore.IsZero() && !student.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+
+	if f.EmailVerified != nil && student.EmailVerified != *f.EmailVerified {
+		return false
+	}
+
+	if !f.IncludeArchived && !student.ArchivedAt.IsZero() {
+		return false
+	}
+
+	return true
+}
+
+// what a student report groups by
+const (
+	// ReportByAgeBucket groups students into age ranges of BucketSize
+	ReportByAgeBucket = "age_bucket"
+	// ReportByStatus groups students by whether their email is verified
+	ReportByStatus = "status"
+)
+
+// the groups of ReportByStatus
+const (
+	StatusVerified   = "verified"
+	StatusUnverified = "unverified"
+)
+
+// ReportQuery asks for the aggregates of the students passing Filter,
+// grouped by GroupBy.
+type ReportQuery struct {
+	GroupBy string
+	// BucketSize is the width of the age ranges of ReportByAgeBucket
+	BucketSize int
+	Filter     StudentFilter
+}
+
+// Key returns the key of the group of student in the report, which also
+// orders the groups: the first age of its range for ReportByAgeBucket, 1
+// or 0 for ReportByStatus.
+func (q ReportQuery) Key(student types.Student) int {
+	if q.GroupBy == ReportByAgeBucket {
+		return student.Age / q.BucketSize * q.BucketSize
+	}
+
+	if student.EmailVerified {
+		return 1
+	}
+
+	return 0
+}
+
+// Label names the group with key.
+func (q ReportQuery) Label(key int) string {
+	if q.GroupBy == ReportByAgeBucket {
+		return fmt.Sprintf("%d-%d", key, key+q.BucketSize-1)
+	}
+
+	if key == 1 {
+		return StatusVerified
+	}
+
+	return StatusUnverified
+}
 
 // create interface
 type Storage interface {
 	// define methods for storage operations
-	CreateStudent(name, email string, age int) (int64, error)
+	CreateStudent(ctx context.Context, name, email string, age int) (int64, error)
+
+	GetStudentById(ctx context.Context, id int64) (types.Student, error)
+	GetStudentByEmail(ctx context.Context, email string) (types.Student, error)
+	GetStudentList(ctx context.Context, filter StudentFilter) ([]types.Student, error)
+	UpdateStudent(ctx context.Context, id int64, name, email string, age int) error
+
+	// DeleteStudent also deletes the files of the student, and with a job
+	// storage queues a FileCleanupKind job deleting their contents, in the
+	// same transaction.
+	DeleteStudent(ctx context.Context, id int64) error
+
+	// MergeStudents folds student otherId into id in one transaction: id
+	// keeps its fields, but takes the email of otherId with its
+	// verification if only that one is verified, and gets the earlier
+	// created_at. Data about otherId is moved to id, its versions joining
+	// those of id in the order they were made and followed by a version of
+	// the merged student, and otherId is deleted. It returns the merged
+	// student.
+	MergeStudents(ctx context.Context, id, otherId int64) (types.Student, error)
+
+	// SetVerificationToken stores the hash of the token that verifies the
+	// email of student id until expiresAt, replacing any earlier one.
+	// UpdateStudent clears it, and the verification, when the email changes.
+	SetVerificationToken(ctx context.Context, id int64, tokenHash string, expiresAt time.Time) error
+	// VerifyEmail marks the email of the student with an unexpired token
+	// hashing to tokenHash as verified, the token used up, and returns the
+	// student.
+	VerifyEmail(ctx context.Context, tokenHash string, now time.Time) (types.Student, error)
+
+	// GetStudentVersions returns the versions of student id, oldest first.
+	// Creating a student stores its first version and every update that
+	// changes its name, email or age another; deleting it drops them.
+	GetStudentVersions(ctx context.Context, id int64) ([]types.StudentVersion, error)
+	GetStudentVersion(ctx context.Context, id int64, version int) (types.StudentVersion, error)
+
+	// ArchiveStudents moves the students last updated before before to the
+	// archive and returns them. Until UnarchiveStudent moves them back,
+	// archived students are only found by GetArchivedStudent, and by
+	// GetStudentList and ReportStudents with IncludeArchived; their
+	// versions are kept.
+	ArchiveStudents(ctx context.Context, before time.Time) ([]types.Student, error)
+	GetArchivedStudent(ctx context.Context, id int64) (types.Student, error)
+	// UnarchiveStudent moves archived student id back and returns it,
+	// updated now so it isn't archived again at once.
+	UnarchiveStudent(ctx context.Context, id int64) (types.Student, error)
+
+	// ReportStudents returns the aggregates of each group of the report,
+	// ordered by ReportQuery.Key, with the average age rounded to two
+	// decimals. Groups without students are left out.
+	ReportStudents(ctx context.Context, query ReportQuery) ([]types.ReportGroup, error)
+}
+
+// WebhookStorage keeps webhook registrations and their delivery log.
+type WebhookStorage interface {
+	CreateWebhook(ctx context.Context, url, secret string, events []string) (int64, error)
+	GetWebhookList(ctx context.Context) ([]types.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+
+	CreateWebhookDelivery(ctx context.Context, delivery types.WebhookDelivery) error
+	GetWebhookDeliveries(ctx context.Context, webhookId int64, limit int) ([]types.WebhookDelivery, error)
+}
+
+// FileStorage keeps what the files uploaded for students are; their
+// contents are in a blob store.
+type FileStorage interface {
+	CreateFile(ctx context.Context, file types.File) (int64, error)
+	// GetFiles returns the files of student studentId, oldest first.
+	GetFiles(ctx context.Context, studentId int64) ([]types.File, error)
+	GetFile(ctx context.Context, studentId, id int64) (types.File, error)
+	DeleteFile(ctx context.Context, studentId, id int64) error
+}
+
+// FileCleanupKind is the kind of the job DeleteStudent queues to delete the
+// contents of the files of the student, tried FileCleanupAttempts times.
+const (
+	FileCleanupKind     = "files.cleanup"
+	FileCleanupAttempts = 5
+)
+
+// FileCleanup is the payload of FileCleanupKind jobs.
+type FileCleanup struct {
+	// Keys are the blob keys of the contents
+	Keys []string `json:"keys"`
+}
 
-	GetStudentById(id int64) (types.Student, error)
-	GetStudentList() ([]types.Student, error)
-	UpdateStudent(id int64, name, email string, age int) error
+// JobStorage is the persistent queue behind the background workers.
+type JobStorage interface {
+	CreateJob(ctx context.Context, job types.Job) (int64, error)
+	// ClaimJob marks the next due job as running until now+lease and returns
+	// it; jobs whose lease ran out are claimed again.
+	ClaimJob(ctx context.Context, now time.Time, lease time.Duration) (types.Job, bool, error)
+	CompleteJob(ctx context.Context, id int64) error
+	RescheduleJob(ctx context.Context, id int64, runAt time.Time, reason string) error
+	DeadLetterJob(ctx context.Context, id int64, reason string) error
+	// PostponeJob queues a running job again for runAt and gives back the
+	// attempt it was claimed with.
+	PostponeJob(ctx context.Context, id int64, runAt time.Time) error
+	RequeueJob(ctx context.Context, id int64) error
+	UpdateJobProgress(ctx context.Context, id int64, progress types.JobProgress) error
+	// SetJobResult stores a summary shown with the job and, optionally, an
+	// output to download, such as an export.
+	SetJobResult(ctx context.Context, id int64, result []byte, output []byte) error
+	GetJobOutput(ctx context.Context, id int64) ([]byte, error)
 
-	DeleteStudent(id int64) error
+	GetJob(ctx context.Context, id int64) (types.Job, error)
+	GetJobList(ctx context.Context, status, kind string, limit int) ([]types.Job, error)
+	CountJobs(ctx context.Context) (map[string]int, error)
 }
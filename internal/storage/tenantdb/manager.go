@@ -0,0 +1,174 @@
+// Package tenantdb routes the data of tenants configured with a database of
+// their own to that database, and everyone else's to the main one.
+package tenantdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/storage/sqlite"
+	"github.com/cmanish049/students-api/internal/tenant"
+)
+
+// pingTimeout bounds a single health check
+const pingTimeout = 5 * time.Second
+
+// Status is the state of a tenant database, shown on the admin listener.
+type Status struct {
+	Tenant    string    `json:"tenant"`
+	Path      string    `json:"path"`
+	Open      bool      `json:"open"`
+	Healthy   bool      `json:"healthy"`
+	CheckedAt time.Time `json:"checked_at,omitzero"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// entry is the connection of one tenant; its mutex keeps a slow open or
+// migration from holding up the other tenants.
+type entry struct {
+	mu     sync.Mutex
+	db     *sqlite.Sqlite
+	status Status
+}
+
+// Manager opens the tenant databases on first use, caches the connections
+// and health-checks them. A connection that fails a check is closed and
+// opened again by the next request of its tenant.
+type Manager struct {
+	main    *sqlite.Sqlite
+	entries map[string]*entry
+}
+
+// NewManager returns a Manager for the databases at paths, by tenant id.
+// Tenants not in paths use main.
+func NewManager(main *sqlite.Sqlite, paths map[string]string) *Manager {
+	entries := make(map[string]*entry, len(paths))
+	for id, path := range paths {
+		entries[id] = &entry{status: Status{Tenant: id, Path: path}}
+	}
+
+	return &Manager{main: main, entries: entries}
+}
+
+// Main returns the main database.
+func (m *Manager) Main() *sqlite.Sqlite {
+	return m.main
+}
+
+// For returns the database of the tenant ctx acts for.
+func (m *Manager) For(ctx context.Context) (*sqlite.Sqlite, error) {
+	return m.open(tenant.From(ctx))
+}
+
+// Tenants returns the ids of the tenants with a database of their own.
+func (m *Manager) Tenants() []string {
+	return slices.Sorted(maps.Keys(m.entries))
+}
+
+func (m *Manager) open(id string) (*sqlite.Sqlite, error) {
+	e, ok := m.entries[id]
+	if !ok {
+		return m.main, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.db != nil {
+		return e.db, nil
+	}
+
+	db, err := sqlite.Open(e.status.Path)
+	if err == nil {
+		if err = db.Migrate(); err != nil {
+			db.Db.Close()
+		}
+	}
+
+	e.status.CheckedAt = time.Now()
+	if err != nil {
+		e.status.Healthy = false
+		e.status.Error = err.Error()
+		return nil, fmt.Errorf("database of tenant %s: %w", id, err)
+	}
+
+	slog.Info("tenant database opened", slog.String("tenant", id), slog.String("path", e.status.Path))
+
+	e.db = db
+	e.status.Open, e.status.Healthy, e.status.Error = true, true, ""
+
+	return db, nil
+}
+
+// Run checks the open connections every interval until ctx is done.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// Check pings every open connection and closes the ones that fail.
+func (m *Manager) Check(ctx context.Context) {
+	for _, id := range m.Tenants() {
+		e := m.entries[id]
+
+		e.mu.Lock()
+		if e.db != nil {
+			pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+			err := e.db.Ping(pingCtx)
+			cancel()
+
+			e.status.CheckedAt = time.Now()
+			if err != nil {
+				slog.Warn("tenant database failed its health check", slog.String("tenant", id), slog.String("error", err.Error()))
+
+				e.db.Db.Close()
+				e.db = nil
+				e.status.Open, e.status.Healthy, e.status.Error = false, false, err.Error()
+			} else {
+				e.status.Healthy, e.status.Error = true, ""
+			}
+		}
+		e.mu.Unlock()
+	}
+}
+
+// Status returns the state of every tenant database, ordered by tenant.
+func (m *Manager) Status() []Status {
+	res := make([]Status, 0, len(m.entries))
+	for _, id := range m.Tenants() {
+		e := m.entries[id]
+
+		e.mu.Lock()
+		res = append(res, e.status)
+		e.mu.Unlock()
+	}
+
+	return res
+}
+
+// Close closes the open tenant databases, not the main one.
+func (m *Manager) Close() {
+	for _, e := range m.entries {
+		e.mu.Lock()
+		if e.db != nil {
+			e.db.Db.Close()
+			e.db = nil
+			e.status.Open = false
+		}
+		e.mu.Unlock()
+	}
+}
@@ -0,0 +1,297 @@
+package tenantdb
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// Storage is a storage.Storage, storage.WebhookStorage and
+// storage.FileStorage whose calls go to the database of the tenant of
+// their context. Jobs always stay in the main database, so one queue
+// serves every tenant.
+type Storage struct {
+	m *Manager
+}
+
+func New(m *Manager) *Storage {
+	return &Storage{m: m}
+}
+
+func (s *Storage) CreateStudent(ctx context.Context, name, email string, age int) (int64, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return db.CreateStudent(ctx, name, email, age)
+}
+
+func (s *Storage) GetStudentById(ctx context.Context, id int64) (types.Student, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	return db.GetStudentById(ctx, id)
+}
+
+func (s *Storage) GetStudentByEmail(ctx context.Context, email string) (types.Student, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	return db.GetStudentByEmail(ctx, email)
+}
+
+func (s *Storage) GetStudentList(ctx context.Context, filter storage.StudentFilter) ([]types.Student, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetStudentList(ctx, filter)
+}
+
+func (s *Storage) UpdateStudent(ctx context.Context, id int64, name, email string, age int) error {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.UpdateStudent(ctx, id, name, email, age)
+}
+
+func (s *Storage) DeleteStudent(ctx context.Context, id int64) error {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := db.DeleteStudent(ctx, id); err != nil {
+		return err
+	}
+
+	// the cleanup of the files queued with the deletion is run from the main
+	// database; one that failed to move is moved with the next deletion
+	if db != s.m.Main() && !dryrun.Enabled(ctx) {
+		if _, err := db.MoveJobs(ctx, s.m.Main(), storage.FileCleanupKind); err != nil {
+			slog.Error("failed to queue file cleanup", slog.String("tenant", tenant.From(ctx)), slog.Int64("student_id", id), slog.String("error", err.Error()))
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) MergeStudents(ctx context.Context, id, otherId int64) (types.Student, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	return db.MergeStudents(ctx, id, otherId)
+}
+
+func (s *Storage) SetVerificationToken(ctx context.Context, id int64, tokenHash string, expiresAt time.Time) error {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.SetVerificationToken(ctx, id, tokenHash, expiresAt)
+}
+
+func (s *Storage) VerifyEmail(ctx context.Context, tokenHash string, now time.Time) (types.Student, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	return db.VerifyEmail(ctx, tokenHash, now)
+}
+
+func (s *Storage) GetStudentVersions(ctx context.Context, id int64) ([]types.StudentVersion, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetStudentVersions(ctx, id)
+}
+
+func (s *Storage) GetStudentVersion(ctx context.Context, id int64, version int) (types.StudentVersion, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return types.StudentVersion{}, err
+	}
+
+	return db.GetStudentVersion(ctx, id, version)
+}
+
+// ArchiveStudents archives the students of the main database and of every
+// tenant database, opening those that aren't yet.
+func (s *Storage) ArchiveStudents(ctx context.Context, before time.Time) ([]types.Student, error) {
+	archived, err := s.m.Main().ArchiveStudents(ctx, before)
+	if err != nil {
+		return archived, err
+	}
+
+	for _, id := range s.m.Tenants() {
+		db, err := s.m.open(id)
+		if err != nil {
+			return archived, err
+		}
+
+		students, err := db.ArchiveStudents(ctx, before)
+		archived = append(archived, students...)
+		if err != nil {
+			return archived, err
+		}
+	}
+
+	return archived, nil
+}
+
+func (s *Storage) GetArchivedStudent(ctx context.Context, id int64) (types.Student, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	return db.GetArchivedStudent(ctx, id)
+}
+
+func (s *Storage) UnarchiveStudent(ctx context.Context, id int64) (types.Student, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	return db.UnarchiveStudent(ctx, id)
+}
+
+func (s *Storage) ReportStudents(ctx context.Context, query storage.ReportQuery) ([]types.ReportGroup, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.ReportStudents(ctx, query)
+}
+
+func (s *Storage) CreateFile(ctx context.Context, file types.File) (int64, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return db.CreateFile(ctx, file)
+}
+
+func (s *Storage) GetFiles(ctx context.Context, studentId int64) ([]types.File, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetFiles(ctx, studentId)
+}
+
+func (s *Storage) GetFile(ctx context.Context, studentId, id int64) (types.File, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return types.File{}, err
+	}
+
+	return db.GetFile(ctx, studentId, id)
+}
+
+func (s *Storage) DeleteFile(ctx context.Context, studentId, id int64) error {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.DeleteFile(ctx, studentId, id)
+}
+
+func (s *Storage) CreateWebhook(ctx context.Context, url, secret string, events []string) (int64, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return db.CreateWebhook(ctx, url, secret, events)
+}
+
+func (s *Storage) GetWebhookList(ctx context.Context) ([]types.Webhook, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetWebhookList(ctx)
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, id int64) error {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.DeleteWebhook(ctx, id)
+}
+
+func (s *Storage) CreateWebhookDelivery(ctx context.Context, delivery types.WebhookDelivery) error {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.CreateWebhookDelivery(ctx, delivery)
+}
+
+func (s *Storage) GetWebhookDeliveries(ctx context.Context, webhookId int64, limit int) ([]types.WebhookDelivery, error) {
+	db, err := s.m.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetWebhookDeliveries(ctx, webhookId, limit)
+}
+
+// PurgeJobs purges the jobs of every tenant, which are all in the main
+// database.
+func (s *Storage) PurgeJobs(ctx context.Context, status string, before time.Time) (int64, error) {
+	return s.m.Main().PurgeJobs(ctx, status, before)
+}
+
+// PurgeWebhookDeliveries purges the deliveries in the main database and in
+// every tenant database, opening those that aren't yet.
+func (s *Storage) PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	total, err := s.m.Main().PurgeWebhookDeliveries(ctx, before)
+	if err != nil {
+		return total, err
+	}
+
+	for _, id := range s.m.Tenants() {
+		db, err := s.m.open(id)
+		if err != nil {
+			return total, err
+		}
+
+		n, err := db.PurgeWebhookDeliveries(ctx, before)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
@@ -0,0 +1,126 @@
+package tenant
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Policy decides which tenant a request acts for.
+type Policy struct {
+	// Tokens maps the bearer tokens clients authenticate with to the tenant
+	// they act for
+	Tokens map[string]string
+	// TrustHeader accepts the Header of requests without a token. Anyone
+	// can send it, so it is only safe behind a gateway that sets it.
+	TrustHeader bool
+	// Required rejects requests naming no tenant instead of letting them
+	// act for Default
+	Required bool
+}
+
+// Sessions verifies the tokens of logged in users, which act for the
+// tenant of their session, see auth.Sessions.
+type Sessions interface {
+	Tenant(token string) (string, bool)
+}
+
+// Resolver resolves the tenant of requests by a Policy that can be changed
+// while requests are served.
+type Resolver struct {
+	mu       sync.RWMutex
+	policy   Policy
+	sessions Sessions
+}
+
+func NewResolver(policy Policy) *Resolver {
+	return &Resolver{policy: policy}
+}
+
+// Set changes the policy for requests that start from now on.
+func (r *Resolver) Set(policy Policy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.policy = policy
+}
+
+// SetSessions accepts the session tokens sessions verifies besides the
+// tokens of the policy.
+func (r *Resolver) SetSessions(sessions Sessions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sessions = sessions
+}
+
+// Resolve returns the tenant of a request that sent the bearer token and
+// the Header value header, either of which may be empty. A token decides
+// the tenant; a header naming another one is refused. Errors are *Error.
+func (r *Resolver) Resolve(token, header string) (string, error) {
+	r.mu.RLock()
+	policy, sessions := r.policy, r.sessions
+	r.mu.RUnlock()
+
+	if token != "" {
+		id, ok := lookup(policy.Tokens, token)
+		if !ok && sessions != nil {
+			id, ok = sessions.Tenant(token)
+		}
+		if !ok {
+			return "", &Error{Status: http.StatusUnauthorized, format: "invalid token"}
+		}
+		if header != "" && header != id {
+			return "", &Error{Status: http.StatusForbidden, format: "the token does not belong to tenant %s", args: []any{header}}
+		}
+		return id, nil
+	}
+
+	if header != "" {
+		if !policy.TrustHeader {
+			return "", &Error{Status: http.StatusUnauthorized, format: "%s is not accepted, authenticate with a token", args: []any{Header}}
+		}
+		if !Valid(header) {
+			return "", &Error{Status: http.StatusBadRequest, format: "invalid tenant id %q", args: []any{header}}
+		}
+		return header, nil
+	}
+
+	if policy.Required {
+		return "", &Error{Status: http.StatusUnauthorized, format: "a tenant is required, authenticate with a token"}
+	}
+
+	return Default, nil
+}
+
+// ResolveRequest is Resolve with the "Authorization: Bearer" token and the
+// Header of req.
+func (r *Resolver) ResolveRequest(req *http.Request) (string, error) {
+	return r.Resolve(BearerToken(req.Header.Get("Authorization")), req.Header.Get(Header))
+}
+
+// BearerToken returns the token of an Authorization value, empty if it
+// isn't a bearer token.
+func BearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+// lookup finds token in tokens comparing in constant time, so response
+// times don't tell how much of a guessed token is right.
+func lookup(tokens map[string]string, token string) (string, bool) {
+	var id string
+	found := false
+	for t, tenant := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			id, found = tenant, true
+		}
+	}
+
+	return id, found
+}
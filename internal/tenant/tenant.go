@@ -0,0 +1,73 @@
+// Package tenant carries the tenant (school) a request acts for in its
+// context. The storages scope every query to it, so one instance can serve
+// several schools without one seeing or changing another's data.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// Default is the tenant of contexts that carry none: requests that name no
+// tenant when none is required, the CLI without --tenant, and every record
+// written before tenants existed.
+const Default = "default"
+
+// Header names the tenant of a request that has no token, if trusted.
+const Header = "X-Tenant-ID"
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Valid reports whether id can name a tenant: up to 63 lowercase letters,
+// digits, '-' and '_', starting with a letter or digit.
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+type key struct{}
+
+// all marks the context of operator tooling that spans every tenant
+type all struct{}
+
+// With returns a context acting for tenant id.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// From returns the tenant ctx acts for, Default if none.
+func From(ctx context.Context) string {
+	if id, ok := ctx.Value(key{}).(string); ok {
+		return id
+	}
+
+	return Default
+}
+
+// All returns a context whose reads span every tenant, for the admin
+// listener and the job queue. Writes with it still go to From(ctx).
+func All(ctx context.Context) context.Context {
+	return context.WithValue(ctx, all{}, true)
+}
+
+// IsAll reports whether ctx was returned by All.
+func IsAll(ctx context.Context) bool {
+	v, _ := ctx.Value(all{}).(bool)
+	return v
+}
+
+// Error is a request whose tenant can't be resolved, answered with Status.
+type Error struct {
+	Status int
+	format string
+	args   []any
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf(e.format, e.args...)
+}
+
+// Message lets the message be translated, see i18n.Message.
+func (e *Error) Message() (string, []any) {
+	return e.format, e.args
+}
@@ -1,8 +1,108 @@
 package types
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type Student struct {
 	Id    int    `json:"id"`
 	Name  string `json:"name" validate:"required"`
-	Email string `json:"email" validate:"required"`
-	Age   int    `json:"age" validate:"required"`
+	Email string `json:"email" validate:"required,email_domain"`
+	Age   int    `json:"age" validate:"required,age"`
+	// set by the storage
+	TenantId        string    `json:"tenant_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at,omitzero"`
+	UpdatedAt       time.Time `json:"updated_at,omitzero"`
+	EmailVerified   bool      `json:"email_verified"`
+	EmailVerifiedAt time.Time `json:"email_verified_at,omitzero"`
+	// ArchivedAt is when an archived student was archived
+	ArchivedAt time.Time `json:"archived_at,omitzero"`
+}
+
+// StudentVersion is what the fields of a student were after a change,
+// numbered from 1 for each student.
+type StudentVersion struct {
+	StudentId int    `json:"student_id"`
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Age       int    `json:"age"`
+	// when the change was made
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// File is a file uploaded for a student, such as a photo or a document.
+// Its contents are in the blob store under Key.
+type File struct {
+	Id          int64  `json:"id"`
+	StudentId   int64  `json:"student_id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// Key is where the contents are in the blob store
+	Key string `json:"-"`
+	// set by the storage
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReportGroup is the aggregates of one group of students in a report.
+type ReportGroup struct {
+	Group      string  `json:"group"`
+	Count      int     `json:"count"`
+	Verified   int     `json:"verified"`
+	AverageAge float64 `json:"average_age"`
+	MinAge     int     `json:"min_age"`
+	MaxAge     int     `json:"max_age"`
+}
+
+type Webhook struct {
+	Id        int64     `json:"id"`
+	TenantId  string    `json:"tenant_id,omitempty"`
+	Url       string    `json:"url" validate:"required,url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events" validate:"dive,oneof=student.created student.updated student.deleted student.email_verified student.archived student.unarchived"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	Id         int64     `json:"id"`
+	WebhookId  int64     `json:"webhook_id"`
+	EventId    int64     `json:"event_id"`
+	EventType  string    `json:"event_type"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	Success    bool      `json:"success"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Job struct {
+	Id          int64           `json:"id"`
+	TenantId    string          `json:"tenant_id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+	Progress    JobProgress     `json:"progress"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	RunAt       time.Time       `json:"run_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// JobProgress is reported by long jobs; Total is 0 while unknown.
+type JobProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
 }
+
+// job statuses; dead jobs have used up their attempts and wait for an operator
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobDead    = "dead"
+)
@@ -0,0 +1,114 @@
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// systemd socket activation, see sd_listen_fds(3)
+const (
+	systemdPrefix  = "systemd"
+	envListenPid   = "LISTEN_PID"
+	envListenFds   = "LISTEN_FDS"
+	envListenNames = "LISTEN_FDNAMES"
+)
+
+// parseAddr splits a configured address into a network and address.
+// "unix:/path/to.sock" and absolute paths are unix sockets,
+// anything else is a tcp host:port.
+func parseAddr(addr string) (network, address string) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		return "unix", path
+	}
+
+	if strings.HasPrefix(addr, "/") {
+		return "unix", addr
+	}
+
+	return "tcp", addr
+}
+
+func listen(addr string) (net.Listener, error) {
+	network, address := parseAddr(addr)
+
+	if network == "unix" {
+		removeStaleSocket(address)
+	}
+
+	return net.Listen(network, address)
+}
+
+// removeStaleSocket deletes a socket file left behind by a crashed process,
+// otherwise listening on the same path fails with "address already in use".
+func removeStaleSocket(path string) {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode().Type() != fs.ModeSocket {
+		return
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		// someone is still serving on it; let net.Listen report the error
+		conn.Close()
+		return
+	}
+
+	os.Remove(path)
+}
+
+// systemdListeners returns the listeners passed by systemd socket activation,
+// keyed as "systemd:<FileDescriptorName>", in the order they were passed.
+func systemdListeners() (map[string]net.Listener, []string, error) {
+	defer func() {
+		os.Unsetenv(envListenPid)
+		os.Unsetenv(envListenFds)
+		os.Unsetenv(envListenNames)
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv(envListenPid))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv(envListenFds))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", envListenFds, err)
+	}
+
+	var names []string
+	if v := os.Getenv(envListenNames); v != "" {
+		names = strings.Split(v, ":")
+	}
+
+	listeners := map[string]net.Listener{}
+	var order []string
+
+	for i := range count {
+		name := "unknown"
+		if i < len(names) {
+			name = names[i]
+		}
+		key := systemdPrefix + ":" + name
+
+		f := os.NewFile(uintptr(firstInheritedFd+i), key)
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("systemd listener %s: %w", key, err)
+		}
+
+		if _, ok := listeners[key]; ok {
+			ln.Close()
+			return nil, nil, errors.New("duplicate systemd socket name " + name)
+		}
+
+		listeners[key] = ln
+		order = append(order, key)
+	}
+
+	return listeners, order, nil
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package upgrade
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signal asks a running process to start an upgrade.
+var Signal os.Signal = syscall.SIGUSR2
@@ -0,0 +1,8 @@
+//go:build windows
+
+package upgrade
+
+import "os"
+
+// Signal is nil on windows, where listener handoff is not supported.
+var Signal os.Signal
@@ -0,0 +1,279 @@
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// environment used to hand listeners over from the old process to the new one
+const (
+	envListeners = "STUDENTS_API_LISTENERS"
+	envReadyFd   = "STUDENTS_API_READY_FD"
+)
+
+// readyTimeout is how long the old process waits for the new one to come up
+const readyTimeout = 30 * time.Second
+
+// listeners passed through ExtraFiles start right after stdin, stdout and stderr
+const firstInheritedFd = 3
+
+type filer interface {
+	File() (*os.File, error)
+}
+
+// Upgrader hands listening sockets over to a freshly started copy of the
+// binary, so a deploy can replace the running process without refusing or
+// dropping connections.
+type Upgrader struct {
+	pidFile string
+
+	mu        sync.Mutex
+	inherited map[string]net.Listener
+	systemd   []string
+	active    map[string]net.Listener
+	order     []string
+	readyFd   *os.File
+	hasParent bool
+	upgrading bool
+}
+
+// New picks up listeners inherited from a parent process or from systemd
+// socket activation (if any).
+// pidFile is optional; when set it is rewritten once this process is ready.
+func New(pidFile string) (*Upgrader, error) {
+	u := &Upgrader{
+		pidFile:   pidFile,
+		inherited: map[string]net.Listener{},
+		active:    map[string]net.Listener{},
+	}
+
+	names := os.Getenv(envListeners)
+	os.Unsetenv(envListeners)
+
+	if names == "" {
+		listeners, order, err := systemdListeners()
+		if err != nil {
+			return nil, err
+		}
+		for name, ln := range listeners {
+			u.inherited[name] = ln
+		}
+		u.systemd = order
+	} else {
+		for i, name := range strings.Split(names, ",") {
+			f := os.NewFile(uintptr(firstInheritedFd+i), name)
+			ln, err := net.FileListener(f)
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("inherit listener %s: %w", name, err)
+			}
+			u.inherited[name] = ln
+		}
+	}
+
+	if fd := os.Getenv(envReadyFd); fd != "" {
+		os.Unsetenv(envReadyFd)
+
+		n, err := strconv.Atoi(fd)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", envReadyFd, err)
+		}
+		u.readyFd = os.NewFile(uintptr(n), "ready")
+		u.hasParent = true
+	}
+
+	return u, nil
+}
+
+// Listen returns the listener inherited for addr, or opens a new one.
+// addr is a tcp host:port, a unix socket ("unix:/path" or an absolute path),
+// or "systemd[:name]" to use a socket passed by systemd socket activation.
+func (u *Upgrader) Listen(addr string) (net.Listener, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	name := addr
+	if name == systemdPrefix && len(u.systemd) > 0 {
+		name = u.systemd[0]
+	}
+
+	if ln, ok := u.inherited[name]; ok {
+		delete(u.inherited, name)
+		// the new process has to find it under the configured address
+		u.track(addr, ln)
+		return ln, nil
+	}
+
+	if name == systemdPrefix || strings.HasPrefix(name, systemdPrefix+":") {
+		return nil, fmt.Errorf("no socket %q passed by systemd", addr)
+	}
+
+	ln, err := listen(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	u.track(addr, ln)
+	return ln, nil
+}
+
+func (u *Upgrader) track(name string, ln net.Listener) {
+	u.active[name] = ln
+	u.order = append(u.order, name)
+}
+
+// HasParent reports whether this process was started by an upgrade.
+func (u *Upgrader) HasParent() bool {
+	return u.hasParent
+}
+
+// Ready closes inherited listeners that were not claimed, writes the pid
+// file and tells the parent process (if any) that it can shut down.
+func (u *Upgrader) Ready() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for name, ln := range u.inherited {
+		ln.Close()
+		delete(u.inherited, name)
+	}
+
+	if u.pidFile != "" {
+		if err := writePidFile(u.pidFile); err != nil {
+			return err
+		}
+	}
+
+	if u.readyFd == nil {
+		return nil
+	}
+
+	_, err := u.readyFd.Write([]byte{1})
+	u.readyFd.Close()
+	u.readyFd = nil
+
+	return err
+}
+
+// Upgrade starts a new copy of the running binary with all active listeners
+// and waits until it reports ready. On success the caller should shut down
+// gracefully; the new process keeps accepting on the same sockets.
+func (u *Upgrader) Upgrade() error {
+	u.mu.Lock()
+	if u.upgrading {
+		u.mu.Unlock()
+		return errors.New("upgrade already in progress")
+	}
+	u.upgrading = true
+	u.mu.Unlock()
+
+	defer func() {
+		u.mu.Lock()
+		u.upgrading = false
+		u.mu.Unlock()
+	}()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	names := append([]string(nil), u.order...)
+	var files []*os.File
+	for _, name := range names {
+		f, err := u.active[name].(filer).File()
+		if err != nil {
+			u.mu.Unlock()
+			closeAll(files)
+			return fmt.Errorf("dup listener %s: %w", name, err)
+		}
+		files = append(files, f)
+	}
+	u.mu.Unlock()
+	defer closeAll(files)
+
+	readR, readW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readR.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, readW)
+	cmd.Env = append(os.Environ(),
+		envListeners+"="+strings.Join(names, ","),
+		envReadyFd+"="+strconv.Itoa(firstInheritedFd+len(files)),
+	)
+
+	if err := cmd.Start(); err != nil {
+		readW.Close()
+		return fmt.Errorf("start new process: %w", err)
+	}
+	readW.Close()
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := readR.Read(buf)
+		ready <- err
+	}()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			cmd.Process.Kill()
+			return fmt.Errorf("new process did not become ready: %w", err)
+		}
+		u.keepSockets()
+		return nil
+	case err := <-exited:
+		return fmt.Errorf("new process exited before ready: %v", err)
+	case <-time.After(readyTimeout):
+		cmd.Process.Kill()
+		return errors.New("timed out waiting for new process")
+	}
+}
+
+// keepSockets stops unix socket files from being removed when this process
+// closes its listeners, since the new process is now serving on them.
+func (u *Upgrader) keepSockets() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for _, ln := range u.active {
+		if ul, ok := ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+}
+
+func closeAll(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+func writePidFile(path string) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+		return fmt.Errorf("write pid file: %w", err)
+	}
+
+	return os.Rename(tmp, path)
+}
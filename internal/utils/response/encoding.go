@@ -0,0 +1,124 @@
+package response
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ContentTypeJson     = "application/json"
+	ContentTypeMsgpack  = "application/msgpack"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// aliases in use for the binary formats
+var mediaTypes = map[string]string{
+	"application/json":        ContentTypeJson,
+	"application/msgpack":     ContentTypeMsgpack,
+	"application/x-msgpack":   ContentTypeMsgpack,
+	"application/vnd.msgpack": ContentTypeMsgpack,
+	"application/x-protobuf":  ContentTypeProtobuf,
+	"application/protobuf":    ContentTypeProtobuf,
+}
+
+// Write encodes data in the format the request's Accept header prefers:
+// JSON (the default), MessagePack, or protobuf when msg, the protobuf form
+// of data, is given. Anything else falls back to JSON. JSON and MessagePack
+// are wrapped in an Envelope; protobuf messages are written as they are.
+func Write(w http.ResponseWriter, r *http.Request, status int, data any, msg proto.Message) error {
+	Vary(w, "Accept")
+	pageHeaders(w, r, data)
+
+	switch Negotiate(r.Header.Get("Accept"), msg != nil) {
+	case ContentTypeMsgpack:
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		// same field names as the JSON form
+		enc.SetCustomStructTag("json")
+		if err := enc.Encode(envelope(r.Context(), data)); err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentTypeMsgpack)
+		w.WriteHeader(status)
+		_, err := w.Write(buf.Bytes())
+		return err
+	case ContentTypeProtobuf:
+		b, err := proto.Marshal(msg)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentTypeProtobuf)
+		w.WriteHeader(status)
+		_, err = w.Write(b)
+		return err
+	}
+
+	return WriteJson(w, r, status, data)
+}
+
+// Vary adds fields to the Vary header of a response, once each.
+func Vary(w http.ResponseWriter, fields ...string) {
+	h := w.Header()
+	for _, field := range fields {
+		present := false
+		for _, v := range h.Values("Vary") {
+			for _, f := range strings.Split(v, ",") {
+				if strings.EqualFold(strings.TrimSpace(f), field) {
+					present = true
+				}
+			}
+		}
+
+		if !present {
+			h.Add("Vary", field)
+		}
+	}
+}
+
+// Negotiate picks the response content type for an Accept header.
+func Negotiate(accept string, protobuf bool) string {
+	type offer struct {
+		contentType string
+		q           float64
+	}
+
+	var offers []offer
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		contentType, ok := mediaTypes[mediaType]
+		if !ok || (contentType == ContentTypeProtobuf && !protobuf) {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			offers = append(offers, offer{contentType, q})
+		}
+	}
+
+	if len(offers) == 0 {
+		return ContentTypeJson
+	}
+
+	sort.SliceStable(offers, func(i, j int) bool { return offers[i].q > offers[j].q })
+
+	return offers[0].contentType
+}
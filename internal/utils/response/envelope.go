@@ -0,0 +1,109 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/requestid"
+)
+
+// Code is a stable, machine-readable error code. Clients branch on it
+// rather than on messages, which are translated and may be reworded.
+type Code string
+
+const (
+	CodeBadRequest           Code = "bad_request"
+	CodeUnauthorized         Code = "unauthorized"
+	CodeForbidden            Code = "forbidden"
+	CodeValidation           Code = "validation_failed"
+	CodeNotFound             Code = "not_found"
+	CodeConflict             Code = "conflict"
+	CodeEmailTaken           Code = "email_taken"
+	CodeTooLarge             Code = "payload_too_large"
+	CodeUnsupportedMediaType Code = "unsupported_media_type"
+	CodeInternal             Code = "internal"
+	CodeMaintenance          Code = "maintenance"
+	CodeOverloaded           Code = "overloaded"
+	CodeTimeout              Code = "timeout"
+	CodeFaultInjected        Code = "fault_injected"
+)
+
+// CodeOf returns the code of errors answered with status that have no more
+// specific one.
+func CodeOf(status int) Code {
+	switch status {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodeTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMediaType
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	}
+
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+
+	return CodeBadRequest
+}
+
+// Envelope is the body of every JSON and MessagePack response: the data of
+// a success, or the error of a failure, and metadata about the response.
+type Envelope struct {
+	Data  any        `json:"data,omitempty"`
+	Error *ErrorBody `json:"error,omitempty"`
+	Meta  Meta       `json:"meta"`
+}
+
+type ErrorBody struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+	// Fields has the invalid fields of validation errors
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+type Meta struct {
+	RequestId  string      `json:"request_id,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination describes the part of a list a response has.
+type Pagination struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	// Total is the length of the whole list, if known
+	Total *int `json:"total,omitempty"`
+}
+
+// Page is data that is part of a longer list, written with its pagination.
+type Page struct {
+	Data       any
+	Pagination Pagination
+}
+
+// envelope wraps data, unless it is a Page, in which case its data is.
+func envelope(ctx context.Context, data any) Envelope {
+	env := Envelope{Data: data, Meta: Meta{RequestId: requestid.From(ctx)}}
+
+	if page, ok := data.(Page); ok {
+		env.Data = page.Data
+		env.Meta.Pagination = &page.Pagination
+	}
+
+	return env
+}
+
+// wantsProblem reports whether the request asks for problem+json errors
+// rather than the envelope.
+func wantsProblem(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), ContentTypeProblem)
+}
@@ -0,0 +1,46 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// pageHeaders sets the X-Total-Count and Link (first, prev, next, last)
+// headers of a page whose total is known, for tools that paginate through
+// headers rather than the body. The links are the request's path and query
+// with another ?offset=.
+func pageHeaders(w http.ResponseWriter, r *http.Request, data any) {
+	page, ok := data.(Page)
+	if !ok || page.Pagination.Total == nil || page.Pagination.Limit < 1 {
+		return
+	}
+
+	limit, offset, total := page.Pagination.Limit, page.Pagination.Offset, *page.Pagination.Total
+
+	last := 0
+	if total > 0 {
+		last = (total - 1) / limit * limit
+	}
+
+	links := []string{pageLink(r, limit, 0, "first")}
+	if offset > 0 {
+		links = append(links, pageLink(r, limit, max(offset-limit, 0), "prev"))
+	}
+	if offset+limit < total {
+		links = append(links, pageLink(r, limit, offset+limit, "next"))
+	}
+	links = append(links, pageLink(r, limit, last, "last"))
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+func pageLink(r *http.Request, limit, offset int, rel string) string {
+	query := r.URL.Query()
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+
+	return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, query.Encode(), rel)
+}
@@ -0,0 +1,65 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const ContentTypeProblem = "application/problem+json"
+
+// problem types besides about:blank, which is a problem described by its
+// status alone
+const (
+	ProblemValidation = "urn:students-api:problem:validation"
+	ProblemNotFound   = "urn:students-api:problem:not-found"
+	ProblemConflict   = "urn:students-api:problem:conflict"
+)
+
+// Problem is an RFC 7807 problem details object, the body of error
+// responses to clients that accept problem+json.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is the error's code in the envelope, as an extension member
+	Code Code `json:"code"`
+	// Errors has the invalid fields of validation problems
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a field that failed a validation rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// NewProblem returns a problem of type about:blank about the request r,
+// titled with the status text and coded with CodeOf(status).
+func NewProblem(r *http.Request, status int, detail string) Problem {
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     CodeOf(status),
+	}
+}
+
+// WriteProblem answers with p as problem+json if the request accepts it,
+// and as the error of an Envelope otherwise.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) error {
+	if !wantsProblem(r) {
+		env := envelope(r.Context(), nil)
+		env.Error = &ErrorBody{Code: p.Code, Message: p.Detail, Fields: p.Errors}
+		return writeJson(w, p.Status, env)
+	}
+
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(p.Status)
+
+	return json.NewEncoder(w).Encode(p)
+}
@@ -2,52 +2,20 @@ package response
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
-	"strings"
-
-	"github.com/go-playground/validator/v10"
-)
-
-const (
-	StatusOK    = "OK"
-	StatusError = "Error"
 )
 
-// inplace of any we can write interface{}
-func WriteJson(w http.ResponseWriter, status int, data any) error {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-
-	return json.NewEncoder(w).Encode(data)
-}
-
-type Response struct {
-	Status string `json:"status"`
-	Error  string `json:"error"`
-}
+// WriteJson writes data as JSON, wrapped in an Envelope. A Page also gets
+// its pagination as headers.
+func WriteJson(w http.ResponseWriter, r *http.Request, status int, data any) error {
+	pageHeaders(w, r, data)
 
-func GeneralError(err error) Response {
-	return Response{
-		Status: StatusError,
-		Error:  err.Error(),
-	}
+	return writeJson(w, status, envelope(r.Context(), data))
 }
 
-func ValidationError(errs validator.ValidationErrors) Response {
-	var errMsgs []string
-
-	for _, err := range errs {
-		switch err.ActualTag() {
-		case "required":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is required field", err.Field()))
-		default:
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is invalid", err.Field()))
-		}
-	}
+func writeJson(w http.ResponseWriter, status int, body any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 
-	return Response{
-		Status: StatusError,
-		Error:  strings.Join(errMsgs, ", "),
-	}
+	return json.NewEncoder(w).Encode(body)
 }
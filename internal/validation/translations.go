@@ -0,0 +1,143 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cmanish049/students-api/internal/utils/response"
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	detranslations "github.com/go-playground/validator/v10/translations/de"
+	estranslations "github.com/go-playground/validator/v10/translations/es"
+	frtranslations "github.com/go-playground/validator/v10/translations/fr"
+)
+
+// languages are the ones field errors are translated into besides English,
+// whose messages are message's
+var languages = []struct {
+	locale   func() locales.Translator
+	register func(*validator.Validate, ut.Translator) error
+	// custom has the messages of the tags registered by this package
+	custom map[string]string
+}{
+	{es.New, estranslations.RegisterDefaultTranslations, map[string]string{
+		"email_domain": "{0} no pertenece a un dominio de email permitido",
+		"age":          "{0} está fuera del rango permitido",
+		"domain_age":   "{0} está fuera del rango permitido para las direcciones de {1}",
+		"phone":        "{0} no es un número de teléfono válido",
+	}},
+	{fr.New, frtranslations.RegisterDefaultTranslations, map[string]string{
+		"email_domain": "{0} n'appartient pas à un domaine d'e-mail autorisé",
+		"age":          "{0} est en dehors de la plage autorisée",
+		"domain_age":   "{0} est en dehors de la plage autorisée pour les adresses {1}",
+		"phone":        "{0} n'est pas un numéro de téléphone valide",
+	}},
+	{de.New, detranslations.RegisterDefaultTranslations, map[string]string{
+		"email_domain": "{0} gehört zu keiner erlaubten E-Mail-Domain",
+		"age":          "{0} liegt außerhalb des erlaubten Bereichs",
+		"domain_age":   "{0} liegt außerhalb des erlaubten Bereichs für {1}-Adressen",
+		"phone":        "{0} ist keine gültige Telefonnummer",
+	}},
+}
+
+// registerTranslations sets up a translator for every one of languages.
+// Translations are registered per validator, so each has its own.
+func (v *Validator) registerTranslations() error {
+	v.translators = map[string]ut.Translator{}
+
+	for _, lang := range languages {
+		locale := lang.locale()
+		trans, _ := ut.New(locale, locale).GetTranslator(locale.Locale())
+
+		if err := lang.register(v.Validate, trans); err != nil {
+			return fmt.Errorf("register %s translations: %w", locale.Locale(), err)
+		}
+
+		for tag, text := range lang.custom {
+			register := func(trans ut.Translator) error {
+				return trans.Add(tag, text, true)
+			}
+			translate := func(trans ut.Translator, fe validator.FieldError) string {
+				msg, err := trans.T(fe.Tag(), fe.Field(), fe.Param())
+				if err != nil {
+					return fe.Error()
+				}
+				return msg
+			}
+
+			if err := v.RegisterTranslation(tag, trans, register, translate); err != nil {
+				return fmt.Errorf("register %s translation of %s: %w", locale.Locale(), tag, err)
+			}
+		}
+
+		v.translators[locale.Locale()] = trans
+	}
+
+	return nil
+}
+
+// Error reports the fields that failed validation. It unwraps to the
+// validator.ValidationErrors.
+type Error struct {
+	Errors      validator.ValidationErrors
+	translators map[string]ut.Translator
+}
+
+func (e *Error) Error() string {
+	return e.Translate("en")
+}
+
+func (e *Error) Unwrap() error {
+	return e.Errors
+}
+
+// Translate returns the message of e in lang, in English if there is no
+// translator for it.
+func (e *Error) Translate(lang string) string {
+	fields := e.Fields(lang)
+
+	msgs := make([]string, len(fields))
+	for i, f := range fields {
+		msgs[i] = f.Message
+	}
+
+	return strings.Join(msgs, ", ")
+}
+
+// Fields returns the invalid fields with their messages in lang.
+func (e *Error) Fields(lang string) []response.FieldError {
+	trans, translated := e.translators[lang]
+
+	fields := make([]response.FieldError, len(e.Errors))
+	for i, fe := range e.Errors {
+		msg := message(fe)
+		if translated {
+			msg = fe.Translate(trans)
+		}
+		fields[i] = response.FieldError{Field: fe.Field(), Rule: fe.Tag(), Message: msg}
+	}
+
+	return fields
+}
+
+// message returns the English message of a field error.
+func message(fe validator.FieldError) string {
+	switch fe.ActualTag() {
+	case "required":
+		return fmt.Sprintf("field %s is required field", fe.Field())
+	case "email_domain":
+		return fmt.Sprintf("field %s is not at an allowed email domain", fe.Field())
+	case "age":
+		return fmt.Sprintf("field %s is outside the allowed range", fe.Field())
+	case "domain_age":
+		return fmt.Sprintf("field %s is outside the allowed range for %s addresses", fe.Field(), fe.Param())
+	case "phone":
+		return fmt.Sprintf("field %s is not a valid phone number", fe.Field())
+	}
+
+	return fmt.Sprintf("field %s is invalid", fe.Field())
+}
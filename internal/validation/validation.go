@@ -0,0 +1,226 @@
+// Package validation provides the validator shared by every entry point,
+// with the custom tags and the per-deployment rules for student records.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/cmanish049/students-api/internal/types"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// DefaultPhonePattern accepts numbers in E.164 form, e.g. +15551234567.
+const DefaultPhonePattern = `^\+[1-9][0-9]{6,14}$`
+
+// Rules restrict student records beyond the field tags. Zero values don't
+// restrict anything.
+type Rules struct {
+	// EmailDomains are the only domains student emails may be at
+	EmailDomains []string `yaml:"email_domains" env:"STUDENTS_API_VALIDATION_EMAIL_DOMAINS"`
+	MinAge       int      `yaml:"min_age" env:"STUDENTS_API_VALIDATION_MIN_AGE"`
+	MaxAge       int      `yaml:"max_age" env:"STUDENTS_API_VALIDATION_MAX_AGE"`
+	// PhonePattern is the regular expression phone numbers must match,
+	// DefaultPhonePattern if empty
+	PhonePattern string `yaml:"phone_pattern" env:"STUDENTS_API_VALIDATION_PHONE_PATTERN"`
+	// DomainAges narrow the age range of students whose email is at a domain
+	DomainAges []DomainAge `yaml:"domain_ages"`
+}
+
+// DomainAge is the age range of the students at an email domain; a zero
+// bound is open.
+type DomainAge struct {
+	Domain string `yaml:"domain"`
+	MinAge int    `yaml:"min_age"`
+	MaxAge int    `yaml:"max_age"`
+}
+
+type compiled struct {
+	domains    map[string]bool
+	minAge     int
+	maxAge     int
+	phone      *regexp.Regexp
+	domainAges map[string]DomainAge
+}
+
+// Validator is a validator.Validate with these tags registered:
+//
+//	email_domain  the domain of an email address is allowed
+//	age           an age is within the configured bounds
+//	phone         a phone number matches the configured pattern
+//
+// and the cross-field rules of types.Student. Its field errors can be
+// translated, see Error. It is safe for concurrent use, and its rules can be
+// replaced while it is.
+type Validator struct {
+	*validator.Validate
+
+	rules       atomic.Pointer[compiled]
+	translators map[string]ut.Translator
+}
+
+func New(rules Rules) (*Validator, error) {
+	v := &Validator{Validate: validator.New()}
+	if err := v.SetRules(rules); err != nil {
+		return nil, err
+	}
+
+	v.RegisterValidation("email_domain", v.emailDomain)
+	v.RegisterValidation("age", v.age)
+	v.RegisterValidation("phone", v.phone)
+	v.RegisterStructValidation(v.student, types.Student{})
+
+	if err := v.registerTranslations(); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Struct validates s like validator.Validate.Struct, but reports invalid
+// fields with an *Error.
+func (v *Validator) Struct(s any) error {
+	err := v.Validate.Struct(s)
+
+	var validateErrs validator.ValidationErrors
+	if errors.As(err, &validateErrs) {
+		return &Error{Errors: validateErrs, translators: v.translators}
+	}
+
+	return err
+}
+
+// SetRules replaces the rules; the old ones stay if the new ones are invalid.
+func (v *Validator) SetRules(rules Rules) error {
+	c, err := compile(rules)
+	if err != nil {
+		return err
+	}
+
+	v.rules.Store(c)
+
+	return nil
+}
+
+// Check reports problems with rules without applying them.
+func Check(rules Rules) error {
+	_, err := compile(rules)
+	return err
+}
+
+func compile(rules Rules) (*compiled, error) {
+	var errs []error
+
+	c := &compiled{
+		domains:    map[string]bool{},
+		minAge:     rules.MinAge,
+		maxAge:     rules.MaxAge,
+		domainAges: map[string]DomainAge{},
+	}
+
+	for _, d := range rules.EmailDomains {
+		if err := checkDomain(d); err != nil {
+			errs = append(errs, fmt.Errorf("email_domains: %w", err))
+			continue
+		}
+		c.domains[strings.ToLower(d)] = true
+	}
+
+	if err := checkRange(rules.MinAge, rules.MaxAge); err != nil {
+		errs = append(errs, err)
+	}
+
+	pattern := rules.PhonePattern
+	if pattern == "" {
+		pattern = DefaultPhonePattern
+	}
+	phone, err := regexp.Compile(pattern)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("phone_pattern: %w", err))
+	}
+	c.phone = phone
+
+	for n, da := range rules.DomainAges {
+		if err := checkDomain(da.Domain); err != nil {
+			errs = append(errs, fmt.Errorf("domain_ages %d: %w", n+1, err))
+		}
+		if err := checkRange(da.MinAge, da.MaxAge); err != nil {
+			errs = append(errs, fmt.Errorf("domain_ages %d: %w", n+1, err))
+		}
+		c.domainAges[strings.ToLower(da.Domain)] = da
+	}
+
+	return c, errors.Join(errs...)
+}
+
+func checkDomain(domain string) error {
+	if domain == "" || strings.ContainsAny(domain, "@ ") {
+		return fmt.Errorf("%q is not a domain", domain)
+	}
+
+	return nil
+}
+
+func checkRange(minAge, maxAge int) error {
+	if minAge < 0 || maxAge < 0 {
+		return errors.New("ages must not be negative")
+	}
+
+	if maxAge > 0 && minAge > maxAge {
+		return fmt.Errorf("min_age %d is above max_age %d", minAge, maxAge)
+	}
+
+	return nil
+}
+
+// domainOf returns the lower-cased domain of an email address.
+func domainOf(email string) string {
+	_, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return ""
+	}
+
+	return strings.ToLower(domain)
+}
+
+func within(age, minAge, maxAge int) bool {
+	return age >= minAge && (maxAge == 0 || age <= maxAge)
+}
+
+func (v *Validator) emailDomain(fl validator.FieldLevel) bool {
+	rules := v.rules.Load()
+	if len(rules.domains) == 0 {
+		return true
+	}
+
+	return rules.domains[domainOf(fl.Field().String())]
+}
+
+func (v *Validator) age(fl validator.FieldLevel) bool {
+	rules := v.rules.Load()
+
+	return within(int(fl.Field().Int()), rules.minAge, rules.maxAge)
+}
+
+func (v *Validator) phone(fl validator.FieldLevel) bool {
+	return v.rules.Load().phone.MatchString(fl.Field().String())
+}
+
+// student checks the rules that depend on more than one field.
+func (v *Validator) student(sl validator.StructLevel) {
+	student := sl.Current().Interface().(types.Student)
+
+	// a missing age is reported by its required tag
+	if student.Age == 0 {
+		return
+	}
+
+	domain := domainOf(student.Email)
+	if da, ok := v.rules.Load().domainAges[domain]; ok && !within(student.Age, da.MinAge, da.MaxAge) {
+		sl.ReportError(student.Age, "Age", "age", "domain_age", domain)
+	}
+}
@@ -0,0 +1,181 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// why a webhook URL is refused
+var (
+	ErrInvalidURL     = errors.New("not an http or https URL")
+	ErrHostNotAllowed = errors.New("not an allowed webhook host")
+	ErrAddressBlocked = errors.New("loopback, private, link-local or unspecified address")
+)
+
+// Guard keeps webhooks from reaching what the server can but their callers
+// shouldn't: the admin listener on loopback, the hosts of the internal
+// network and the metadata services of clouds. Anyone able to register a
+// webhook could otherwise have the server send signed requests there.
+//
+// It checks the address every connection is made to, after DNS, so a
+// host resolving to another address at delivery time, or a redirect to
+// another host, is refused too.
+type Guard struct {
+	// allowed are the hosts webhooks may be at, any public one if empty;
+	// entries starting with "*." also allow the subdomains
+	allowed []string
+}
+
+// NewGuard returns a Guard limiting webhooks to allowedHosts, if any.
+// Allowed hosts may be on the private network, for receivers inside it,
+// but never loopback, link-local or unspecified addresses.
+func NewGuard(allowedHosts []string) *Guard {
+	allowed := make([]string, len(allowedHosts))
+	for i, host := range allowedHosts {
+		allowed[i] = strings.ToLower(strings.TrimSuffix(host, "."))
+	}
+
+	return &Guard{allowed: allowed}
+}
+
+// CheckURL reports why webhooks can't be delivered to raw, nil if they can
+// as far as can be told before delivery. The host's current addresses are
+// checked too, unless it doesn't resolve yet.
+func (g *Guard) CheckURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+
+	host := u.Hostname()
+	if !g.allowedHost(host) {
+		return ErrHostNotAllowed
+	}
+
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else if addrs, err = net.DefaultResolver.LookupNetIP(ctx, "ip", host); err != nil {
+		// the delivery checks again once it resolves
+		return nil
+	}
+
+	private := len(g.allowed) > 0
+	for _, addr := range addrs {
+		if blocked(addr, private) {
+			return ErrAddressBlocked
+		}
+	}
+
+	return nil
+}
+
+// allowedHost reports whether host is one of the allowed hosts.
+func (g *Guard) allowedHost(host string) bool {
+	if len(g.allowed) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range g.allowed {
+		if parent, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+parent) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reserved are the ranges webhooks never connect to that netip has no
+// predicate for.
+var reserved = []netip.Prefix{
+	// "this network", of which 0.0.0.0 reaches the host itself
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// shared address space of carrier-grade NATs, where some clouds have
+	// their metadata service (100.100.100.200)
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("255.255.255.255/32"),
+	// NAT64, which maps the IPv4 addresses, loopback and private ones too,
+	// into IPv6 on networks translating it
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// blocked reports whether webhooks must not connect to addr; private
+// addresses are allowed with private.
+func blocked(addr netip.Addr, private bool) bool {
+	addr = addr.Unmap()
+
+	if addr.IsLoopback() || addr.IsUnspecified() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || (addr.IsPrivate() && !private) {
+		return true
+	}
+
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Client returns an HTTP client for deliveries that only connects where
+// the guard allows. It uses no proxy, which would connect on its behalf.
+func (g *Guard) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if !g.allowedHost(host) {
+			return nil, fmt.Errorf("%s: %w", host, ErrHostNotAllowed)
+		}
+
+		// the dialer resolves host; its addresses are checked one by one
+		d := *dialer
+		d.Control = func(_, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if blocked(ap.Addr(), len(g.allowed) > 0) {
+				return fmt.Errorf("%s resolves to %s: %w", host, ap.Addr(), ErrAddressBlocked)
+			}
+			return nil
+		}
+
+		return d.DialContext(ctx, network, address)
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrInvalidURL
+			}
+			return nil
+		},
+	}
+}
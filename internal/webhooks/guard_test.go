@@ -0,0 +1,176 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBlocked(t *testing.T) {
+	tests := []struct {
+		addr    string
+		private bool
+		want    bool
+	}{
+		{"127.0.0.1", false, true},
+		{"127.1.2.3", true, true},
+		{"::1", true, true},
+		{"::ffff:127.0.0.1", true, true},
+		{"0.0.0.0", true, true},
+		{"0.1.2.3", true, true},
+		{"::", true, true},
+		{"255.255.255.255", true, true},
+		{"169.254.169.254", true, true},
+		{"fe80::1", true, true},
+		{"ff01::1", true, true},
+		{"ff02::1", true, true},
+		{"100.64.0.1", true, true},
+		{"100.100.100.200", true, true},
+		{"100.127.255.255", true, true},
+		{"64:ff9b::7f00:1", true, true},
+		{"64:ff9b::a00:1", false, true},
+		{"10.0.0.1", false, true},
+		{"172.16.0.1", false, true},
+		{"192.168.1.1", false, true},
+		{"::ffff:10.0.0.1", false, true},
+		{"fd00::1", false, true},
+		{"10.0.0.1", true, false},
+		{"fd00::1", true, false},
+		{"100.63.255.255", false, false},
+		{"100.128.0.0", false, false},
+		{"93.184.216.34", false, false},
+		{"2606:2800:220:1::1", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := blocked(netip.MustParseAddr(tt.addr), tt.private); got != tt.want {
+			t.Errorf("blocked(%s, %v) = %v, want %v", tt.addr, tt.private, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedHost(t *testing.T) {
+	tests := []struct {
+		allowed []string
+		host    string
+		want    bool
+	}{
+		{nil, "anything.example.com", true},
+		{[]string{"lms.example.com"}, "lms.example.com", true},
+		{[]string{"lms.example.com"}, "LMS.Example.com.", true},
+		{[]string{"lms.example.com"}, "example.com", false},
+		{[]string{"lms.example.com"}, "evil.lms.example.com", false},
+		{[]string{"*.example.com"}, "hooks.example.com", true},
+		{[]string{"*.example.com"}, "a.b.example.com", true},
+		{[]string{"*.example.com"}, "example.com", false},
+		{[]string{"*.example.com"}, "evilexample.com", false},
+		// only "*." is a wildcard
+		{[]string{"*example.com"}, "evilexample.com", false},
+		{[]string{"*"}, "anything.example.com", false},
+		{[]string{"lms.example.com", "*.hooks.example.com"}, "a.hooks.example.com", true},
+	}
+
+	for _, tt := range tests {
+		if got := NewGuard(tt.allowed).allowedHost(tt.host); got != tt.want {
+			t.Errorf("allowedHost(%q) with %q = %v, want %v", tt.host, tt.allowed, got, tt.want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"https://93.184.216.34/hook", nil},
+		{"ftp://93.184.216.34/hook", ErrInvalidURL},
+		{"https:///hook", ErrInvalidURL},
+		{"http://127.0.0.1:8081/maintenance", ErrAddressBlocked},
+		{"http://[::ffff:10.0.0.1]/hook", ErrAddressBlocked},
+		{"http://100.100.100.200/latest/meta-data", ErrAddressBlocked},
+		{"http://localhost/hook", ErrAddressBlocked},
+	}
+
+	for _, tt := range tests {
+		if err := NewGuard(nil).CheckURL(context.Background(), tt.url); !errors.Is(err, tt.want) {
+			t.Errorf("CheckURL(%s) = %v, want %v", tt.url, err, tt.want)
+		}
+	}
+
+	if err := NewGuard([]string{"lms.example.com"}).CheckURL(context.Background(), "https://93.184.216.34/hook"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("CheckURL of a host not allowed = %v, want %v", err, ErrHostNotAllowed)
+	}
+}
+
+// TestClient checks that the address is checked when connecting, after DNS
+// and redirects, not just when the webhook was registered.
+func TestClient(t *testing.T) {
+	var hits atomic.Int32
+	loopback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer loopback.Close()
+	_, port, _ := net.SplitHostPort(loopback.Listener.Addr().String())
+
+	t.Run("DNS", func(t *testing.T) {
+		// localhost resolves to loopback, allowed or not
+		client := NewGuard([]string{"localhost"}).Client(time.Second)
+
+		_, err := client.Get("http://localhost:" + port + "/")
+		if !errors.Is(err, ErrAddressBlocked) {
+			t.Errorf("Get = %v, want %v", err, ErrAddressBlocked)
+		}
+	})
+
+	t.Run("Redirect", func(t *testing.T) {
+		addr := publicAddr(t)
+		ln, err := net.Listen("tcp", net.JoinHostPort(addr.String(), "0"))
+		if err != nil {
+			t.Skipf("listen on %s: %v", addr, err)
+		}
+
+		redirect := httptest.NewUnstartedServer(http.RedirectHandler("http://localhost:"+port+"/", http.StatusFound))
+		redirect.Listener.Close()
+		redirect.Listener = ln
+		redirect.Start()
+		defer redirect.Close()
+
+		client := NewGuard([]string{addr.String(), "localhost"}).Client(time.Second)
+
+		_, err = client.Get(redirect.URL)
+		if !errors.Is(err, ErrAddressBlocked) {
+			t.Errorf("Get = %v, want %v", err, ErrAddressBlocked)
+		}
+	})
+
+	if n := hits.Load(); n != 0 {
+		t.Errorf("loopback server was reached %d times", n)
+	}
+}
+
+// publicAddr returns an IPv4 address of the host that webhooks can be
+// delivered to, skipping the test if there is none.
+func publicAddr(t *testing.T) netip.Addr {
+	t.Helper()
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		t.Skipf("interface addresses: %v", err)
+	}
+
+	for _, a := range addrs {
+		prefix, err := netip.ParsePrefix(a.String())
+		if err == nil && prefix.Addr().Is4() && !blocked(prefix.Addr(), true) {
+			return prefix.Addr()
+		}
+	}
+
+	t.Skip("no address webhooks can be delivered to")
+	return netip.Addr{}
+}
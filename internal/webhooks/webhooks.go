@@ -0,0 +1,167 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/events"
+	"github.com/cmanish049/students-api/internal/jobs"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// Kind is the job kind of a delivery to one webhook
+const Kind = "webhook.delivery"
+
+// Dispatcher delivers events to the registered webhooks. Every delivery is
+// a job on the queue, so failed ones are retried with exponential backoff,
+// also across restarts, and every attempt is recorded.
+type Dispatcher struct {
+	store  storage.WebhookStorage
+	queue  *jobs.Queue
+	client *http.Client
+}
+
+// delivery is the job payload. The body is kept as sent the first time so
+// retries carry the same signature input.
+type delivery struct {
+	WebhookId int64           `json:"webhook_id"`
+	EventId   int64           `json:"event_id"`
+	EventType string          `json:"event_type"`
+	Body      json.RawMessage `json:"body"`
+}
+
+// New returns a Dispatcher delivering to where guard allows.
+func New(store storage.WebhookStorage, queue *jobs.Queue, guard *Guard, maxAttempts int, timeout, backoff time.Duration) *Dispatcher {
+	d := &Dispatcher{
+		store:  store,
+		queue:  queue,
+		client: guard.Client(timeout),
+	}
+
+	queue.Register(Kind, maxAttempts, backoff, d.deliver)
+
+	return d
+}
+
+// Handle is an events.Bus subscriber; deliveries are queued in the background.
+func (d *Dispatcher) Handle(e events.Event) {
+	go d.dispatch(e)
+}
+
+func (d *Dispatcher) dispatch(e events.Event) {
+	// only the webhooks of the event's tenant hear about it
+	ctx, cancel := context.WithTimeout(tenant.With(context.Background(), e.Tenant), 10*time.Second)
+	defer cancel()
+
+	hooks, err := d.store.GetWebhookList(ctx)
+	if err != nil {
+		slog.Error("failed to load webhooks", slog.String("error", err.Error()))
+		return
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		slog.Error("failed to encode event", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, hook := range hooks {
+		if len(hook.Events) > 0 && !slices.Contains(hook.Events, string(e.Type)) {
+			continue
+		}
+
+		_, err := d.queue.Enqueue(ctx, Kind, delivery{WebhookId: hook.Id, EventId: e.Id, EventType: string(e.Type), Body: body})
+		if err != nil {
+			slog.Error("failed to queue webhook delivery", slog.Int64("webhook_id", hook.Id), slog.Int64("event_id", e.Id), slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, job types.Job) error {
+	var p delivery
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return err
+	}
+
+	hooks, err := d.store.GetWebhookList(ctx)
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(hooks, func(h types.Webhook) bool { return h.Id == p.WebhookId })
+	if i < 0 {
+		// deleted since the event, nobody to deliver to
+		return nil
+	}
+	hook := hooks[i]
+
+	status, err := d.send(ctx, hook, p)
+
+	record := types.WebhookDelivery{
+		WebhookId:  hook.Id,
+		EventId:    p.EventId,
+		EventType:  p.EventType,
+		Attempt:    job.Attempts,
+		StatusCode: status,
+		Success:    err == nil,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if err := d.store.CreateWebhookDelivery(context.WithoutCancel(ctx), record); err != nil {
+		slog.Error("failed to record webhook delivery", slog.Int64("webhook_id", hook.Id), slog.String("error", err.Error()))
+	}
+
+	return err
+}
+
+func (d *Dispatcher) send(ctx context.Context, hook types.Webhook, p delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.Url, bytes.NewReader(p.Body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "students-api-webhooks")
+	req.Header.Set("X-Webhook-Event", p.EventType)
+	req.Header.Set("X-Webhook-Id", strconv.FormatInt(p.EventId, 10))
+	req.Header.Set("X-Webhook-Signature", "t="+strconv.FormatInt(timestamp, 10)+",v1="+Sign(hook.Secret, timestamp, p.Body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the
+// webhook secret. Receivers recompute it to verify a delivery and reject
+// stale timestamps to prevent replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
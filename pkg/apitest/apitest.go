@@ -0,0 +1,154 @@
+// Package apitest drives the HTTP API in-process for tests, against any
+// storage (typically a storagetest.Memory).
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cmanish049/students-api/internal/events"
+	"github.com/cmanish049/students-api/internal/http/router"
+	studentsvc "github.com/cmanish049/students-api/internal/service/student"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/utils/response"
+	"github.com/cmanish049/students-api/internal/validation"
+	"github.com/cmanish049/students-api/internal/webhooks"
+)
+
+// Store is what the API needs from a storage.
+type Store interface {
+	storage.Storage
+	storage.WebhookStorage
+}
+
+// Handler serves the v1 student and webhook routes on store the way the
+// server does, without the middleware. Mutations are published on bus
+// (if not nil), so tests can subscribe to the events.
+func Handler(store Store, bus *events.Bus) http.Handler {
+	// no rules beyond the field tags; this can't fail
+	validate, _ := validation.New(validation.Rules{})
+	students := studentsvc.New(store, bus, validate)
+
+	v1 := router.New().Group("/api/v1")
+	router.Students(v1, students)
+	// webhooks are refused at internal addresses, as by the server
+	router.Webhooks(v1, store, webhooks.NewGuard(nil), validate)
+
+	return v1
+}
+
+// NewServer starts an httptest.Server for Handler(store, nil), closed when
+// the test ends.
+func NewServer(t testing.TB, store Store) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(Handler(store, nil))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// Response is a recorded response.
+type Response struct {
+	t testing.TB
+
+	Code   int
+	Header http.Header
+	Body   []byte
+}
+
+// Do sends a request to h. A non-nil body is encoded as JSON, unless it is
+// already a []byte or string.
+func Do(t testing.TB, h http.Handler, method, path string, body any) *Response {
+	t.Helper()
+
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		r = bytes.NewReader(b)
+	case string:
+		r = bytes.NewReader([]byte(b))
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("encode request body: %v", err)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, r)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return Serve(t, h, req)
+}
+
+// Serve sends a prepared request to h.
+func Serve(t testing.TB, h http.Handler, req *http.Request) *Response {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return &Response{t: t, Code: rec.Code, Header: rec.Header(), Body: rec.Body.Bytes()}
+}
+
+// ExpectStatus fails the test unless the response has the given status.
+func (r *Response) ExpectStatus(code int) *Response {
+	r.t.Helper()
+
+	if r.Code != code {
+		r.t.Fatalf("status = %d, want %d; body: %s", r.Code, code, r.Body)
+	}
+
+	return r
+}
+
+// Envelope is the body of a JSON response, with its data left encoded.
+type Envelope struct {
+	Data  json.RawMessage     `json:"data"`
+	Error *response.ErrorBody `json:"error"`
+	Meta  response.Meta       `json:"meta"`
+}
+
+// Envelope decodes the body, failing the test if it isn't valid JSON.
+func (r *Response) Envelope() Envelope {
+	r.t.Helper()
+
+	var env Envelope
+	if err := json.Unmarshal(r.Body, &env); err != nil {
+		r.t.Fatalf("decode response body %q: %v", r.Body, err)
+	}
+
+	return env
+}
+
+// JSON decodes the data of the response into v, failing the test if it
+// has none.
+func (r *Response) JSON(v any) {
+	r.t.Helper()
+
+	env := r.Envelope()
+	if err := json.Unmarshal(env.Data, v); err != nil {
+		r.t.Fatalf("decode response data %q: %v", env.Data, err)
+	}
+}
+
+// Error returns the error of an error response, failing the test if it
+// isn't one.
+func (r *Response) Error() response.ErrorBody {
+	r.t.Helper()
+
+	env := r.Envelope()
+	if env.Error == nil {
+		r.t.Fatalf("response has no error; body: %s", r.Body)
+	}
+
+	return *env.Error
+}
@@ -0,0 +1,608 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+// Store is what TestStorage checks.
+type Store interface {
+	storage.Storage
+	storage.WebhookStorage
+	storage.FileStorage
+}
+
+// TestStorage checks that the stores open returns behave like the SQLite
+// storage, which Memory is meant to match: ids, uniqueness, tenants, dry
+// runs, versions, archiving, merging, files and webhooks. open is called
+// for every subtest and must return an empty store.
+func TestStorage(t *testing.T, open func(t *testing.T) Store) {
+	tests := []struct {
+		name string
+		test func(*testing.T, Store)
+	}{
+		{"Students", testStudents},
+		{"UniqueEmails", testUniqueEmails},
+		{"Tenants", testTenants},
+		{"DryRun", testDryRun},
+		{"List", testList},
+		{"Versions", testVersions},
+		{"Verification", testVerification},
+		{"Archive", testArchive},
+		{"Merge", testMerge},
+		{"Files", testFiles},
+		{"Webhooks", testWebhooks},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, open(t))
+		})
+	}
+}
+
+func create(t *testing.T, ctx context.Context, s Store, student types.Student) types.Student {
+	t.Helper()
+
+	id, err := s.CreateStudent(ctx, student.Name, student.Email, student.Age)
+	if err != nil {
+		t.Fatalf("CreateStudent(%s): %v", student.Email, err)
+	}
+
+	created, err := s.GetStudentById(ctx, id)
+	if err != nil {
+		t.Fatalf("GetStudentById(%d): %v", id, err)
+	}
+
+	return created
+}
+
+// ids returns the ids of students, in order.
+func ids(students []types.Student) []int {
+	var res []int
+	for _, s := range students {
+		res = append(res, s.Id)
+	}
+
+	return res
+}
+
+func expectErr(t *testing.T, what string, err, kind error) {
+	t.Helper()
+
+	if !errors.Is(err, kind) {
+		t.Errorf("%s: error = %v, want %v", what, err, kind)
+	}
+}
+
+func testStudents(t *testing.T, s Store) {
+	ctx := context.Background()
+	before := time.Now().UTC().Add(-time.Second)
+
+	student := create(t, ctx, s, Student(WithName("Ann Lee"), WithEmail("ann@example.com"), WithAge(21)))
+	if student.Id == 0 || student.Name != "Ann Lee" || student.Email != "ann@example.com" || student.Age != 21 {
+		t.Errorf("created student = %+v", student)
+	}
+	if student.TenantId != tenant.Default {
+		t.Errorf("tenant = %q, want %q", student.TenantId, tenant.Default)
+	}
+	if student.CreatedAt.Before(before) || !student.UpdatedAt.Equal(student.CreatedAt) {
+		t.Errorf("created_at = %v, updated_at = %v", student.CreatedAt, student.UpdatedAt)
+	}
+	if student.EmailVerified || !student.ArchivedAt.IsZero() {
+		t.Errorf("new student is verified or archived: %+v", student)
+	}
+
+	byEmail, err := s.GetStudentByEmail(ctx, "ann@example.com")
+	if err != nil || byEmail.Id != student.Id {
+		t.Errorf("GetStudentByEmail = %+v, %v", byEmail, err)
+	}
+
+	if err := s.UpdateStudent(ctx, int64(student.Id), "Ann Smith", "ann.smith@example.com", 22); err != nil {
+		t.Fatalf("UpdateStudent: %v", err)
+	}
+	updated, err := s.GetStudentById(ctx, int64(student.Id))
+	if err != nil {
+		t.Fatalf("GetStudentById: %v", err)
+	}
+	if updated.Name != "Ann Smith" || updated.Email != "ann.smith@example.com" || updated.Age != 22 {
+		t.Errorf("updated student = %+v", updated)
+	}
+	if !updated.CreatedAt.Equal(student.CreatedAt) || updated.UpdatedAt.Before(student.UpdatedAt) {
+		t.Errorf("update moved created_at or updated_at back: %+v", updated)
+	}
+
+	if err := s.DeleteStudent(ctx, int64(student.Id)); err != nil {
+		t.Fatalf("DeleteStudent: %v", err)
+	}
+
+	_, err = s.GetStudentById(ctx, int64(student.Id))
+	expectErr(t, "GetStudentById of a deleted student", err, storage.ErrNotFound)
+	_, err = s.GetStudentByEmail(ctx, "ann.smith@example.com")
+	expectErr(t, "GetStudentByEmail of a deleted student", err, storage.ErrNotFound)
+	expectErr(t, "UpdateStudent of a deleted student", s.UpdateStudent(ctx, int64(student.Id), "Ann", "ann@example.com", 21), storage.ErrNotFound)
+	expectErr(t, "DeleteStudent of a deleted student", s.DeleteStudent(ctx, int64(student.Id)), storage.ErrNotFound)
+
+	// ids aren't reused
+	next := create(t, ctx, s, Student())
+	if next.Id <= student.Id {
+		t.Errorf("id after deleting %d = %d", student.Id, next.Id)
+	}
+}
+
+func testUniqueEmails(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	ann := create(t, ctx, s, Student(WithEmail("ann@example.com")))
+	bob := create(t, ctx, s, Student(WithEmail("bob@example.com")))
+
+	_, err := s.CreateStudent(ctx, "Ann Again", "ann@example.com", 20)
+	expectErr(t, "CreateStudent with a used email", err, storage.ErrConflict)
+
+	expectErr(t, "UpdateStudent to a used email", s.UpdateStudent(ctx, int64(bob.Id), bob.Name, ann.Email, bob.Age), storage.ErrConflict)
+
+	// keeping one's own email is no conflict
+	if err := s.UpdateStudent(ctx, int64(ann.Id), "Ann Lee", ann.Email, ann.Age); err != nil {
+		t.Errorf("UpdateStudent keeping the email: %v", err)
+	}
+
+	// nor is the email of a deleted student
+	if err := s.DeleteStudent(ctx, int64(bob.Id)); err != nil {
+		t.Fatalf("DeleteStudent: %v", err)
+	}
+	create(t, ctx, s, Student(WithEmail("bob@example.com")))
+}
+
+func testTenants(t *testing.T, s Store) {
+	north := tenant.With(context.Background(), "north")
+	south := tenant.With(context.Background(), "south")
+
+	ann := create(t, north, s, Student(WithEmail("ann@example.com")))
+	if ann.TenantId != "north" {
+		t.Errorf("tenant = %q, want north", ann.TenantId)
+	}
+
+	// emails are unique within a tenant only
+	bob := create(t, south, s, Student(WithEmail("ann@example.com")))
+
+	_, err := s.GetStudentById(south, int64(ann.Id))
+	expectErr(t, "GetStudentById of another tenant", err, storage.ErrNotFound)
+	expectErr(t, "UpdateStudent of another tenant", s.UpdateStudent(south, int64(ann.Id), "Ann", "ann@example.com", 20), storage.ErrNotFound)
+	expectErr(t, "DeleteStudent of another tenant", s.DeleteStudent(south, int64(ann.Id)), storage.ErrNotFound)
+	_, err = s.MergeStudents(south, int64(bob.Id), int64(ann.Id))
+	expectErr(t, "MergeStudents with another tenant", err, storage.ErrNotFound)
+	_, err = s.GetStudentVersions(south, int64(ann.Id))
+	expectErr(t, "GetStudentVersions of another tenant", err, storage.ErrNotFound)
+
+	byEmail, err := s.GetStudentByEmail(south, "ann@example.com")
+	if err != nil || byEmail.Id != bob.Id {
+		t.Errorf("GetStudentByEmail = %+v, %v; want student %d", byEmail, err, bob.Id)
+	}
+
+	list, err := s.GetStudentList(north, storage.StudentFilter{})
+	if err != nil || !slices.Equal(ids(list), []int{ann.Id}) {
+		t.Errorf("GetStudentList(north) = %v, %v; want [%d]", ids(list), err, ann.Id)
+	}
+
+	// only tenant.All sees everyone
+	all, err := s.GetStudentList(tenant.All(context.Background()), storage.StudentFilter{})
+	if err != nil || !slices.Equal(ids(all), []int{ann.Id, bob.Id}) {
+		t.Errorf("GetStudentList(all) = %v, %v; want [%d %d]", ids(all), err, ann.Id, bob.Id)
+	}
+
+	archived, err := s.ArchiveStudents(south, time.Now().Add(time.Minute))
+	if err != nil || !slices.Equal(ids(archived), []int{bob.Id}) {
+		t.Errorf("ArchiveStudents(south) = %v, %v; want [%d]", ids(archived), err, bob.Id)
+	}
+	_, err = s.GetArchivedStudent(north, int64(bob.Id))
+	expectErr(t, "GetArchivedStudent of another tenant", err, storage.ErrNotFound)
+	_, err = s.UnarchiveStudent(north, int64(bob.Id))
+	expectErr(t, "UnarchiveStudent of another tenant", err, storage.ErrNotFound)
+	if _, err := s.GetStudentById(north, int64(ann.Id)); err != nil {
+		t.Errorf("archiving south archived north too: %v", err)
+	}
+}
+
+func testDryRun(t *testing.T, s Store) {
+	ctx := context.Background()
+	dry := dryrun.With(ctx)
+
+	ann := create(t, ctx, s, Student(WithEmail("ann@example.com")))
+
+	if _, err := s.CreateStudent(dry, "Bob", "bob@example.com", 20); err != nil {
+		t.Errorf("dry CreateStudent: %v", err)
+	}
+	_, err := s.CreateStudent(dry, "Ann Again", "ann@example.com", 20)
+	expectErr(t, "dry CreateStudent with a used email", err, storage.ErrConflict)
+
+	if err := s.UpdateStudent(dry, int64(ann.Id), "Ann Smith", ann.Email, 30); err != nil {
+		t.Errorf("dry UpdateStudent: %v", err)
+	}
+	expectErr(t, "dry UpdateStudent of a missing student", s.UpdateStudent(dry, 1000, "Bob", "bob@example.com", 20), storage.ErrNotFound)
+
+	if err := s.DeleteStudent(dry, int64(ann.Id)); err != nil {
+		t.Errorf("dry DeleteStudent: %v", err)
+	}
+	if _, err := s.ArchiveStudents(dry, time.Now().Add(time.Minute)); err != nil {
+		t.Errorf("dry ArchiveStudents: %v", err)
+	}
+
+	list, err := s.GetStudentList(ctx, storage.StudentFilter{IncludeArchived: true})
+	if err != nil || len(list) != 1 || list[0] != ann {
+		t.Errorf("after dry runs students = %+v, %v; want only %+v", list, err, ann)
+	}
+}
+
+func testList(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	list, err := s.GetStudentList(ctx, storage.StudentFilter{})
+	if err != nil || list != nil {
+		t.Errorf("empty GetStudentList = %v, %v; want nil", list, err)
+	}
+
+	ann := create(t, ctx, s, Student())
+	time.Sleep(10 * time.Millisecond)
+	bob := create(t, ctx, s, Student())
+
+	list, err = s.GetStudentList(ctx, storage.StudentFilter{})
+	if err != nil || !slices.Equal(ids(list), []int{ann.Id, bob.Id}) {
+		t.Errorf("GetStudentList = %v, %v; want [%d %d]", ids(list), err, ann.Id, bob.Id)
+	}
+
+	list, err = s.GetStudentList(ctx, storage.StudentFilter{CreatedAfter: ann.CreatedAt})
+	if err != nil || !slices.Equal(ids(list), []int{bob.Id}) {
+		t.Errorf("GetStudentList(created after %d) = %v, %v; want [%d]", ann.Id, ids(list), err, bob.Id)
+	}
+
+	list, err = s.GetStudentList(ctx, storage.StudentFilter{CreatedBefore: bob.CreatedAt})
+	if err != nil || !slices.Equal(ids(list), []int{ann.Id}) {
+		t.Errorf("GetStudentList(created before %d) = %v, %v; want [%d]", bob.Id, ids(list), err, ann.Id)
+	}
+
+	verified := true
+	list, err = s.GetStudentList(ctx, storage.StudentFilter{EmailVerified: &verified})
+	if err != nil || list != nil {
+		t.Errorf("GetStudentList(verified) = %v, %v; want nil", ids(list), err)
+	}
+}
+
+func testVersions(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	ann := create(t, ctx, s, Student(WithName("Ann Lee"), WithAge(20)))
+	if err := s.UpdateStudent(ctx, int64(ann.Id), "Ann Smith", ann.Email, 21); err != nil {
+		t.Fatalf("UpdateStudent: %v", err)
+	}
+
+	versions, err := s.GetStudentVersions(ctx, int64(ann.Id))
+	if err != nil {
+		t.Fatalf("GetStudentVersions: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 1 || versions[0].Name != "Ann Lee" || versions[1].Version != 2 || versions[1].Name != "Ann Smith" || versions[1].Age != 21 {
+		t.Errorf("versions = %+v", versions)
+	}
+
+	v, err := s.GetStudentVersion(ctx, int64(ann.Id), 1)
+	if err != nil || v.Name != "Ann Lee" || v.StudentId != ann.Id {
+		t.Errorf("GetStudentVersion(1) = %+v, %v", v, err)
+	}
+	_, err = s.GetStudentVersion(ctx, int64(ann.Id), 3)
+	expectErr(t, "GetStudentVersion of a missing version", err, storage.ErrNotFound)
+	_, err = s.GetStudentVersions(ctx, 1000)
+	expectErr(t, "GetStudentVersions of a missing student", err, storage.ErrNotFound)
+}
+
+func testVerification(t *testing.T, s Store) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	ann := create(t, ctx, s, Student())
+	bob := create(t, ctx, s, Student())
+
+	if err := s.SetVerificationToken(ctx, int64(ann.Id), "ann-hash", now.Add(time.Hour)); err != nil {
+		t.Fatalf("SetVerificationToken: %v", err)
+	}
+	if err := s.SetVerificationToken(ctx, int64(bob.Id), "bob-hash", now.Add(-time.Hour)); err != nil {
+		t.Fatalf("SetVerificationToken: %v", err)
+	}
+	expectErr(t, "SetVerificationToken of a missing student", s.SetVerificationToken(ctx, 1000, "hash", now.Add(time.Hour)), storage.ErrNotFound)
+
+	_, err := s.VerifyEmail(ctx, "bob-hash", now)
+	expectErr(t, "VerifyEmail with an expired token", err, storage.ErrNotFound)
+	_, err = s.VerifyEmail(tenant.With(ctx, "north"), "ann-hash", now)
+	expectErr(t, "VerifyEmail of another tenant", err, storage.ErrNotFound)
+
+	verified, err := s.VerifyEmail(ctx, "ann-hash", now)
+	if err != nil || verified.Id != ann.Id || !verified.EmailVerified || verified.EmailVerifiedAt.IsZero() {
+		t.Fatalf("VerifyEmail = %+v, %v", verified, err)
+	}
+	_, err = s.VerifyEmail(ctx, "ann-hash", now)
+	expectErr(t, "VerifyEmail with a used token", err, storage.ErrNotFound)
+
+	// of the verified email only
+	if err := s.UpdateStudent(ctx, int64(ann.Id), "Ann", ann.Email, ann.Age); err != nil {
+		t.Fatalf("UpdateStudent: %v", err)
+	}
+	if got, _ := s.GetStudentById(ctx, int64(ann.Id)); !got.EmailVerified {
+		t.Errorf("keeping the email unverified it: %+v", got)
+	}
+	if err := s.UpdateStudent(ctx, int64(ann.Id), "Ann", "ann.new@example.com", ann.Age); err != nil {
+		t.Fatalf("UpdateStudent: %v", err)
+	}
+	if got, _ := s.GetStudentById(ctx, int64(ann.Id)); got.EmailVerified || !got.EmailVerifiedAt.IsZero() {
+		t.Errorf("a new email is verified: %+v", got)
+	}
+}
+
+func testArchive(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	ann := create(t, ctx, s, Student(WithEmail("ann@example.com")))
+	time.Sleep(10 * time.Millisecond)
+	cutoff := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	bob := create(t, ctx, s, Student())
+
+	archived, err := s.ArchiveStudents(ctx, cutoff)
+	if err != nil || !slices.Equal(ids(archived), []int{ann.Id}) || archived[0].ArchivedAt.IsZero() {
+		t.Fatalf("ArchiveStudents = %+v, %v; want student %d", archived, err, ann.Id)
+	}
+
+	_, err = s.GetStudentById(ctx, int64(ann.Id))
+	expectErr(t, "GetStudentById of an archived student", err, storage.ErrNotFound)
+
+	got, err := s.GetArchivedStudent(ctx, int64(ann.Id))
+	if err != nil || got.Email != ann.Email || got.ArchivedAt.IsZero() {
+		t.Errorf("GetArchivedStudent = %+v, %v", got, err)
+	}
+	_, err = s.GetArchivedStudent(ctx, int64(bob.Id))
+	expectErr(t, "GetArchivedStudent of a student not archived", err, storage.ErrNotFound)
+
+	list, err := s.GetStudentList(ctx, storage.StudentFilter{})
+	if err != nil || !slices.Equal(ids(list), []int{bob.Id}) {
+		t.Errorf("GetStudentList = %v, %v; want [%d]", ids(list), err, bob.Id)
+	}
+	list, err = s.GetStudentList(ctx, storage.StudentFilter{IncludeArchived: true})
+	if err != nil || !slices.Equal(ids(list), []int{ann.Id, bob.Id}) || list[0].ArchivedAt.IsZero() {
+		t.Errorf("GetStudentList(include archived) = %v, %v; want [%d %d]", ids(list), err, ann.Id, bob.Id)
+	}
+
+	// the email of an archived student can be taken, and then it can't
+	// come back
+	carl := create(t, ctx, s, Student(WithEmail("ann@example.com")))
+	if carl.Id <= bob.Id {
+		t.Errorf("id after archiving = %d, want more than %d", carl.Id, bob.Id)
+	}
+	_, err = s.UnarchiveStudent(ctx, int64(ann.Id))
+	expectErr(t, "UnarchiveStudent with a used email", err, storage.ErrConflict)
+	if err := s.DeleteStudent(ctx, int64(carl.Id)); err != nil {
+		t.Fatalf("DeleteStudent: %v", err)
+	}
+
+	back, err := s.UnarchiveStudent(ctx, int64(ann.Id))
+	if err != nil || back.Id != ann.Id || !back.ArchivedAt.IsZero() || !back.CreatedAt.Equal(ann.CreatedAt) || !back.UpdatedAt.After(ann.UpdatedAt) {
+		t.Errorf("UnarchiveStudent = %+v, %v", back, err)
+	}
+	if got, err := s.GetStudentById(ctx, int64(ann.Id)); err != nil || got != back {
+		t.Errorf("GetStudentById after unarchiving = %+v, %v; want %+v", got, err, back)
+	}
+	_, err = s.UnarchiveStudent(ctx, int64(ann.Id))
+	expectErr(t, "UnarchiveStudent of a student not archived", err, storage.ErrNotFound)
+}
+
+func testMerge(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	older := create(t, ctx, s, Student(WithName("Ann Lee")))
+	time.Sleep(10 * time.Millisecond)
+	kept := create(t, ctx, s, Student(WithName("Ann Smith")))
+	time.Sleep(10 * time.Millisecond)
+	if err := s.UpdateStudent(ctx, int64(older.Id), "Ann B. Lee", older.Email, older.Age); err != nil {
+		t.Fatalf("UpdateStudent: %v", err)
+	}
+
+	fileId, err := s.CreateFile(ctx, newFile(older.Id, "id.pdf"))
+	if err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+
+	// a dry run changes nothing
+	if _, err := s.MergeStudents(dryrun.With(ctx), int64(kept.Id), int64(older.Id)); err != nil {
+		t.Fatalf("MergeStudents dry run: %v", err)
+	}
+	if _, err := s.GetStudentById(ctx, int64(older.Id)); err != nil {
+		t.Errorf("GetStudentById after a dry run merge: %v", err)
+	}
+
+	merged, err := s.MergeStudents(ctx, int64(kept.Id), int64(older.Id))
+	if err != nil {
+		t.Fatalf("MergeStudents: %v", err)
+	}
+	if merged.Id != kept.Id || merged.Name != "Ann Smith" || merged.Email != kept.Email || !merged.CreatedAt.Equal(older.CreatedAt) || merged.UpdatedAt.Before(kept.UpdatedAt) {
+		t.Errorf("merged student = %+v", merged)
+	}
+	if got, err := s.GetStudentById(ctx, int64(kept.Id)); err != nil || got != merged {
+		t.Errorf("GetStudentById after merging = %+v, %v; want %+v", got, err, merged)
+	}
+
+	// both histories in the order they were made, then the merge
+	versions, err := s.GetStudentVersions(ctx, int64(kept.Id))
+	if err != nil {
+		t.Fatalf("GetStudentVersions: %v", err)
+	}
+	var names []string
+	for i, v := range versions {
+		names = append(names, v.Name)
+		if v.Version != i+1 || v.StudentId != kept.Id {
+			t.Errorf("version %d = %+v", i+1, v)
+		}
+	}
+	if want := []string{"Ann Lee", "Ann Smith", "Ann B. Lee", "Ann Smith"}; !slices.Equal(names, want) {
+		t.Errorf("versions after merging = %q, want %q", names, want)
+	}
+	if last := versions[len(versions)-1]; last.Email != merged.Email || !last.CreatedAt.Equal(merged.UpdatedAt) {
+		t.Errorf("latest version = %+v, want one of %+v", last, merged)
+	}
+
+	_, err = s.GetStudentById(ctx, int64(older.Id))
+	expectErr(t, "GetStudentById of a merged student", err, storage.ErrNotFound)
+
+	if f, err := s.GetFile(ctx, int64(kept.Id), fileId); err != nil || f.StudentId != int64(kept.Id) {
+		t.Errorf("file after merging = %+v, %v; want one of student %d", f, err, kept.Id)
+	}
+
+	_, err = s.MergeStudents(ctx, int64(kept.Id), int64(older.Id))
+	expectErr(t, "MergeStudents with a merged student", err, storage.ErrNotFound)
+
+	// a verified email replaces an unverified one
+	verified := create(t, ctx, s, Student(WithEmail("ann.lee@example.com")))
+	if err := s.SetVerificationToken(ctx, int64(verified.Id), "merge-hash", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("SetVerificationToken: %v", err)
+	}
+	if verified, err = s.VerifyEmail(ctx, "merge-hash", time.Now().UTC()); err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	if err := s.SetVerificationToken(ctx, int64(kept.Id), "kept-hash", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("SetVerificationToken: %v", err)
+	}
+
+	merged, err = s.MergeStudents(ctx, int64(kept.Id), int64(verified.Id))
+	if err != nil {
+		t.Fatalf("MergeStudents: %v", err)
+	}
+	if merged.Name != "Ann Smith" || merged.Email != "ann.lee@example.com" || !merged.EmailVerified || !merged.EmailVerifiedAt.Equal(verified.EmailVerifiedAt) {
+		t.Errorf("merged with a verified email = %+v", merged)
+	}
+	// the token was for the email replaced
+	_, err = s.VerifyEmail(ctx, "kept-hash", time.Now().UTC())
+	expectErr(t, "VerifyEmail with the token of a replaced email", err, storage.ErrNotFound)
+}
+
+func newFile(studentId int, name string) types.File {
+	return types.File{
+		StudentId:   int64(studentId),
+		Name:        name,
+		ContentType: "application/pdf",
+		Size:        3,
+		Key:         "default/students/" + name,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func testFiles(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	ann := create(t, ctx, s, Student())
+	bob := create(t, ctx, s, Student())
+
+	files, err := s.GetFiles(ctx, int64(ann.Id))
+	if err != nil || files != nil {
+		t.Errorf("GetFiles without files = %v, %v; want nil", files, err)
+	}
+
+	first, err := s.CreateFile(ctx, newFile(ann.Id, "a.pdf"))
+	if err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+	second, err := s.CreateFile(ctx, newFile(ann.Id, "b.pdf"))
+	if err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+	bobs, err := s.CreateFile(ctx, newFile(bob.Id, "c.pdf"))
+	if err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+
+	files, err = s.GetFiles(ctx, int64(ann.Id))
+	if err != nil || len(files) != 2 || files[0].Id != first || files[1].Id != second {
+		t.Errorf("GetFiles = %+v, %v; want files %d and %d", files, err, first, second)
+	}
+	if want := newFile(ann.Id, "a.pdf"); err == nil && len(files) > 0 && (files[0].Name != want.Name || files[0].Key != want.Key || !files[0].CreatedAt.Equal(want.CreatedAt)) {
+		t.Errorf("file = %+v, want %+v", files[0], want)
+	}
+
+	_, err = s.GetFile(ctx, int64(bob.Id), first)
+	expectErr(t, "GetFile of another student", err, storage.ErrNotFound)
+	if files, _ := s.GetFiles(tenant.With(ctx, "north"), int64(ann.Id)); files != nil {
+		t.Errorf("GetFiles of another tenant = %+v", files)
+	}
+
+	if err := s.DeleteFile(ctx, int64(ann.Id), first); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	expectErr(t, "DeleteFile of a deleted file", s.DeleteFile(ctx, int64(ann.Id), first), storage.ErrNotFound)
+
+	// the files of a deleted student go with it
+	if err := s.DeleteStudent(ctx, int64(ann.Id)); err != nil {
+		t.Fatalf("DeleteStudent: %v", err)
+	}
+	_, err = s.GetFile(ctx, int64(ann.Id), second)
+	expectErr(t, "GetFile of a deleted student", err, storage.ErrNotFound)
+	if _, err := s.GetFile(ctx, int64(bob.Id), bobs); err != nil {
+		t.Errorf("deleting student %d deleted a file of student %d: %v", ann.Id, bob.Id, err)
+	}
+}
+
+func testWebhooks(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	webhooks, err := s.GetWebhookList(ctx)
+	if err != nil || webhooks != nil {
+		t.Errorf("empty GetWebhookList = %v, %v; want nil", webhooks, err)
+	}
+
+	first, err := s.CreateWebhook(ctx, "https://example.com/a", "secret", nil)
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	second, err := s.CreateWebhook(ctx, "https://example.com/b", "secret", []string{"student.created"})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if _, err := s.CreateWebhook(tenant.With(ctx, "north"), "https://example.com/c", "secret", nil); err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+
+	webhooks, err = s.GetWebhookList(ctx)
+	if err != nil || len(webhooks) != 2 || webhooks[0].Id != first || webhooks[1].Id != second {
+		t.Fatalf("GetWebhookList = %+v, %v; want webhooks %d and %d", webhooks, err, first, second)
+	}
+	if w := webhooks[0]; w.Url != "https://example.com/a" || w.Secret != "secret" || w.Events == nil || len(w.Events) != 0 {
+		t.Errorf("webhook without events = %+v", w)
+	}
+	if w := webhooks[1]; !slices.Equal(w.Events, []string{"student.created"}) {
+		t.Errorf("webhook events = %v", w.Events)
+	}
+
+	for status := range 3 {
+		d := types.WebhookDelivery{WebhookId: first, EventId: int64(status), EventType: "student.created", StatusCode: 200 + status, Attempt: 1, CreatedAt: time.Now().UTC()}
+		if err := s.CreateWebhookDelivery(ctx, d); err != nil {
+			t.Fatalf("CreateWebhookDelivery: %v", err)
+		}
+	}
+
+	deliveries, err := s.GetWebhookDeliveries(ctx, first, 2)
+	if err != nil || len(deliveries) != 2 || deliveries[0].StatusCode != 202 || deliveries[1].StatusCode != 201 {
+		t.Errorf("GetWebhookDeliveries = %+v, %v; want the newest 2", deliveries, err)
+	}
+
+	expectErr(t, "DeleteWebhook of another tenant", s.DeleteWebhook(tenant.With(ctx, "north"), first), storage.ErrNotFound)
+	if err := s.DeleteWebhook(ctx, first); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	expectErr(t, "DeleteWebhook of a deleted webhook", s.DeleteWebhook(ctx, first), storage.ErrNotFound)
+
+	deliveries, err = s.GetWebhookDeliveries(ctx, first, 10)
+	if err != nil || deliveries != nil {
+		t.Errorf("GetWebhookDeliveries of a deleted webhook = %+v, %v; want nil", deliveries, err)
+	}
+}
@@ -0,0 +1,71 @@
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+var fixtureSeq atomic.Int64
+
+// Student returns a valid student with a unique email, changed by the
+// given options. The id is left zero; Seed assigns it.
+func Student(opts ...func(*types.Student)) types.Student {
+	n := fixtureSeq.Add(1)
+
+	s := types.Student{
+		Name:  fmt.Sprintf("Student %d", n),
+		Email: fmt.Sprintf("student%d@example.com", n),
+		Age:   20,
+	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	return s
+}
+
+func WithName(name string) func(*types.Student) {
+	return func(s *types.Student) { s.Name = name }
+}
+
+func WithEmail(email string) func(*types.Student) {
+	return func(s *types.Student) { s.Email = email }
+}
+
+func WithAge(age int) func(*types.Student) {
+	return func(s *types.Student) { s.Age = age }
+}
+
+// Seed stores the students and returns them with their ids. It fails the
+// test if any of them can't be stored.
+func Seed(t testing.TB, m *Memory, students ...types.Student) []types.Student {
+	t.Helper()
+
+	seeded := make([]types.Student, 0, len(students))
+	for _, s := range students {
+		id, err := m.CreateStudent(context.Background(), s.Name, s.Email, s.Age)
+		if err != nil {
+			t.Fatalf("seed student %s: %v", s.Email, err)
+		}
+		s.Id = int(id)
+		seeded = append(seeded, s)
+	}
+
+	return seeded
+}
+
+// SeedN stores n generated students.
+func SeedN(t testing.TB, m *Memory, n int) []types.Student {
+	t.Helper()
+
+	students := make([]types.Student, n)
+	for i := range students {
+		students[i] = Student()
+	}
+
+	return Seed(t, m, students...)
+}
@@ -0,0 +1,770 @@
+// Package storagetest provides an in-memory storage and fixtures for
+// testing code written against the storage interfaces without SQLite.
+package storagetest
+
+import (
+	"context"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cmanish049/students-api/internal/dryrun"
+	"github.com/cmanish049/students-api/internal/storage"
+	"github.com/cmanish049/students-api/internal/tenant"
+	"github.com/cmanish049/students-api/internal/types"
+)
+
+var (
+	_ storage.Storage        = (*Memory)(nil)
+	_ storage.WebhookStorage = (*Memory)(nil)
+	_ storage.FileStorage    = (*Memory)(nil)
+)
+
+// Memory implements storage.Storage, storage.WebhookStorage and
+// storage.FileStorage with the same behaviour as the SQLite storage: ids
+// are assigned in order and never reused, emails are unique within a
+// tenant, records of other tenants (see package tenant) are missing,
+// missing records are errors and empty lists are nil. Dry runs (see
+// package dryrun) are checked but not applied. It is safe for concurrent
+// use.
+type Memory struct {
+	mu sync.Mutex
+
+	students      map[int64]types.Student
+	lastStudentId int64
+	// archived are the archived students, out of students
+	archived map[int64]types.Student
+	// verifications are the pending verification tokens, by student id
+	verifications map[int64]verification
+	// versions are the versions of each student, oldest first
+	versions map[int64][]types.StudentVersion
+
+	files      map[int64]file
+	lastFileId int64
+
+	webhooks      map[int64]types.Webhook
+	lastWebhookId int64
+
+	deliveries     []delivery
+	lastDeliveryId int64
+
+	failures map[string]error
+	calls    map[string]int
+}
+
+// verification is the hash of a verification token and its expiry.
+type verification struct {
+	tokenHash string
+	expiresAt time.Time
+}
+
+// file is a file and its tenant, which the type lacks.
+type file struct {
+	tenantId string
+	types.File
+}
+
+// delivery is a logged delivery and its tenant, which the type lacks.
+type delivery struct {
+	tenantId string
+	types.WebhookDelivery
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		students:      map[int64]types.Student{},
+		archived:      map[int64]types.Student{},
+		verifications: map[int64]verification{},
+		versions:      map[int64][]types.StudentVersion{},
+		files:         map[int64]file{},
+		webhooks:      map[int64]types.Webhook{},
+		failures:      map[string]error{},
+		calls:         map[string]int{},
+	}
+}
+
+// FailWith makes every later call of the named method ("CreateStudent",
+// "GetStudentList", ...) return err, for testing error paths. A nil err
+// clears the failure.
+func (m *Memory) FailWith(method string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err == nil {
+		delete(m.failures, method)
+		return
+	}
+	m.failures[method] = err
+}
+
+// Calls reports how often the named method was called.
+func (m *Memory) Calls(method string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.calls[method]
+}
+
+// enter records a call and returns the injected failure, if any. The
+// caller must hold mu.
+func (m *Memory) enter(ctx context.Context, method string) error {
+	m.calls[method]++
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return m.failures[method]
+}
+
+// visible reports whether ctx sees a record of tenant id.
+func visible(ctx context.Context, id string) bool {
+	return tenant.IsAll(ctx) || id == tenant.From(ctx)
+}
+
+// student returns student id if ctx sees it.
+func (m *Memory) student(ctx context.Context, id int64) (types.Student, bool) {
+	s, ok := m.students[id]
+	if !ok || !visible(ctx, s.TenantId) {
+		return types.Student{}, false
+	}
+
+	return s, true
+}
+
+// listed returns the students a filter can pass, with the archived ones
+// only if it includes them. The caller must hold mu.
+func (m *Memory) listed(filter storage.StudentFilter) []types.Student {
+	students := slices.Collect(maps.Values(m.students))
+	if filter.IncludeArchived {
+		students = slices.AppendSeq(students, maps.Values(m.archived))
+	}
+
+	return students
+}
+
+func (m *Memory) emailTaken(ctx context.Context, email string, except int64) bool {
+	for id, s := range m.students {
+		if id != except && s.Email == email && s.TenantId == tenant.From(ctx) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recordVersion stores the fields of student as its next version, unless
+// they are those of its latest one. The caller must hold mu.
+func (m *Memory) recordVersion(student types.Student) {
+	id := int64(student.Id)
+	versions := m.versions[id]
+	if n := len(versions); n > 0 {
+		last := versions[n-1]
+		if last.Name == student.Name && last.Email == student.Email && last.Age == student.Age {
+			return
+		}
+	}
+
+	m.versions[id] = append(versions, types.StudentVersion{
+		StudentId: student.Id,
+		Version:   len(versions) + 1,
+		Name:      student.Name,
+		Email:     student.Email,
+		Age:       student.Age,
+		CreatedAt: student.UpdatedAt,
+	})
+}
+
+func (m *Memory) CreateStudent(ctx context.Context, name, email string, age int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "CreateStudent"); err != nil {
+		return 0, err
+	}
+
+	if m.emailTaken(ctx, email, 0) {
+		return 0, storage.Conflict("email %s is already used by another student", email)
+	}
+
+	if dryrun.Enabled(ctx) {
+		return m.lastStudentId + 1, nil
+	}
+
+	m.lastStudentId++
+	now := time.Now().UTC()
+	m.students[m.lastStudentId] = types.Student{Id: int(m.lastStudentId), Name: name, Email: email, Age: age, TenantId: tenant.From(ctx), CreatedAt: now, UpdatedAt: now}
+	m.recordVersion(m.students[m.lastStudentId])
+
+	return m.lastStudentId, nil
+}
+
+func (m *Memory) GetStudentById(ctx context.Context, id int64) (types.Student, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "GetStudentById"); err != nil {
+		return types.Student{}, err
+	}
+
+	student, ok := m.student(ctx, id)
+	if !ok {
+		return types.Student{}, storage.NotFound("no student found with id %d", id)
+	}
+
+	return student, nil
+}
+
+func (m *Memory) GetStudentByEmail(ctx context.Context, email string) (types.Student, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "GetStudentByEmail"); err != nil {
+		return types.Student{}, err
+	}
+
+	for _, s := range m.students {
+		if s.Email == email && visible(ctx, s.TenantId) {
+			return s, nil
+		}
+	}
+
+	return types.Student{}, storage.NotFound("no student found with email %s", email)
+}
+
+func (m *Memory) GetStudentList(ctx context.Context, filter storage.StudentFilter) ([]types.Student, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "GetStudentList"); err != nil {
+		return nil, err
+	}
+
+	var students []types.Student
+	for _, s := range m.listed(filter) {
+		if visible(ctx, s.TenantId) && filter.Match(s) {
+			students = append(students, s)
+		}
+	}
+	slices.SortFunc(students, func(a, b types.Student) int { return a.Id - b.Id })
+
+	return students, nil
+}
+
+func (m *Memory) UpdateStudent(ctx context.Context, id int64, name, email string, age int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "UpdateStudent"); err != nil {
+		return err
+	}
+
+	current, ok := m.student(ctx, id)
+	if !ok {
+		return storage.NotFound("no student found with id %d", id)
+	}
+
+	if m.emailTaken(ctx, email, id) {
+		return storage.Conflict("email %s is already used by another student", email)
+	}
+
+	if dryrun.Enabled(ctx) {
+		return nil
+	}
+
+	updated := types.Student{Id: int(id), Name: name, Email: email, Age: age, TenantId: current.TenantId, CreatedAt: current.CreatedAt, UpdatedAt: time.Now().UTC()}
+	if email == current.Email {
+		updated.EmailVerified, updated.EmailVerifiedAt = current.EmailVerified, current.EmailVerifiedAt
+	} else {
+		delete(m.verifications, id)
+	}
+	m.students[id] = updated
+	m.recordVersion(updated)
+
+	return nil
+}
+
+func (m *Memory) DeleteStudent(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "DeleteStudent"); err != nil {
+		return err
+	}
+
+	if _, ok := m.student(ctx, id); !ok {
+		return storage.NotFound("no student found with id %d", id)
+	}
+
+	if dryrun.Enabled(ctx) {
+		return nil
+	}
+
+	delete(m.students, id)
+	delete(m.verifications, id)
+	delete(m.versions, id)
+
+	// the files go too; there are no blobs, or jobs deleting them, here
+	for fileId, f := range m.files {
+		if f.StudentId == id && visible(ctx, f.tenantId) {
+			delete(m.files, fileId)
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) MergeStudents(ctx context.Context, id, otherId int64) (types.Student, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "MergeStudents"); err != nil {
+		return types.Student{}, err
+	}
+
+	merged, ok := m.student(ctx, id)
+	if !ok {
+		return types.Student{}, storage.NotFound("no student found with id %d", id)
+	}
+	other, ok := m.student(ctx, otherId)
+	if !ok {
+		return types.Student{}, storage.NotFound("no student found with id %d", otherId)
+	}
+
+	if other.CreatedAt.Before(merged.CreatedAt) {
+		merged.CreatedAt = other.CreatedAt
+	}
+	merged.UpdatedAt = time.Now().UTC()
+
+	takeEmail := other.EmailVerified && !merged.EmailVerified
+	if takeEmail {
+		merged.Email, merged.EmailVerified, merged.EmailVerifiedAt = other.Email, true, other.EmailVerifiedAt
+	}
+
+	if dryrun.Enabled(ctx) {
+		return merged, nil
+	}
+
+	m.students[id] = merged
+	for fid, f := range m.files {
+		if f.StudentId == otherId {
+			f.StudentId = id
+			m.files[fid] = f
+		}
+	}
+
+	// the versions of both in the order they were made, then the merge
+	versions := append(m.versions[id], m.versions[otherId]...)
+	slices.SortStableFunc(versions, func(a, b types.StudentVersion) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	for i := range versions {
+		versions[i].StudentId, versions[i].Version = merged.Id, i+1
+	}
+	m.versions[id] = append(versions, types.StudentVersion{
+		StudentId: merged.Id,
+		Version:   len(versions) + 1,
+		Name:      merged.Name,
+		Email:     merged.Email,
+		Age:       merged.Age,
+		CreatedAt: merged.UpdatedAt,
+	})
+
+	delete(m.students, otherId)
+	delete(m.verifications, otherId)
+	delete(m.versions, otherId)
+	if takeEmail {
+		delete(m.verifications, id)
+	}
+
+	return merged, nil
+}
+
+func (m *Memory) SetVerificationToken(ctx context.Context, id int64, tokenHash string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "SetVerificationToken"); err != nil {
+		return err
+	}
+
+	if _, ok := m.student(ctx, id); !ok {
+		return storage.NotFound("no student found with id %d", id)
+	}
+
+	if dryrun.Enabled(ctx) {
+		return nil
+	}
+
+	m.verifications[id] = verification{tokenHash: tokenHash, expiresAt: expiresAt}
+
+	return nil
+}
+
+func (m *Memory) VerifyEmail(ctx context.Context, tokenHash string, now time.Time) (types.Student, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "VerifyEmail"); err != nil {
+		return types.Student{}, err
+	}
+
+	for id, v := range m.verifications {
+		student, ok := m.student(ctx, id)
+		if !ok || v.tokenHash != tokenHash || !now.Before(v.expiresAt) {
+			continue
+		}
+
+		student.EmailVerified, student.EmailVerifiedAt = true, now.UTC()
+		if !dryrun.Enabled(ctx) {
+			m.students[id] = student
+			delete(m.verifications, id)
+		}
+
+		return student, nil
+	}
+
+	return types.Student{}, storage.NotFound("invalid or expired verification token")
+}
+
+func (m *Memory) GetStudentVersions(ctx context.Context, id int64) ([]types.StudentVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "GetStudentVersions"); err != nil {
+		return nil, err
+	}
+
+	if _, ok := m.student(ctx, id); !ok {
+		return nil, storage.NotFound("no student found with id %d", id)
+	}
+
+	return slices.Clone(m.versions[id]), nil
+}
+
+func (m *Memory) GetStudentVersion(ctx context.Context, id int64, version int) (types.StudentVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "GetStudentVersion"); err != nil {
+		return types.StudentVersion{}, err
+	}
+
+	if _, ok := m.student(ctx, id); !ok {
+		return types.StudentVersion{}, storage.NotFound("no student found with id %d", id)
+	}
+
+	versions := m.versions[id]
+	if version < 1 || version > len(versions) {
+		return types.StudentVersion{}, storage.NotFound("student %d has no version %d", id, version)
+	}
+
+	return versions[version-1], nil
+}
+
+func (m *Memory) ArchiveStudents(ctx context.Context, before time.Time) ([]types.Student, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "ArchiveStudents"); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var archived []types.Student
+	for _, s := range m.students {
+		if visible(ctx, s.TenantId) && s.UpdatedAt.Before(before) {
+			s.ArchivedAt = now
+			archived = append(archived, s)
+		}
+	}
+	slices.SortFunc(archived, func(a, b types.Student) int { return a.Id - b.Id })
+
+	if dryrun.Enabled(ctx) {
+		return archived, nil
+	}
+
+	for _, s := range archived {
+		id := int64(s.Id)
+		delete(m.students, id)
+		delete(m.verifications, id)
+		m.archived[id] = s
+	}
+
+	return archived, nil
+}
+
+func (m *Memory) GetArchivedStudent(ctx context.Context, id int64) (types.Student, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "GetArchivedStudent"); err != nil {
+		return types.Student{}, err
+	}
+
+	student, ok := m.archived[id]
+	if !ok || !visible(ctx, student.TenantId) {
+		return types.Student{}, storage.NotFound("no archived student found with id %d", id)
+	}
+
+	return student, nil
+}
+
+func (m *Memory) UnarchiveStudent(ctx context.Context, id int64) (types.Student, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "UnarchiveStudent"); err != nil {
+		return types.Student{}, err
+	}
+
+	student, ok := m.archived[id]
+	if !ok || !visible(ctx, student.TenantId) {
+		return types.Student{}, storage.NotFound("no archived student found with id %d", id)
+	}
+
+	if m.emailTaken(ctx, student.Email, id) {
+		return types.Student{}, storage.Conflict("email %s is already used by another student", student.Email)
+	}
+
+	student.ArchivedAt = time.Time{}
+	student.UpdatedAt = time.Now().UTC()
+	if dryrun.Enabled(ctx) {
+		return student, nil
+	}
+
+	delete(m.archived, id)
+	m.students[id] = student
+	m.recordVersion(student)
+
+	return student, nil
+}
+
+func (m *Memory) ReportStudents(ctx context.Context, query storage.ReportQuery) ([]types.ReportGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "ReportStudents"); err != nil {
+		return nil, err
+	}
+
+	groups := map[int]*types.ReportGroup{}
+	ages := map[int]int{}
+	for _, s := range m.listed(query.Filter) {
+		if !visible(ctx, s.TenantId) || !query.Filter.Match(s) {
+			continue
+		}
+
+		key := query.Key(s)
+		g, ok := groups[key]
+		if !ok {
+			g = &types.ReportGroup{Group: query.Label(key), MinAge: s.Age, MaxAge: s.Age}
+			groups[key] = g
+		}
+
+		g.Count++
+		if s.EmailVerified {
+			g.Verified++
+		}
+		g.MinAge, g.MaxAge = min(g.MinAge, s.Age), max(g.MaxAge, s.Age)
+		ages[key] += s.Age
+	}
+
+	var res []types.ReportGroup
+	for _, key := range slices.Sorted(maps.Keys(groups)) {
+		g := groups[key]
+		g.AverageAge = math.Round(float64(ages[key])/float64(g.Count)*100) / 100
+		res = append(res, *g)
+	}
+
+	return res, nil
+}
+
+func (m *Memory) CreateFile(ctx context.Context, f types.File) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "CreateFile"); err != nil {
+		return 0, err
+	}
+
+	if dryrun.Enabled(ctx) {
+		return m.lastFileId + 1, nil
+	}
+
+	m.lastFileId++
+	f.Id = m.lastFileId
+	f.CreatedAt = f.CreatedAt.UTC()
+	m.files[f.Id] = file{tenantId: tenant.From(ctx), File: f}
+
+	return f.Id, nil
+}
+
+func (m *Memory) GetFiles(ctx context.Context, studentId int64) ([]types.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "GetFiles"); err != nil {
+		return nil, err
+	}
+
+	var files []types.File
+	for _, f := range m.files {
+		if f.StudentId == studentId && visible(ctx, f.tenantId) {
+			files = append(files, f.File)
+		}
+	}
+	slices.SortFunc(files, func(a, b types.File) int { return int(a.Id - b.Id) })
+
+	return files, nil
+}
+
+func (m *Memory) GetFile(ctx context.Context, studentId, id int64) (types.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "GetFile"); err != nil {
+		return types.File{}, err
+	}
+
+	f, ok := m.files[id]
+	if !ok || f.StudentId != studentId || !visible(ctx, f.tenantId) {
+		return types.File{}, storage.NotFound("student %d has no file %d", studentId, id)
+	}
+
+	return f.File, nil
+}
+
+func (m *Memory) DeleteFile(ctx context.Context, studentId, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "DeleteFile"); err != nil {
+		return err
+	}
+
+	if f, ok := m.files[id]; !ok || f.StudentId != studentId || !visible(ctx, f.tenantId) {
+		return storage.NotFound("student %d has no file %d", studentId, id)
+	}
+
+	if dryrun.Enabled(ctx) {
+		return nil
+	}
+
+	delete(m.files, id)
+
+	return nil
+}
+
+func (m *Memory) CreateWebhook(ctx context.Context, url, secret string, events []string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "CreateWebhook"); err != nil {
+		return 0, err
+	}
+
+	if events == nil {
+		events = []string{}
+	}
+
+	m.lastWebhookId++
+	m.webhooks[m.lastWebhookId] = types.Webhook{
+		Id:        m.lastWebhookId,
+		TenantId:  tenant.From(ctx),
+		Url:       url,
+		Secret:    secret,
+		Events:    slices.Clone(events),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	return m.lastWebhookId, nil
+}
+
+func (m *Memory) GetWebhookList(ctx context.Context) ([]types.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "GetWebhookList"); err != nil {
+		return nil, err
+	}
+
+	var webhooks []types.Webhook
+	for _, w := range m.webhooks {
+		if !visible(ctx, w.TenantId) {
+			continue
+		}
+		w.Events = slices.Clone(w.Events)
+		webhooks = append(webhooks, w)
+	}
+	slices.SortFunc(webhooks, func(a, b types.Webhook) int { return int(a.Id - b.Id) })
+
+	return webhooks, nil
+}
+
+func (m *Memory) DeleteWebhook(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "DeleteWebhook"); err != nil {
+		return err
+	}
+
+	if w, ok := m.webhooks[id]; !ok || !visible(ctx, w.TenantId) {
+		return storage.NotFound("no webhook found with id %d", id)
+	}
+
+	delete(m.webhooks, id)
+	m.deliveries = slices.DeleteFunc(m.deliveries, func(d delivery) bool { return d.WebhookId == id })
+
+	return nil
+}
+
+func (m *Memory) CreateWebhookDelivery(ctx context.Context, d types.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "CreateWebhookDelivery"); err != nil {
+		return err
+	}
+
+	m.lastDeliveryId++
+	d.Id = m.lastDeliveryId
+	m.deliveries = append(m.deliveries, delivery{tenantId: tenant.From(ctx), WebhookDelivery: d})
+
+	return nil
+}
+
+func (m *Memory) GetWebhookDeliveries(ctx context.Context, webhookId int64, limit int) ([]types.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.enter(ctx, "GetWebhookDeliveries"); err != nil {
+		return nil, err
+	}
+
+	// newest first, like the SQLite storage
+	var deliveries []types.WebhookDelivery
+	for i := len(m.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if d := m.deliveries[i]; d.WebhookId == webhookId && visible(ctx, d.tenantId) {
+			deliveries = append(deliveries, d.WebhookDelivery)
+		}
+	}
+
+	return deliveries, nil
+}
+
+// Emails returns the emails of all students, sorted, which makes for
+// short assertions.
+func (m *Memory) Emails() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var emails []string
+	for _, s := range m.students {
+		emails = append(emails, s.Email)
+	}
+	slices.Sort(emails)
+
+	return emails
+}
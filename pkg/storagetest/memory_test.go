@@ -0,0 +1,7 @@
+package storagetest
+
+import "testing"
+
+func TestMemory(t *testing.T) {
+	TestStorage(t, func(*testing.T) Store { return NewMemory() })
+}